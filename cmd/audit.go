@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aaronireland/state-server/pkg/api/audit"
+	"github.com/aaronireland/state-server/pkg/server"
+)
+
+// Requests the audit report from the running State Server and writes the overlaps and gaps between
+// all of the states in its data store to w as a GeoJSON FeatureCollection. The server is found at the
+// given URL, or on the configured port of this host if no URL is given. The report comes from the
+// server rather than from opening the data store here, so it covers what the server holds with any
+// backend, including the memory backend, and never touches a data store the server has open
+func auditStates(w io.Writer, args ...string) error {
+	var url string
+	switch len(args) {
+	case 0:
+		config, err := server.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("invalid server configuration: %w", err)
+		}
+		url = fmt.Sprintf("http://localhost:%d", config.Port)
+	case 1:
+		url = strings.TrimSuffix(args[0], "/")
+	default:
+		return fmt.Errorf("usage: state-server audit [url]")
+	}
+
+	resp, err := http.Get(url + "/api/v1/audit")
+	if err != nil {
+		return fmt.Errorf("unable to reach the state server at %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("audit failed with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var report audit.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return fmt.Errorf("invalid audit report: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
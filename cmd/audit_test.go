@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/api/audit"
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/aaronireland/state-server/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditStates(t *testing.T) {
+	store := backend.NewMemoryStore()
+	_, err := store.Create(geospatial.State{Name: "West", Border: []geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 0, Lat: 2}, {Lng: 2, Lat: 2}, {Lng: 2, Lat: 0}, {Lng: 0, Lat: 0}}})
+	assert.Nil(t, err, "data store should add valid state with no errors")
+	_, err = store.Create(geospatial.State{Name: "East", Border: []geospatial.Coordinate{{Lng: 1, Lat: 0}, {Lng: 1, Lat: 2}, {Lng: 3, Lat: 2}, {Lng: 3, Lat: 0}, {Lng: 1, Lat: 0}}})
+	assert.Nil(t, err, "data store should add valid state with no errors")
+
+	running := httptest.NewServer(server.StateServerAPIRouter(backend.WithContext(store)))
+	defer running.Close()
+
+	t.Run("should write the issues found in the data store of the running server as GeoJSON", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Nil(t, auditStates(&out, running.URL+"/"), "audit should not produce an error")
+
+		var report audit.Report
+		assert.Nil(t, json.Unmarshal(out.Bytes(), &report), "output should be valid json")
+		assert.Equal(t, 1, len(report.Features), "overlapping states should produce a single issue")
+		assert.Equal(t, []string{"East", "West"}, report.Features[0].Properties.States, "issue should name both overlapping states")
+	})
+
+	t.Run("should find the server on the configured port without a url", func(t *testing.T) {
+		t.Setenv("PORT", running.URL[strings.LastIndex(running.URL, ":")+1:])
+
+		var out bytes.Buffer
+		assert.Nil(t, auditStates(&out), "audit should not produce an error")
+		assert.Contains(t, out.String(), "FeatureCollection", "output should be the audit report")
+	})
+
+	t.Run("should fail if the server cannot produce the report", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "uh-oh data store no good", http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		err := auditStates(&bytes.Buffer{}, failing.URL)
+		assert.NotNil(t, err, "audit should fail for an error response")
+		assert.Contains(t, err.Error(), "uh-oh data store no good", "error should include the server's response")
+	})
+
+	t.Run("should fail if the server is not running", func(t *testing.T) {
+		stopped := httptest.NewServer(http.NotFoundHandler())
+		stopped.Close()
+
+		assert.NotNil(t, auditStates(&bytes.Buffer{}, stopped.URL), "audit should fail when the server cannot be reached")
+		assert.NotNil(t, auditStates(&bytes.Buffer{}, stopped.URL, running.URL), "audit should fail for more than one url")
+	})
+
+	t.Run("should fail for an invalid port", func(t *testing.T) {
+		t.Setenv("PORT", "oops")
+		assert.NotNil(t, auditStates(&bytes.Buffer{}), "audit should fail for an invalid server configuration")
+	})
+}
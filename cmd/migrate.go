@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/server"
+)
+
+// Copies everything in the data store of one backend into the data store of another, replacing anything
+// already in the target, e.g. to move the snapshot and write-ahead log of the file backend into a bolt
+// database. Each backend is given by its name followed by a colon and its options in the format of
+// STATE_SERVER_BACKEND_OPTIONS. The versions of the states are kept along with their history. Both data
+// stores are opened here, so the server must not be running with either of them
+func migrateStores(w io.Writer, args ...string) (err error) {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(w)
+	from := flags.String("from", "", "the backend to migrate from, e.g. file:dir:/var/lib/state-server")
+	to := flags.String("to", "", "the backend to migrate into, e.g. bolt:path:/var/lib/state-server/states.db")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" || flags.NArg() > 0 {
+		return fmt.Errorf("usage: state-server migrate --from <backend>:<options> --to <backend>:<options>")
+	}
+
+	source, err := server.OpenBackendSpec(*from)
+	if err != nil {
+		return err
+	}
+	defer closeStore(source, &err)
+
+	target, err := server.OpenBackendSpec(*to)
+	if err != nil {
+		return err
+	}
+	defer closeStore(target, &err)
+
+	snapshotter, ok := unwrap(source).(interface{ Snapshot() backend.Snapshot })
+	if !ok {
+		return fmt.Errorf("unable to migrate from %s: the backend cannot be copied", *from)
+	}
+	importer, ok := unwrap(target).(interface{ Import(backend.Snapshot) error })
+	if !ok {
+		return fmt.Errorf("unable to migrate into %s: the backend cannot be replaced", *to)
+	}
+
+	snapshot := snapshotter.Snapshot()
+	if err := importer.Import(snapshot); err != nil {
+		return fmt.Errorf("unable to migrate into %s: %w", *to, err)
+	}
+
+	fmt.Fprintf(w, "migrated %d states, %d regions, %d boundaries and %d revisions at version %d\n",
+		len(snapshot.States), len(snapshot.Regions), len(snapshot.Boundaries), len(snapshot.History), snapshot.Version)
+	return nil
+}
+
+// Gets the data store adapted by [backend.WithContext], if it was adapted
+func unwrap(store server.StateLocationDataProvider) interface{} {
+	if wrapped, ok := store.(interface{ Unwrap() backend.Store }); ok {
+		return wrapped.Unwrap()
+	}
+	return store
+}
+
+// Closes the data store if it needs to be closed, adding any error from closing it to the error of the migration
+func closeStore(store server.StateLocationDataProvider, err *error) {
+	if closer, ok := store.(io.Closer); ok {
+		*err = errors.Join(*err, closer.Close())
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateStores(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	dir := t.TempDir()
+	files, err := backend.NewFileStore(dir, 0)
+	assert.Nil(t, err, "file store should open an empty directory")
+	_, err = files.CreateAs("alice", geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+	assert.Nil(t, err, "data store should add valid state with no errors")
+	_, err = files.Create(geospatial.State{Name: "East", Border: square(10, 0, 10)})
+	assert.Nil(t, err, "data store should add valid state with no errors")
+	_, err = files.CreateRegion(geospatial.Region{Name: "Both", States: []string{"West", "East"}})
+	assert.Nil(t, err, "data store should add valid region with no errors")
+	_, err = files.CreateBoundary([]string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+	assert.Nil(t, err, "data store should add valid county with no errors")
+	_, err = files.Update("ws", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10)})
+	assert.Nil(t, err, "data store should update state with no errors")
+	want := files.Snapshot()
+	assert.Nil(t, files.Close(), "file store should close with no errors")
+
+	t.Run("should copy the file backend into a bolt database", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.db")
+
+		var out bytes.Buffer
+		assert.Nil(t, migrateStores(&out, "--from", "file:dir:"+dir, "--to", "bolt:path:"+path), "migrate should not produce an error")
+		assert.Contains(t, out.String(), "migrated 2 states", "migrate should report what it copied")
+
+		migrated, err := backend.NewBoltStore(path)
+		assert.Nil(t, err, "the migrated database should open")
+		assert.Equal(t, want, migrated.Snapshot(), "every state, region, boundary and revision should be copied")
+		assert.Nil(t, migrated.Close(), "bolt store should close with no errors")
+	})
+
+	t.Run("should copy a bolt database into a sqlite database", func(t *testing.T) {
+		bolt, sqlite := filepath.Join(t.TempDir(), "states.db"), filepath.Join(t.TempDir(), "states.sqlite")
+		assert.Nil(t, migrateStores(&bytes.Buffer{}, "--from", "file:dir:"+dir, "--to", "bolt:path:"+bolt), "migrate should not produce an error")
+		assert.Nil(t, migrateStores(&bytes.Buffer{}, "--from", "bolt:path:"+bolt, "--to", "sqlite:path:"+sqlite), "migrate should not produce an error")
+
+		migrated, err := backend.NewSQLiteStore(sqlite)
+		assert.Nil(t, err, "the migrated database should open")
+		assert.Equal(t, want, migrated.Snapshot(), "every state, region, boundary and revision should be copied")
+		assert.Nil(t, migrated.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should fail for invalid arguments", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.db")
+		assert.NotNil(t, migrateStores(&bytes.Buffer{}, "--from", "file:dir:"+dir), "migrate should fail without a target")
+		assert.NotNil(t, migrateStores(&bytes.Buffer{}, "--from", "file:dir:"+dir, "--to", "bolt:path:"+path, "extra"), "migrate should fail for extra arguments")
+		assert.NotNil(t, migrateStores(&bytes.Buffer{}, "--from", "nope:dir:"+dir, "--to", "bolt:path:"+path), "migrate should fail for an unknown backend")
+		assert.NotNil(t, migrateStores(&bytes.Buffer{}, "--from", "file:"+dir, "--to", "bolt:path:"+path), "migrate should fail for invalid options")
+		assert.NotNil(t, migrateStores(&bytes.Buffer{}, "--from", "file:dir:"+dir, "--to", "memory"), "migrate should fail for a backend which cannot be replaced")
+	})
+}
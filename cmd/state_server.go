@@ -13,31 +13,56 @@
 // Stop the server:
 //
 //	/path/to/state-server stop
+//
+// Report the overlaps and gaps between the states held by the running server as GeoJSON, optionally
+// giving the URL of a server which is not listening on the configured port of this host:
+//
+//	/path/to/state-server audit [http://localhost:8080]
+//
+// Copy everything in the data store of one backend into another while the server is stopped, giving each
+// backend as its name followed by its options in the format of STATE_SERVER_BACKEND_OPTIONS:
+//
+//	/path/to/state-server migrate --from file:dir:/var/lib/state-server --to bolt:path:/var/lib/state-server/states.db
 package cmd
 
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
-	"github.com/aaronireland/state-server/pkg/api/backend"
 	"github.com/aaronireland/state-server/pkg/server"
 )
 
 // The main command function that runs the State Server HTTP server
 func StateServer(args ...string) error {
+	if len(args) > 1 && args[1] == "audit" {
+		return auditStates(os.Stdout, args[2:]...)
+	}
+	if len(args) > 1 && args[1] == "migrate" {
+		return migrateStores(os.Stdout, args[2:]...)
+	}
+
 	command, action, backgroundProcess := parseArgs(args...)
 
 	if backgroundProcess {
 		return daemon(command, action)
 	}
 
-	store := backend.NewMemoryStore()
 	config, err := server.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("invalid server configuration: %w", err)
 	}
 
+	store, err := server.OpenBackend(config)
+	if err != nil {
+		return err
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
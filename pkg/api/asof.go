@@ -0,0 +1,21 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Parses the optional asOf query parameter, an RFC 3339 timestamp at which a request is evaluated against
+// the data store as it was at that time. Returns the zero time if the parameter is not given
+func ParseAsOf(params url.Values) (time.Time, error) {
+	if !params.Has("asOf") {
+		return time.Time{}, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339Nano, params.Get("asOf"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid asOf: %s, expecting an RFC 3339 timestamp", params.Get("asOf"))
+	}
+	return asOf, nil
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"sort"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// Compares the border of every state with the border of every other state and reports each
+// pair of states which overlap along with the gaps between states whose borders run alongside
+// each other within the tolerance (in degrees). Issues are ordered largest area first
+func Analyze(states []geospatial.State, tolerance float64) Report {
+	issues := []Issue{}
+
+	for i, a := range states {
+		for _, b := range states[i+1:] {
+			if !a.Border.Bounds().Expand(tolerance).Intersects(b.Border.Bounds()) {
+				continue
+			}
+
+			names := []string{a.Name, b.Name}
+			sort.Strings(names)
+
+			if overlap := a.Border.Intersection(b.Border); len(overlap) > 0 {
+				issues = append(issues, newIssue(Overlap, names, overlap))
+			}
+			if gaps := geospatial.BorderGaps(a.Border, b.Border, tolerance); len(gaps) > 0 {
+				issues = append(issues, newIssue(Gap, names, gaps))
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Properties.Area > issues[j].Properties.Area
+	})
+
+	return Report{Type: "FeatureCollection", Features: issues}
+}
+
+func newIssue(problem Problem, states []string, region geospatial.MultiPolygon) Issue {
+	return Issue{
+		Type:       "Feature",
+		Properties: IssueProperties{Problem: problem, States: states, Area: region.Area()},
+		Geometry:   api.NewGeometry(region),
+	}
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/api/backend"
+)
+
+type RouteHandler struct {
+	store DataProvider
+}
+
+// HTTP request handler for the /api/v1/audit endpoint renders the overlaps and gaps
+// between every state in the data store as a GeoJSON FeatureCollection
+func (h RouteHandler) AuditStates(w http.ResponseWriter, r *http.Request) {
+	states, err := h.store.GetAll(r.Context())
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	api.GeoJSON(w, r, Analyze(states, backend.BorderTolerance))
+}
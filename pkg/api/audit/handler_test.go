@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockDataProvider struct {
+	Err    error
+	States []geospatial.State
+}
+
+func (m mockDataProvider) GetAll(ctx context.Context) ([]geospatial.State, error) {
+	return m.States, m.Err
+}
+
+func square(name string, minLng, minLat, size float64) geospatial.State {
+	return geospatial.State{Name: name, Border: geospatial.Polygon{
+		{Lng: minLng, Lat: minLat},
+		{Lng: minLng, Lat: minLat + size},
+		{Lng: minLng + size, Lat: minLat + size},
+		{Lng: minLng + size, Lat: minLat},
+		{Lng: minLng, Lat: minLat},
+	}}
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Run("should report overlapping states", func(t *testing.T) {
+		got := Analyze([]geospatial.State{square("West", 0, 0, 2), square("East", 1, 0, 2)}, 0.05)
+
+		assert.Equal(t, 1, len(got.Features), "expect a single issue")
+		issue := got.Features[0]
+		assert.Equal(t, Overlap, issue.Properties.Problem, "overlapping states should be reported as an overlap")
+		assert.Equal(t, []string{"East", "West"}, issue.Properties.States, "states should be sorted by name")
+		assert.Equal(t, "Polygon", issue.Geometry.Type, "a single overlapping area should be a Polygon")
+		overlap := geospatial.Polygon{{Lng: 1, Lat: 0}, {Lng: 1, Lat: 2}, {Lng: 2, Lat: 2}, {Lng: 2, Lat: 0}, {Lng: 1, Lat: 0}}
+		assert.InEpsilon(t, overlap.Area(), issue.Properties.Area, 1e-6, "expect the 1x2 degree overlap")
+	})
+
+	t.Run("should report gaps between neighboring states", func(t *testing.T) {
+		got := Analyze([]geospatial.State{square("West", 0, 0, 1), square("East", 1.02, 0, 1)}, 0.05)
+
+		assert.Equal(t, 1, len(got.Features), "expect a single issue")
+		assert.Equal(t, Gap, got.Features[0].Properties.Problem, "space between neighboring states should be reported as a gap")
+		assert.Greater(t, got.Features[0].Properties.Area, float64(0), "gap should have an area")
+	})
+
+	t.Run("should order issues by area and ignore consistent borders", func(t *testing.T) {
+		got := Analyze([]geospatial.State{
+			square("A", 0, 0, 1), square("B", 1, 0, 1), square("C", 0.5, 0.9, 1), square("D", 10, 10, 1),
+		}, 0.05)
+
+		assert.Equal(t, 2, len(got.Features), "C overlaps both A and B, which share an exact border")
+		assert.GreaterOrEqual(t, got.Features[0].Properties.Area, got.Features[1].Properties.Area, "largest issues first")
+	})
+}
+
+func TestAuditHandler(t *testing.T) {
+	t.Run("should render the report as GeoJSON", func(t *testing.T) {
+		store := mockDataProvider{States: []geospatial.State{square("West", 0, 0, 2), square("East", 1, 0, 2)}}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+
+		http.HandlerFunc(RouteHandler{store}.AuditStates).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, api.ContentTypeGeoJSON, rr.Header().Get("Content-Type"), "response should be GeoJSON")
+
+		var report Report
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&report), "should be able to decode response json")
+		assert.Equal(t, "FeatureCollection", report.Type, "report should be a FeatureCollection")
+		assert.Equal(t, 1, len(report.Features), "expect a single issue")
+	})
+
+	t.Run("should render an empty collection when there are no issues", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+
+		http.HandlerFunc(RouteHandler{mockDataProvider{}}.AuditStates).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.JSONEq(t, `{"type":"FeatureCollection","features":[]}`, rr.Body.String(), "report with no issues should have an empty list of features")
+	})
+
+	t.Run("should respond with an error when the data store fails", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+
+		http.HandlerFunc(RouteHandler{mockDataProvider{Err: fmt.Errorf("boom")}}.AuditStates).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
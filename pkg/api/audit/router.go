@@ -0,0 +1,29 @@
+// Package audit provides the router and handler for the State Server
+// endpoint which reports where the borders of the stored states are
+// inconsistent with each other, i.e. where states overlap or where
+// there are gaps between neighboring states
+package audit
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// Injects the dependcies required by the handler for the
+// backend data store
+type DataProvider interface {
+	GetAll(ctx context.Context) ([]geospatial.State, error)
+}
+
+// Maps the handler to the REST API endpoint for the audit API
+func Router(store DataProvider) chi.Router {
+	router := chi.NewRouter()
+	handler := RouteHandler{store}
+
+	router.Get("/", handler.AuditStates)
+
+	return router
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/aaronireland/state-server/pkg/api"
+)
+
+// The kind of inconsistency found between the borders of two states
+type Problem string
+
+const (
+	// The area is inside the borders of both states
+	Overlap Problem = "overlap"
+	// The area lies between the borders of two neighboring states but is inside neither
+	Gap Problem = "gap"
+)
+
+// GeoJSON schema for the properties object of an [Issue]. Area is given in square kilometers
+type IssueProperties struct {
+	Problem Problem  `json:"problem"`
+	States  []string `json:"states"`
+	Area    float64  `json:"area"`
+}
+
+// GeoJSON schema for a feature object describing the region in which
+// the borders of two states are inconsistent with each other
+type Issue struct {
+	Type       string          `json:"type"`
+	Properties IssueProperties `json:"properties"`
+	Geometry   api.Geometry    `json:"geometry"`
+}
+
+// GeoJSON schema for the FeatureCollection of every [Issue] found in the data store
+type Report struct {
+	Type     string  `json:"type"`
+	Features []Issue `json:"features"`
+}
+
+// render method which hooks into the go-chi renderer
+func (rr Report) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
@@ -0,0 +1,586 @@
+// Package backendtest provides a conformance test suite for the data stores behind the state-server API, so
+// that every implementation of the [server.StateLocationDataProvider] interface, including those built outside
+// of the state-server, behaves the same way as [backend.StateLocationMemoryStore]: names are matched ignoring
+// case, duplicates are rejected, failures are reported with the error types of the backend package and
+// concurrent changes are safe.
+//
+// # Usage
+//
+//	func TestConformance(t *testing.T) {
+//		backendtest.Run(t, func(t *testing.T) backendtest.DataStore {
+//			return backend.WithContext(mystore.New(t.TempDir()))
+//		})
+//	}
+package backendtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+// The methods of a data store which are checked by the conformance tests
+type DataStore interface {
+	GetAll(ctx context.Context) ([]geospatial.State, error)
+	GetByName(ctx context.Context, name string) (geospatial.State, error)
+	GetContaining(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, error)
+	GetContainingWithVersion(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error)
+	GetHistory(ctx context.Context, name string) ([]backend.Revision, error)
+	GetAllAsOf(ctx context.Context, at time.Time) ([]geospatial.State, error)
+	GetByNameAsOf(ctx context.Context, name string, at time.Time) (geospatial.State, error)
+	GetContainingAsOf(ctx context.Context, coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error)
+	Search(ctx context.Context, query string, limit int) ([]backend.SearchMatch, error)
+	Create(ctx context.Context, state geospatial.State) (geospatial.State, error)
+	Update(ctx context.Context, name string, version uint64, state geospatial.State) (geospatial.State, error)
+	Delete(ctx context.Context, name string, version uint64) error
+	GetNeighbors(ctx context.Context, name string) ([]geospatial.Neighbor, error)
+	Version() uint64
+	GetAllRegions(ctx context.Context) ([]geospatial.Region, error)
+	GetRegion(ctx context.Context, name string) (geospatial.Region, error)
+	CreateRegion(ctx context.Context, region geospatial.Region) (geospatial.Region, error)
+	DeleteRegion(ctx context.Context, name string) error
+	GetBoundary(ctx context.Context, path []string) (geospatial.Boundary, error)
+	GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error)
+	CreateBoundary(ctx context.Context, parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error)
+	DeleteBoundary(ctx context.Context, path []string) error
+}
+
+// Runs the conformance tests against the data stores returned by newStore, which is called once for
+// each test and must return a new, empty data store. Data stores which implement [io.Closer] are
+// closed when the test which opened them finishes
+func Run(t *testing.T, newStore func(t *testing.T) DataStore) {
+	open := func(t *testing.T) DataStore {
+		store := newStore(t)
+		if closer, ok := store.(io.Closer); ok {
+			t.Cleanup(func() {
+				assert.Nil(t, closer.Close(), "data store should close with no errors")
+			})
+		}
+		return store
+	}
+
+	t.Run("should create, get, update and delete states", func(t *testing.T) {
+		testStates(t, open(t))
+	})
+	t.Run("should find states, regions and boundaries ignoring case and whitespace", func(t *testing.T) {
+		testCaseInsensitivity(t, open(t))
+	})
+	t.Run("should produce ConflictError for duplicates", func(t *testing.T) {
+		testDuplicates(t, open(t))
+	})
+	t.Run("should produce the backend error types", func(t *testing.T) {
+		testErrors(t, open(t))
+	})
+	t.Run("should only change states which are still at the expected version", func(t *testing.T) {
+		testVersions(t, open(t))
+	})
+	t.Run("should keep regions and boundaries in step with their states", func(t *testing.T) {
+		testHierarchy(t, open(t))
+	})
+	t.Run("should find states by location, neighbors and search", func(t *testing.T) {
+		testLookups(t, open(t))
+	})
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		testConcurrency(t, open(t))
+	})
+	t.Run("should not change anything once the context is cancelled", func(t *testing.T) {
+		testCancellation(t, open(t))
+	})
+	t.Run("should record the history of each state and answer point-in-time queries", func(t *testing.T) {
+		testHistory(t, open(t))
+	})
+}
+
+// A clockwise square with its south-west corner at the coordinate
+func square(lng, lat, size float64) []geospatial.Coordinate {
+	return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+}
+
+func stateNames(states []geospatial.State) []string {
+	names := []string{}
+	for _, state := range states {
+		names = append(names, state.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func create(t *testing.T, store DataStore, state geospatial.State) geospatial.State {
+	ctx := context.Background()
+	created, err := store.Create(ctx, state)
+	assert.Nilf(t, err, "data store should add valid state %s with no errors", state.Name)
+	return created
+}
+
+func testStates(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	all, err := store.GetAll(ctx)
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Empty(t, all, "new data store should be empty")
+
+	before := store.Version()
+	west := create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10), Country: "Squareland", Aliases: []string{"WS"}, Properties: map[string]interface{}{"capital": "Westville"}})
+	assert.Equal(t, "West", west.Name, "created state should keep its name")
+	assert.Equal(t, geospatial.Polygon(square(0, 0, 10)), west.Border, "created state should keep its border")
+	assert.Equal(t, "Squareland", west.Country, "created state should keep its country")
+	assert.Equal(t, []string{"WS"}, west.Aliases, "created state should keep its aliases")
+	assert.Equal(t, "Westville", west.Properties["capital"], "created state should keep its properties")
+	assert.Greater(t, store.Version(), before, "data store version should change when a state is created")
+	assert.Equal(t, store.Version(), west.Version, "created state should be at the data store version")
+
+	got, err := store.GetByName(ctx, "West")
+	assert.Nil(t, err, "GetByName should find the created state")
+	assert.Equal(t, west, got, "GetByName should return the state as it was created")
+
+	create(t, store, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+	all, err = store.GetAll(ctx)
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Equal(t, []string{"East", "West"}, stateNames(all))
+
+	updated, err := store.Update(ctx, "West", 0, geospatial.State{Name: "West", Border: square(0, 0, 5), Properties: map[string]interface{}{"capital": "Newton"}})
+	assert.Nil(t, err, "Update should not produce an error for a valid state")
+	assert.Greater(t, updated.Version, west.Version, "updated state should have a new version")
+	got, err = store.GetByName(ctx, "West")
+	assert.Nil(t, err, "GetByName should find the updated state")
+	assert.Equal(t, updated, got, "GetByName should return the state as it was updated")
+	assert.Empty(t, got.Aliases, "aliases left out of the update should be removed")
+
+	renamed, err := store.Update(ctx, "west", 0, geospatial.State{Name: "Western", Border: square(0, 0, 5)})
+	assert.Nil(t, err, "Update should rename the state")
+	assert.Equal(t, "Western", renamed.Name)
+	_, err = store.GetByName(ctx, "West")
+	var notFound *backend.StateNotFoundError
+	assert.True(t, errors.As(err, &notFound), "renamed state should not be found by its old name")
+
+	assert.Nil(t, store.Delete(ctx, "Western", 0), "Delete should not produce an error")
+	_, err = store.GetByName(ctx, "Western")
+	assert.True(t, errors.As(err, &notFound), "deleted state should not be found")
+	all, err = store.GetAll(ctx)
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Equal(t, []string{"East"}, stateNames(all))
+
+	again := create(t, store, geospatial.State{Name: "Western", Border: square(0, 0, 5)})
+	assert.Greater(t, again.Version, renamed.Version, "versions should not be reused after a state is deleted")
+}
+
+func testCaseInsensitivity(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	created := create(t, store, geospatial.State{Name: "New  Mexico", Border: square(0, 0, 10), Aliases: []string{"NM", "35"}})
+	assert.Equal(t, "New Mexico", created.Name, "state name should keep the casing given with its whitespace collapsed")
+
+	for _, name := range []string{"New Mexico", "new mexico", "NEW MEXICO", " new   MEXICO ", "nm", "Nm", "35"} {
+		got, err := store.GetByName(ctx, name)
+		if assert.Nilf(t, err, "GetByName should find %q", name) {
+			assert.Equal(t, "New Mexico", got.Name, "GetByName should return the name the state was created with")
+		}
+	}
+
+	_, err := store.CreateRegion(ctx, geospatial.Region{Name: "South West", States: []string{"new mexico"}})
+	assert.Nil(t, err, "region members should be found ignoring case")
+	region, err := store.GetRegion(ctx, "SOUTH west")
+	assert.Nil(t, err, "GetRegion should ignore case")
+	assert.Equal(t, "South West", region.Name)
+	assert.Equal(t, []string{"New Mexico"}, region.States, "region members should be named as they were created")
+
+	_, err = store.CreateBoundary(ctx, []string{"nm"}, geospatial.Boundary{Name: "Santa Fe", Border: square(1, 1, 2)})
+	assert.Nil(t, err, "boundaries should be added beneath a state's alias")
+	county, err := store.GetBoundary(ctx, []string{"NEW MEXICO", "santa fe"})
+	assert.Nil(t, err, "GetBoundary should ignore case")
+	assert.Equal(t, "Santa Fe", county.Name)
+	assert.Equal(t, []string{"New Mexico"}, county.Path, "boundary paths should use the names given")
+
+	assert.Nil(t, store.DeleteBoundary(ctx, []string{"35", "SANTA FE"}), "DeleteBoundary should ignore case")
+	assert.Nil(t, store.DeleteRegion(ctx, "south west"), "DeleteRegion should ignore case")
+	assert.Nil(t, store.Delete(ctx, "nm", 0), "Delete should find the state by alias")
+	all, err := store.GetAll(ctx)
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Empty(t, all, "state deleted by alias should be removed")
+}
+
+func testDuplicates(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	var conflict *backend.ConflictError
+	create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+	east := create(t, store, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+
+	for _, state := range []geospatial.State{
+		{Name: "west", Border: square(20, 0, 10)},
+		{Name: "WS", Border: square(20, 0, 10)},
+		{Name: "North", Border: square(20, 0, 10), Aliases: []string{"ws"}},
+		{Name: "North", Border: square(20, 0, 10), Aliases: []string{"West"}},
+	} {
+		_, err := store.Create(ctx, state)
+		assert.Truef(t, errors.As(err, &conflict), "state %s with aliases %v should produce ConflictError", state.Name, state.Aliases)
+	}
+
+	_, err := store.Update(ctx, "East", 0, geospatial.State{Name: "West", Border: east.Border})
+	assert.True(t, errors.As(err, &conflict), "renaming a state to the name of another should produce ConflictError")
+	_, err = store.Update(ctx, "East", 0, geospatial.State{Name: "East", Border: east.Border, Aliases: []string{"ws"}})
+	assert.True(t, errors.As(err, &conflict), "giving a state the alias of another should produce ConflictError")
+
+	all, err := store.GetAll(ctx)
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Equal(t, []string{"East", "West"}, stateNames(all), "conflicting states should not be added")
+
+	_, err = store.CreateRegion(ctx, geospatial.Region{Name: "Pair", States: []string{"West", "East"}})
+	assert.Nil(t, err, "data store should add valid region with no errors")
+	_, err = store.CreateRegion(ctx, geospatial.Region{Name: "PAIR", States: []string{"West"}})
+	assert.True(t, errors.As(err, &conflict), "duplicate region should produce ConflictError")
+
+	_, err = store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+	assert.Nil(t, err, "data store should add valid county with no errors")
+	_, err = store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "north", Border: square(0, 5, 5)})
+	assert.True(t, errors.As(err, &conflict), "duplicate county should produce ConflictError")
+}
+
+func testErrors(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+
+	var stateNotFound *backend.StateNotFoundError
+	_, err := store.GetByName(ctx, "Missing")
+	assert.True(t, errors.As(err, &stateNotFound), "GetByName should produce StateNotFoundError")
+	_, err = store.Update(ctx, "Missing", 0, geospatial.State{Name: "Missing", Border: square(20, 0, 10)})
+	assert.True(t, errors.As(err, &stateNotFound), "Update should produce StateNotFoundError")
+	assert.True(t, errors.As(store.Delete(ctx, "Missing", 0), &stateNotFound), "Delete should produce StateNotFoundError")
+	_, err = store.GetNeighbors(ctx, "Missing")
+	assert.True(t, errors.As(err, &stateNotFound), "GetNeighbors should produce StateNotFoundError")
+	_, err = store.CreateBoundary(ctx, []string{"Missing"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+	assert.True(t, errors.As(err, &stateNotFound), "CreateBoundary should produce StateNotFoundError beneath a missing state")
+	assert.True(t, errors.As(store.DeleteBoundary(ctx, []string{"Missing", "North"}), &stateNotFound), "DeleteBoundary should produce StateNotFoundError beneath a missing state")
+
+	var invalidState *backend.InvalidStateError
+	for _, state := range []geospatial.State{
+		{Name: "Open", Border: square(20, 0, 10)[:4]},
+		{Name: "Short", Border: square(20, 0, 10)[:3]},
+		{Name: "X", Border: square(20, 0, 10)},
+	} {
+		_, err := store.Create(ctx, state)
+		assert.Truef(t, errors.As(err, &invalidState), "invalid state %s should produce InvalidStateError", state.Name)
+	}
+	_, err = store.Update(ctx, "West", 0, geospatial.State{Name: "West", Border: square(0, 0, 10)[:4]})
+	assert.True(t, errors.As(err, &invalidState), "invalid update should produce InvalidStateError")
+	got, err := store.GetByName(ctx, "West")
+	assert.Nil(t, err, "state should be kept after an invalid update")
+	assert.Equal(t, geospatial.Polygon(square(0, 0, 10)), got.Border, "state should be unchanged after an invalid update")
+
+	var regionNotFound *backend.RegionNotFoundError
+	_, err = store.GetRegion(ctx, "Missing")
+	assert.True(t, errors.As(err, &regionNotFound), "GetRegion should produce RegionNotFoundError")
+	assert.True(t, errors.As(store.DeleteRegion(ctx, "Missing"), &regionNotFound), "DeleteRegion should produce RegionNotFoundError")
+
+	var invalidRegion *backend.InvalidRegionError
+	_, err = store.CreateRegion(ctx, geospatial.Region{Name: "Nowhere", States: []string{"West", "Missing"}})
+	assert.True(t, errors.As(err, &invalidRegion), "region with a missing member should produce InvalidRegionError")
+	_, err = store.CreateRegion(ctx, geospatial.Region{Name: "Empty"})
+	assert.True(t, errors.As(err, &invalidRegion), "region without members should produce InvalidRegionError")
+
+	var boundaryNotFound *backend.BoundaryNotFoundError
+	_, err = store.GetBoundary(ctx, []string{"West", "Missing"})
+	assert.True(t, errors.As(err, &boundaryNotFound), "GetBoundary should produce BoundaryNotFoundError")
+	_, err = store.GetChildren(ctx, []string{"West", "Missing"})
+	assert.True(t, errors.As(err, &boundaryNotFound), "GetChildren should produce BoundaryNotFoundError")
+	assert.True(t, errors.As(store.DeleteBoundary(ctx, []string{"West", "Missing"}), &boundaryNotFound), "DeleteBoundary should produce BoundaryNotFoundError")
+
+	var invalidBoundary *backend.InvalidBoundaryError
+	_, err = store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "Away", Border: square(50, 50, 1)})
+	assert.True(t, errors.As(err, &invalidBoundary), "boundary outside its state should produce InvalidBoundaryError")
+	_, err = store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "Bad", Border: square(0, 0, 1)[:3]})
+	assert.True(t, errors.As(err, &invalidBoundary), "boundary with an invalid border should produce InvalidBoundaryError")
+}
+
+func testVersions(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	west := create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+
+	var mismatch *backend.VersionMismatchError
+	_, err := store.Update(ctx, "West", west.Version+1, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+	assert.True(t, errors.As(err, &mismatch), "Update at another version should produce VersionMismatchError")
+	assert.Equal(t, west.Version, mismatch.Current, "VersionMismatchError should give the current version")
+
+	updated, err := store.Update(ctx, "West", west.Version, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+	assert.Nil(t, err, "Update at the current version should not produce an error")
+
+	_, err = store.Update(ctx, "West", west.Version, geospatial.State{Name: "West", Border: square(0, 0, 8)})
+	assert.True(t, errors.As(err, &mismatch), "Update at a stale version should produce VersionMismatchError")
+	assert.True(t, errors.As(store.Delete(ctx, "West", west.Version), &mismatch), "Delete at a stale version should produce VersionMismatchError")
+
+	got, err := store.GetByName(ctx, "West")
+	assert.Nil(t, err, "state should be kept after a version mismatch")
+	assert.Equal(t, updated, got, "state should be unchanged after a version mismatch")
+
+	assert.Nil(t, store.Delete(ctx, "West", updated.Version), "Delete at the current version should not produce an error")
+}
+
+func testHierarchy(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+	create(t, store, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+
+	created, err := store.CreateRegion(ctx, geospatial.Region{Name: "Pair", States: []string{"West", "East"}})
+	assert.Nil(t, err, "data store should add valid region with no errors")
+	assert.Equal(t, []string{"West", "East"}, created.States)
+	assert.True(t, created.Contains(geospatial.Coordinate{Lng: 15, Lat: 5}), "region border should cover its members")
+	regions, err := store.GetAllRegions(ctx)
+	assert.Nil(t, err, "GetAllRegions should not produce an error")
+	assert.Equal(t, 1, len(regions))
+
+	county, err := store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+	assert.Nil(t, err, "data store should add valid county with no errors")
+	assert.Equal(t, geospatial.LevelCounty, county.Level, "boundaries beneath a state are counties")
+	place, err := store.CreateBoundary(ctx, []string{"West", "North"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+	assert.Nil(t, err, "data store should add valid place with no errors")
+	assert.Equal(t, geospatial.LevelPlace, place.Level, "boundaries beneath a county are places")
+	assert.Equal(t, []string{"West", "North"}, place.Path)
+
+	children, err := store.GetChildren(ctx, []string{"West"})
+	assert.Nil(t, err, "GetChildren should not produce an error")
+	assert.Equal(t, []geospatial.Boundary{county}, children)
+
+	_, err = store.Update(ctx, "West", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10)})
+	assert.Nil(t, err, "Update should rename the state")
+	region, err := store.GetRegion(ctx, "Pair")
+	assert.Nil(t, err, "region should be kept when a member is renamed")
+	assert.Equal(t, []string{"Western", "East"}, region.States, "region should follow its renamed member")
+	moved, err := store.GetBoundary(ctx, []string{"Western", "North", "Town"})
+	assert.Nil(t, err, "boundaries should be found beneath the new name")
+	assert.Equal(t, []string{"Western", "North"}, moved.Path, "boundary paths should follow the new name")
+
+	assert.Nil(t, store.Delete(ctx, "East", 0), "Delete should not produce an error")
+	region, err = store.GetRegion(ctx, "Pair")
+	assert.Nil(t, err, "region should be kept when a member is deleted")
+	assert.Equal(t, []string{"Western"}, region.States, "deleted member should be left out of the region")
+	assert.False(t, region.Contains(geospatial.Coordinate{Lng: 15, Lat: 5}), "region border should no longer cover the deleted member")
+
+	assert.Nil(t, store.DeleteBoundary(ctx, []string{"Western", "North"}), "DeleteBoundary should not produce an error")
+	var boundaryNotFound *backend.BoundaryNotFoundError
+	_, err = store.GetBoundary(ctx, []string{"Western", "North", "Town"})
+	assert.True(t, errors.As(err, &boundaryNotFound), "places should be removed with their county")
+
+	assert.Nil(t, store.DeleteRegion(ctx, "Pair"), "DeleteRegion should not produce an error")
+	_, err = store.GetByName(ctx, "Western")
+	assert.Nil(t, err, "members should be kept when their region is deleted")
+}
+
+func testLookups(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+	create(t, store, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+	create(t, store, geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+	create(t, store, geospatial.State{Name: "Over", Border: square(5, 5, 10)})
+
+	for coord, want := range map[geospatial.Coordinate][]string{
+		{Lng: 2, Lat: 2}:       {"West"},
+		{Lng: 7, Lat: 7}:       {"Over", "West"},
+		{Lng: 12, Lat: 7}:      {"East", "Over"},
+		{Lng: 50.5, Lat: 50.5}: {"Far"},
+		{Lng: 30, Lat: 30}:     {},
+	} {
+		states, err := store.GetContaining(ctx, coord)
+		assert.Nil(t, err, "GetContaining should not produce an error")
+		assert.Equalf(t, want, stateNames(states), "states containing %s", coord.String())
+
+		states, version, err := store.GetContainingWithVersion(ctx, coord)
+		assert.Nil(t, err, "GetContainingWithVersion should not produce an error")
+		assert.Equalf(t, want, stateNames(states), "states containing %s", coord.String())
+		assert.Equal(t, store.Version(), version, "states should be read at the current data store version")
+	}
+
+	neighbors, err := store.GetNeighbors(ctx, "west")
+	assert.Nil(t, err, "GetNeighbors should not produce an error")
+	var names []string
+	for _, neighbor := range neighbors {
+		names = append(names, neighbor.Name)
+	}
+	assert.Contains(t, names, "East", "states sharing a border should be neighbors")
+	assert.NotContains(t, names, "Far", "distant states should not be neighbors")
+
+	matches, err := store.Search(ctx, "eas", 5)
+	assert.Nil(t, err, "Search should not produce an error")
+	if assert.NotEmpty(t, matches, "Search should find states by prefix") {
+		assert.Equal(t, "East", matches[0].State)
+	}
+}
+
+func testConcurrency(t *testing.T, store DataStore) {
+	ctx := context.Background()
+	const writers = 16
+
+	var wg sync.WaitGroup
+	created := make([]geospatial.State, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			state, err := store.Create(ctx, geospatial.State{Name: fmt.Sprintf("State %d", i), Border: square(float64(i*2), 60, 1)})
+			assert.Nil(t, err, "concurrent creates should not produce an error")
+			created[i] = state
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := store.GetAll(ctx)
+			assert.Nil(t, err, "GetAll should not produce an error while states are created")
+			_, err = store.GetContaining(ctx, geospatial.Coordinate{Lng: 0.5, Lat: 60.5})
+			assert.Nil(t, err, "GetContaining should not produce an error while states are created")
+		}()
+	}
+	wg.Wait()
+
+	all, err := store.GetAll(ctx)
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Equal(t, writers, len(all), "every concurrently created state should be kept")
+	versions := map[uint64]bool{}
+	for _, state := range created {
+		versions[state.Version] = true
+	}
+	assert.Equal(t, writers, len(versions), "concurrently created states should have distinct versions")
+
+	current := created[0]
+	var mu sync.Mutex
+	succeeded, mismatched := 0, 0
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.Update(ctx, current.Name, current.Version, geospatial.State{Name: current.Name, Border: square(0, 60, 1), Aliases: []string{fmt.Sprintf("S%d", i)}})
+
+			mu.Lock()
+			defer mu.Unlock()
+			var mismatch *backend.VersionMismatchError
+			if err == nil {
+				succeeded++
+			} else if errors.As(err, &mismatch) {
+				mismatched++
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded, "only one concurrent update at the same version should succeed")
+	assert.Equal(t, writers-1, mismatched, "the other concurrent updates should produce VersionMismatchError")
+
+	succeeded, missing := 0, 0
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.Delete(ctx, created[1].Name, 0)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var notFound *backend.StateNotFoundError
+			if err == nil {
+				succeeded++
+			} else if errors.As(err, &notFound) {
+				missing++
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded, "only one concurrent delete of the same state should succeed")
+	assert.Equal(t, writers-1, missing, "the other concurrent deletes should produce StateNotFoundError")
+}
+
+func testCancellation(t *testing.T, store DataStore) {
+	west := create(t, store, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+	version := store.Version()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.GetByName(ctx, "West")
+	assert.ErrorIs(t, err, context.Canceled, "GetByName should produce the context error")
+	_, err = store.Create(ctx, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+	assert.ErrorIs(t, err, context.Canceled, "Create should produce the context error")
+	_, err = store.Update(ctx, "West", 0, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+	assert.ErrorIs(t, err, context.Canceled, "Update should produce the context error")
+	assert.ErrorIs(t, store.Delete(ctx, "West", 0), context.Canceled, "Delete should produce the context error")
+	_, err = store.CreateRegion(ctx, geospatial.Region{Name: "Solo", States: []string{"West"}})
+	assert.ErrorIs(t, err, context.Canceled, "CreateRegion should produce the context error")
+	_, err = store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+	assert.ErrorIs(t, err, context.Canceled, "CreateBoundary should produce the context error")
+
+	assert.Equal(t, version, store.Version(), "data store version should not change with a cancelled context")
+	all, err := store.GetAll(context.Background())
+	assert.Nil(t, err, "GetAll should not produce an error")
+	assert.Equal(t, []geospatial.State{west}, all, "states should be unchanged with a cancelled context")
+	regions, err := store.GetAllRegions(context.Background())
+	assert.Nil(t, err, "GetAllRegions should not produce an error")
+	assert.Empty(t, regions, "no region should be created with a cancelled context")
+}
+
+func testHistory(t *testing.T, store DataStore) {
+	ctx := backend.WithAuthor(context.Background(), "alice")
+	start := time.Now()
+
+	_, err := store.Create(ctx, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+	assert.Nil(t, err, "data store should add valid state with no errors")
+	created := time.Now()
+	time.Sleep(time.Millisecond)
+
+	_, err = store.Update(backend.WithAuthor(ctx, "bob"), "West", 0, geospatial.State{Name: "Western", Border: square(0, 0, 20), Aliases: []string{"WS"}})
+	assert.Nil(t, err, "data store should update state with no errors")
+	renamed := time.Now()
+	time.Sleep(time.Millisecond)
+
+	assert.Nil(t, store.Delete(ctx, "Western", 0), "data store should delete state with no errors")
+
+	history, err := store.GetHistory(ctx, "western")
+	assert.Nil(t, err, "GetHistory should find a deleted state by its name")
+	assert.Equal(t, 3, len(history), "history should have a revision for each change, across the rename")
+	if len(history) == 3 {
+		assert.Equal(t, backend.Created, history[0].Change)
+		assert.Equal(t, "West", history[0].State.Name)
+		assert.Equal(t, "alice", history[0].Author, "revisions should record the author from the context")
+		assert.Equal(t, backend.Updated, history[1].Change)
+		assert.Equal(t, "bob", history[1].Author, "revisions should record the author from the context")
+		assert.Equal(t, "West", history[1].Previous, "renames should record the previous name")
+		assert.Equal(t, []string{"name", "border", "aliases"}, history[1].Fields, "updates should record the fields which changed")
+		assert.Equal(t, backend.Deleted, history[2].Change)
+		for i, revision := range history {
+			assert.False(t, revision.Time.Before(start), "revisions should record when the change was made")
+			if i > 0 {
+				assert.Greater(t, revision.Version, history[i-1].Version, "revisions should be in version order")
+				assert.False(t, revision.Time.Before(history[i-1].Time), "revisions should be in time order")
+			}
+		}
+	}
+
+	_, err = store.GetHistory(ctx, "Nowhere")
+	var notFoundErr *backend.StateNotFoundError
+	assert.ErrorAs(t, err, &notFoundErr, "GetHistory should produce StateNotFoundError for a state which never existed")
+
+	past, err := store.GetByNameAsOf(ctx, "west", created)
+	assert.Nil(t, err, "GetByNameAsOf should find the state as it was before it was renamed")
+	assert.Equal(t, "West", past.Name)
+	assert.Equal(t, geospatial.Polygon(square(0, 0, 10)), past.Border)
+	_, err = store.GetByNameAsOf(ctx, "west", renamed)
+	assert.ErrorAs(t, err, &notFoundErr, "GetByNameAsOf should not find the state by its old name after the rename")
+	past, err = store.GetByNameAsOf(ctx, "ws", renamed)
+	assert.Nil(t, err, "GetByNameAsOf should find the state by an alias it had at the time")
+	assert.Equal(t, "Western", past.Name)
+	_, err = store.GetByNameAsOf(ctx, "west", start.Add(-time.Hour))
+	assert.ErrorAs(t, err, &notFoundErr, "GetByNameAsOf should not find the state before it was created")
+
+	all, err := store.GetAllAsOf(ctx, renamed)
+	assert.Nil(t, err, "GetAllAsOf should not produce an error")
+	assert.Equal(t, []string{"Western"}, stateNames(all))
+	all, err = store.GetAllAsOf(ctx, time.Now())
+	assert.Nil(t, err, "GetAllAsOf should not produce an error")
+	assert.Empty(t, all, "deleted states should not be found after they were deleted")
+
+	containing, err := store.GetContainingAsOf(ctx, geospatial.Coordinate{Lng: 15, Lat: 15}, created)
+	assert.Nil(t, err, "GetContainingAsOf should not produce an error")
+	assert.Empty(t, containing, "location should be outside the border the state had at the time")
+	containing, err = store.GetContainingAsOf(ctx, geospatial.Coordinate{Lng: 15, Lat: 15}, renamed)
+	assert.Nil(t, err, "GetContainingAsOf should not produce an error")
+	assert.Equal(t, []string{"Western"}, stateNames(containing), "location should be inside the border the state had at the time")
+}
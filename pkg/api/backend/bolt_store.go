@@ -0,0 +1,304 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The buckets of the database behind a [StateLocationBoltStore]. States and regions are keyed by their
+// normalized names, boundaries by the normalized names along their path separated by a zero byte so
+// that every boundary sorts after the boundary or state above it, and the revisions in the history of
+// the states by their versions
+var (
+	metaBucket       = []byte("meta")
+	statesBucket     = []byte("states")
+	regionsBucket    = []byte("regions")
+	boundariesBucket = []byte("boundaries")
+	historyBucket    = []byte("history")
+	versionKey       = []byte("version")
+)
+
+// A data store which keeps the states, regions and boundaries in a single database file using the
+// embedded bbolt key-value store, so that they survive restarts without running a database service.
+// Lookups are served from memory, as [StateLocationMemoryStore] does, and each change is written to
+// the database in a single transaction before it is made in memory
+type StateLocationBoltStore struct {
+	*StateLocationMemoryStore
+	db *bolt.DB
+}
+
+// Constructor for the [StateLocationBoltStore] struct opens the database file, creating it if needed,
+// and loads its contents. Fails if the file is already open in another process
+func NewBoltStore(path string) (*StateLocationBoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open data store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{metaBucket, statesBucket, regionsBucket, boundariesBucket, historyBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to open data store %s: %w", path, err)
+	}
+
+	s := &StateLocationBoltStore{StateLocationMemoryStore: NewMemoryStore(), db: db}
+	if err := s.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.StateLocationMemoryStore.journal = s
+
+	return s, nil
+}
+
+// Replaces everything in the data store with the contents of the [Snapshot], e.g. to migrate a
+// [StateLocationMemoryStore] or [StateLocationFileStore] into the database, keeping the versions
+// of the states along with their history. Returns an error, leaving the data store unchanged, if the snapshot is invalid
+func (s *StateLocationBoltStore) Import(snapshot Snapshot) error {
+	restored, err := restoreSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	memory := s.StateLocationMemoryStore
+	memory.changes.Lock()
+	defer memory.changes.Unlock()
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{statesBucket, regionsBucket, boundariesBucket, historyBucket} {
+			if err := tx.DeleteBucket(bucket); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(bucket); err != nil {
+				return err
+			}
+		}
+		return writeSnapshot(tx, restored.Snapshot())
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write to the data store: %w", err)
+	}
+
+	memory.replace(restored)
+	return nil
+}
+
+// Closes the database file. The data store must not be used after it is closed
+func (s *StateLocationBoltStore) Close() error {
+	s.StateLocationMemoryStore.changes.Lock()
+	defer s.StateLocationMemoryStore.changes.Unlock()
+
+	return s.db.Close()
+}
+
+// Writes the change to the database in a single transaction before the change is made in memory. Changes to
+// a state write the state, its revision and the version of the data store, along with every boundary beneath
+// the state and every region it belongs to when the state is renamed. Called with the change lock held
+func (s *StateLocationBoltStore) persist(c change) error {
+	memory := s.StateLocationMemoryStore
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		states, regions := tx.Bucket(statesBucket), tx.Bucket(regionsBucket)
+
+		switch c.op {
+		case opCreateRegion:
+			return putJSON(regions, []byte(c.key), c.region)
+		case opDeleteRegion:
+			return regions.Delete([]byte(c.key))
+		case opCreateBoundary, opDeleteBoundary:
+			return writeBoundaries(tx, c.key, memory.boundariesAfter(c))
+		}
+
+		if err := states.Delete([]byte(c.key)); err != nil {
+			return err
+		}
+		if c.op != opDelete {
+			state := c.revision.State
+			if err := putJSON(states, []byte(normalizeKey(state.Name)), SnapshotState{State: state, Version: state.Version}); err != nil {
+				return err
+			}
+		}
+		if c.op == opDelete || c.revision.Previous != "" {
+			if err := writeBoundaries(tx, c.key, memory.boundariesAfter(c)); err != nil {
+				return err
+			}
+		}
+		if c.revision.Previous != "" {
+			for _, region := range memory.regionsAfter(c) {
+				if err := putJSON(regions, []byte(normalizeKey(region.Name)), region); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := putJSON(tx.Bucket(historyBucket), versionBytes(c.revision.Version), c.revision); err != nil {
+			return err
+		}
+		return tx.Bucket(metaBucket).Put(versionKey, versionBytes(c.revision.Version))
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write to the data store: %w", err)
+	}
+	return nil
+}
+
+// Replaces the boundaries beneath the state with the given key
+func writeBoundaries(tx *bolt.Tx, key string, boundaries []geospatial.Boundary) error {
+	bucket := tx.Bucket(boundariesBucket)
+	if err := deletePrefix(bucket, []byte(key+"\x00")); err != nil {
+		return err
+	}
+	for _, boundary := range boundaries {
+		if err := putJSON(bucket, boundaryKey(boundary), boundary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes everything in the [Snapshot] to the empty buckets of the database
+func writeSnapshot(tx *bolt.Tx, snapshot Snapshot) error {
+	states := tx.Bucket(statesBucket)
+	for _, state := range snapshot.States {
+		if err := putJSON(states, []byte(normalizeKey(state.Name)), state); err != nil {
+			return err
+		}
+	}
+	regions := tx.Bucket(regionsBucket)
+	for _, region := range snapshot.Regions {
+		if err := putJSON(regions, []byte(normalizeKey(region.Name)), region); err != nil {
+			return err
+		}
+	}
+	boundaries := tx.Bucket(boundariesBucket)
+	for _, boundary := range snapshot.Boundaries {
+		if err := putJSON(boundaries, boundaryKey(boundary), boundary); err != nil {
+			return err
+		}
+	}
+	history := tx.Bucket(historyBucket)
+	for _, revision := range snapshot.History {
+		if err := putJSON(history, versionBytes(revision.Version), revision); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(metaBucket).Put(versionKey, versionBytes(snapshot.Version))
+}
+
+// Reads everything in the database into a [Snapshot] and restores it into memory
+func (s *StateLocationBoltStore) load() error {
+	snapshot := Snapshot{States: []SnapshotState{}, Regions: []SnapshotRegion{}, Boundaries: []geospatial.Boundary{}}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if version := tx.Bucket(metaBucket).Get(versionKey); len(version) == 8 {
+			snapshot.Version = binary.BigEndian.Uint64(version)
+		}
+
+		err := tx.Bucket(statesBucket).ForEach(func(key, value []byte) error {
+			var state SnapshotState
+			if err := json.Unmarshal(value, &state); err != nil {
+				return fmt.Errorf("invalid state %s: %w", key, err)
+			}
+			snapshot.States = append(snapshot.States, state)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(regionsBucket).ForEach(func(key, value []byte) error {
+			var region SnapshotRegion
+			if err := json.Unmarshal(value, &region); err != nil {
+				return fmt.Errorf("invalid region %s: %w", key, err)
+			}
+			snapshot.Regions = append(snapshot.Regions, region)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(boundariesBucket).ForEach(func(key, value []byte) error {
+			var boundary geospatial.Boundary
+			if err := json.Unmarshal(value, &boundary); err != nil {
+				return fmt.Errorf("invalid boundary %s: %w", bytes.ReplaceAll(key, []byte{0}, []byte("/")), err)
+			}
+			snapshot.Boundaries = append(snapshot.Boundaries, boundary)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(historyBucket).ForEach(func(key, value []byte) error {
+			var revision Revision
+			if err := json.Unmarshal(value, &revision); err != nil {
+				return fmt.Errorf("invalid revision %d: %w", binary.BigEndian.Uint64(key), err)
+			}
+			snapshot.History = append(snapshot.History, revision)
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read data store: %w", err)
+	}
+
+	sort.Slice(snapshot.States, func(i, j int) bool {
+		return snapshot.States[i].Version < snapshot.States[j].Version
+	})
+
+	return s.StateLocationMemoryStore.Restore(snapshot)
+}
+
+// Encodes a version as a key which sorts in version order
+func versionBytes(version uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, version)
+	return key
+}
+
+// The key of a boundary in the boundaries bucket
+func boundaryKey(boundary geospatial.Boundary) []byte {
+	var names []string
+	for _, name := range append(append([]string{}, boundary.Path...), boundary.Name) {
+		names = append(names, normalizeKey(name))
+	}
+	return []byte(strings.Join(names, "\x00"))
+}
+
+func putJSON(bucket *bolt.Bucket, key []byte, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, data)
+}
+
+// Removes every key in the bucket which starts with the prefix
+func deletePrefix(bucket *bolt.Bucket, prefix []byte) error {
+	var keys [][]byte
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		keys = append(keys, append([]byte{}, key...))
+	}
+	for _, key := range keys {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
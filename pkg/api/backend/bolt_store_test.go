@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+// The changes shared by the data stores, so the same changes can be made to each of them
+type writableStore interface {
+	Create(geospatial.State) (geospatial.State, error)
+	Update(string, uint64, geospatial.State) (geospatial.State, error)
+	Delete(string, uint64) error
+	CreateRegion(geospatial.Region) (geospatial.Region, error)
+	CreateBoundary([]string, geospatial.Boundary) (geospatial.Boundary, error)
+}
+
+func TestBoltStore(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	populate := func(t *testing.T, s writableStore) {
+		_, err := s.Create(geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}, Properties: map[string]interface{}{"fips": "01"}})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.CreateRegion(geospatial.Region{Name: "Both", States: []string{"West", "East", "Far"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+		_, err = s.CreateBoundary([]string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err, "data store should add valid county with no errors")
+		_, err = s.CreateBoundary([]string{"ws", "north"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err, "data store should add valid place with no errors")
+		_, err = s.Update("ws", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+		assert.Nil(t, err, "data store should update state with no errors")
+		assert.Nil(t, s.Delete("Far", 0), "data store should delete state with no errors")
+	}
+
+	t.Run("should recover every change after it is reopened", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.db")
+		s, err := NewBoltStore(path)
+		assert.Nil(t, err, "bolt store should create a new database")
+		populate(t, s)
+		assert.Nil(t, s.DeleteBoundary([]string{"Western", "North", "Town"}), "data store should delete boundary with no errors")
+		want := s.Snapshot()
+		assert.Nil(t, s.Close(), "bolt store should close with no errors")
+
+		reopened, err := NewBoltStore(path)
+		assert.Nil(t, err, "bolt store should reopen")
+		assert.Equal(t, want, reopened.Snapshot(), "bolt store should recover every change")
+
+		county, err := reopened.GetBoundary([]string{"western", "north"})
+		assert.Nil(t, err, "renamed state should keep its counties")
+		assert.Equal(t, []string{"Western"}, county.Path)
+
+		region, err := reopened.GetRegion("both")
+		assert.Nil(t, err, "regions should be recovered")
+		assert.Equal(t, []string{"Western", "East"}, region.States, "regions should follow renamed and deleted members")
+		assert.Nil(t, reopened.Close(), "bolt store should close with no errors")
+	})
+
+	t.Run("should migrate a memory store snapshot", func(t *testing.T) {
+		memory := NewMemoryStore()
+		populate(t, memory)
+
+		path := filepath.Join(t.TempDir(), "states.db")
+		s, err := NewBoltStore(path)
+		assert.Nil(t, err, "bolt store should create a new database")
+		_, err = s.Create(geospatial.State{Name: "Replaced", Border: square(80, 80, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Nil(t, s.Import(memory.Snapshot()), "snapshot should be imported with no errors")
+		assert.Equal(t, memory.Snapshot(), s.Snapshot())
+		assert.Nil(t, s.Close(), "bolt store should close with no errors")
+
+		reopened, err := NewBoltStore(path)
+		assert.Nil(t, err, "bolt store should reopen")
+		assert.Equal(t, memory.Snapshot(), reopened.Snapshot(), "imported snapshot should replace everything in the database")
+
+		_, err = reopened.Create(geospatial.State{Name: "Next", Border: square(80, 80, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		state, err := reopened.GetByName("Next")
+		assert.Nil(t, err, "new state should be found")
+		assert.Equal(t, memory.Version()+1, state.Version, "versions should continue from the imported snapshot")
+
+		invalid := memory.Snapshot()
+		invalid.Boundaries = append(invalid.Boundaries, geospatial.Boundary{Name: "Lost", Path: []string{"Missing"}})
+		assert.NotNil(t, reopened.Import(invalid), "invalid snapshot should not be imported")
+		_, err = reopened.GetByName("Next")
+		assert.Nil(t, err, "data store should be unchanged by an invalid snapshot")
+		assert.Nil(t, reopened.Close(), "bolt store should close with no errors")
+	})
+
+	t.Run("should not make a change which cannot be written to the database", func(t *testing.T) {
+		s, err := NewBoltStore(filepath.Join(t.TempDir(), "states.db"))
+		assert.Nil(t, err, "bolt store should create a new database")
+		populate(t, s)
+		want := s.Snapshot()
+		assert.Nil(t, s.db.Close(), "the database should close")
+
+		_, err = s.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.NotNil(t, err, "Create should fail when the database cannot be written")
+		assert.NotNil(t, s.DeleteBoundary([]string{"Western", "North"}), "DeleteBoundary should fail when the database cannot be written")
+		assert.NotNil(t, s.Import(Snapshot{}), "Import should fail when the database cannot be written")
+		assert.Equal(t, want, s.Snapshot(), "a change which was not written should not be made")
+	})
+
+	t.Run("should return the same errors as the memory store", func(t *testing.T) {
+		s, err := NewBoltStore(filepath.Join(t.TempDir(), "states.db"))
+		assert.Nil(t, err, "bolt store should create a new database")
+		populate(t, s)
+
+		var conflict *ConflictError
+		_, err = s.Create(geospatial.State{Name: "east", Border: square(10, 0, 10)})
+		assert.True(t, errors.As(err, &conflict), "duplicate state should produce ConflictError")
+		_, err = s.CreateRegion(geospatial.Region{Name: "BOTH", States: []string{"East"}})
+		assert.True(t, errors.As(err, &conflict), "duplicate region should produce ConflictError")
+
+		var stateNotFound *StateNotFoundError
+		assert.True(t, errors.As(s.Delete("Far", 0), &stateNotFound), "missing state should produce StateNotFoundError")
+		var regionNotFound *RegionNotFoundError
+		assert.True(t, errors.As(s.DeleteRegion("Missing"), &regionNotFound), "missing region should produce RegionNotFoundError")
+		var boundaryNotFound *BoundaryNotFoundError
+		assert.True(t, errors.As(s.DeleteBoundary([]string{"East", "Missing"}), &boundaryNotFound), "missing boundary should produce BoundaryNotFoundError")
+
+		var mismatch *VersionMismatchError
+		_, err = s.Update("East", 1, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		assert.True(t, errors.As(err, &mismatch), "stale version should produce VersionMismatchError")
+		var invalid *InvalidStateError
+		_, err = s.Create(geospatial.State{Name: "Open", Border: square(80, 80, 1)[:4]})
+		assert.True(t, errors.As(err, &invalid), "invalid state should produce InvalidStateError")
+		assert.Nil(t, s.Close(), "bolt store should close with no errors")
+	})
+}
@@ -0,0 +1,312 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// The methods of the data stores in this package, none of which take a [context.Context]
+type Store interface {
+	GetAll() ([]geospatial.State, error)
+	GetByName(name string) (geospatial.State, error)
+	GetContaining(coord geospatial.Coordinate) ([]geospatial.State, error)
+	GetContainingWithVersion(coord geospatial.Coordinate) ([]geospatial.State, uint64, error)
+	Search(query string, limit int) ([]SearchMatch, error)
+	Create(geospatial.State) (geospatial.State, error)
+	Update(name string, version uint64, state geospatial.State) (geospatial.State, error)
+	Delete(name string, version uint64) error
+	GetNeighbors(name string) ([]geospatial.Neighbor, error)
+	Version() uint64
+	GetAllRegions() ([]geospatial.Region, error)
+	GetRegion(name string) (geospatial.Region, error)
+	CreateRegion(geospatial.Region) (geospatial.Region, error)
+	DeleteRegion(name string) error
+	GetBoundary(path []string) (geospatial.Boundary, error)
+	GetChildren(path []string) ([]geospatial.Boundary, error)
+	CreateBoundary(parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error)
+	DeleteBoundary(path []string) error
+	GetHistory(name string) ([]Revision, error)
+	GetAllAsOf(at time.Time) ([]geospatial.State, error)
+	GetByNameAsOf(name string, at time.Time) (geospatial.State, error)
+	GetContainingAsOf(coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error)
+}
+
+// The methods of the data stores in this package which record who made a change in the state's history
+type authoredStore interface {
+	CreateAs(author string, state geospatial.State) (geospatial.State, error)
+	UpdateAs(author, name string, version uint64, state geospatial.State) (geospatial.State, error)
+	DeleteAs(author, name string, version uint64) error
+}
+
+// The data stores in this package, which keep their contents in a [StateLocationMemoryStore] and persist
+// each change through its journal, so that the context of the request making a change reaches the journal
+type journaledStore interface {
+	memoryStore() *StateLocationMemoryStore
+}
+
+// The data stores in this package which answer location queries from a database rather than from memory,
+// so that the context of the request reaches the query
+type locatingStore interface {
+	locate(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error)
+}
+
+type authorKey struct{}
+
+// Returns a copy of the context which names the author of any change made to a state with it, which
+// the [ContextStore] records in the state's history
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// Gets the author named by the context, if any
+func Author(ctx context.Context) string {
+	author, _ := ctx.Value(authorKey{}).(string)
+	return author
+}
+
+// Adapts a [Store], whose methods do not take a [context.Context], to the context-aware interface used by
+// the API handlers. Each method returns the context's error without calling the data store if the request
+// has already been cancelled or has passed its deadline. Changes made to the data stores in this package
+// hand the context to the data store's journal, so that the SQLite backend runs its transaction with it and
+// rolls back a change whose request is cancelled before the change is committed, in which case the change is
+// not made. The SQLite backend also runs its location queries with the context, while every other read is
+// served from memory, so it finishes without waiting on anything outside of the process.
+// Changes to states are recorded with the [Author] of the context if the data store supports it
+type ContextStore struct {
+	store Store
+}
+
+// Constructor for the [ContextStore] struct wraps the data store
+func WithContext(store Store) *ContextStore {
+	return &ContextStore{store}
+}
+
+// Gets the wrapped data store
+func (s *ContextStore) Unwrap() Store {
+	return s.store
+}
+
+// Closes the wrapped data store if it needs to be closed
+func (s *ContextStore) Close() error {
+	if closer, ok := s.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Gets every state in the data store, unless the context is done
+func (s *ContextStore) GetAll(ctx context.Context) ([]geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetAll()
+}
+
+// Gets the state by its name or one of its aliases, unless the context is done
+func (s *ContextStore) GetByName(ctx context.Context, name string) (geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.State{}, err
+	}
+	return s.store.GetByName(name)
+}
+
+// Gets the states containing the location, unless the context is done
+func (s *ContextStore) GetContaining(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if store, ok := s.store.(locatingStore); ok {
+		states, _, err := store.locate(ctx, coord)
+		return states, err
+	}
+	return s.store.GetContaining(coord)
+}
+
+// Gets the states containing the location along with the version of the data store they were read
+// at, unless the context is done
+func (s *ContextStore) GetContainingWithVersion(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if store, ok := s.store.(locatingStore); ok {
+		return store.locate(ctx, coord)
+	}
+	return s.store.GetContainingWithVersion(coord)
+}
+
+// Searches the names and aliases of the states for the query, unless the context is done
+func (s *ContextStore) Search(ctx context.Context, query string, limit int) ([]SearchMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.Search(query, limit)
+}
+
+// Adds the state to the data store as the [Author] of the context, persisting it with the context
+func (s *ContextStore) Create(ctx context.Context, state geospatial.State) (geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.State{}, err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		created, _, err := store.memoryStore().createState(edit{ctx: ctx, author: Author(ctx)}, state)
+		return copyState(created), err
+	}
+	if store, ok := s.store.(authoredStore); ok {
+		return store.CreateAs(Author(ctx), state)
+	}
+	return s.store.Create(state)
+}
+
+// Replaces the state at the version as the [Author] of the context, persisting the change with the context
+func (s *ContextStore) Update(ctx context.Context, name string, version uint64, state geospatial.State) (geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.State{}, err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		updated, _, err := store.memoryStore().updateState(edit{ctx: ctx, author: Author(ctx)}, name, version, state)
+		return copyState(updated), err
+	}
+	if store, ok := s.store.(authoredStore); ok {
+		return store.UpdateAs(Author(ctx), name, version, state)
+	}
+	return s.store.Update(name, version, state)
+}
+
+// Removes the state at the version as the [Author] of the context, persisting the change with the context
+func (s *ContextStore) Delete(ctx context.Context, name string, version uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		_, err := store.memoryStore().deleteState(edit{ctx: ctx, author: Author(ctx)}, name, version)
+		return err
+	}
+	if store, ok := s.store.(authoredStore); ok {
+		return store.DeleteAs(Author(ctx), name, version)
+	}
+	return s.store.Delete(name, version)
+}
+
+// Gets the states which share a border with the state, unless the context is done
+func (s *ContextStore) GetNeighbors(ctx context.Context, name string) ([]geospatial.Neighbor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetNeighbors(name)
+}
+
+// Gets the version of the data store
+func (s *ContextStore) Version() uint64 {
+	return s.store.Version()
+}
+
+// Gets every region in the data store, unless the context is done
+func (s *ContextStore) GetAllRegions(ctx context.Context) ([]geospatial.Region, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetAllRegions()
+}
+
+// Gets the region by its name, unless the context is done
+func (s *ContextStore) GetRegion(ctx context.Context, name string) (geospatial.Region, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.Region{}, err
+	}
+	return s.store.GetRegion(name)
+}
+
+// Adds the region to the data store, persisting it with the context
+func (s *ContextStore) CreateRegion(ctx context.Context, region geospatial.Region) (geospatial.Region, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.Region{}, err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		return store.memoryStore().createRegion(ctx, region)
+	}
+	return s.store.CreateRegion(region)
+}
+
+// Removes the region from the data store, persisting the change with the context
+func (s *ContextStore) DeleteRegion(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		return store.memoryStore().deleteRegion(ctx, name)
+	}
+	return s.store.DeleteRegion(name)
+}
+
+// Gets the boundary at the path, unless the context is done
+func (s *ContextStore) GetBoundary(ctx context.Context, path []string) (geospatial.Boundary, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.Boundary{}, err
+	}
+	return s.store.GetBoundary(path)
+}
+
+// Gets the boundaries directly beneath the state or boundary at the path, unless the context is done
+func (s *ContextStore) GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetChildren(path)
+}
+
+// Adds the boundary beneath the state or boundary at the parent path, persisting it with the context
+func (s *ContextStore) CreateBoundary(ctx context.Context, parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.Boundary{}, err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		return store.memoryStore().createBoundary(ctx, parent, boundary)
+	}
+	return s.store.CreateBoundary(parent, boundary)
+}
+
+// Removes the boundary at the path, along with every boundary beneath it, persisting the change with the context
+func (s *ContextStore) DeleteBoundary(ctx context.Context, path []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if store, ok := s.store.(journaledStore); ok {
+		return store.memoryStore().deleteBoundary(ctx, path)
+	}
+	return s.store.DeleteBoundary(path)
+}
+
+// Gets every revision of the state, unless the context is done
+func (s *ContextStore) GetHistory(ctx context.Context, name string) ([]Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetHistory(name)
+}
+
+// Gets every state as it was at the time, unless the context is done
+func (s *ContextStore) GetAllAsOf(ctx context.Context, at time.Time) ([]geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetAllAsOf(at)
+}
+
+// Gets the state by the name or alias it had at the time, as it was at the time, unless the context is done
+func (s *ContextStore) GetByNameAsOf(ctx context.Context, name string, at time.Time) (geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return geospatial.State{}, err
+	}
+	return s.store.GetByNameAsOf(name, at)
+}
+
+// Gets the states which contained the location at the time, unless the context is done
+func (s *ContextStore) GetContainingAsOf(ctx context.Context, coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.GetContainingAsOf(coord, at)
+}
@@ -1,6 +1,9 @@
 package backend
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Error struct which implements the Error interface and allows
 // HTTP request handlers to generate the appropriate response
@@ -21,3 +24,66 @@ type InvalidStateError struct {
 func (e *InvalidStateError) Error() string {
 	return fmt.Sprintf("%s", e.Err)
 }
+
+// Error struct which implements the Error interface and allows
+// HTTP request handlers to generate the appropriate response. Returned
+// when a state, region or boundary would clash with one already in the
+// data store, e.g. a duplicate name or alias
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s", e.Err)
+}
+
+// Error struct which implements the Error interface and allows
+// HTTP request handlers to generate the appropriate response
+type VersionMismatchError struct {
+	Name    string
+	Current uint64
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("state %s has been changed, current version is %d", e.Name, e.Current)
+}
+
+// Error struct which implements the Error interface and allows
+// HTTP request handlers to generate the appropriate response
+type RegionNotFoundError struct {
+	Name string
+}
+
+func (e *RegionNotFoundError) Error() string {
+	return fmt.Sprintf("no region found with name: %s", e.Name)
+}
+
+// Error struct which implements the Error interface and allows
+// HTTP request handlers to generate the appropriate response
+type InvalidRegionError struct {
+	Err error
+}
+
+func (e *InvalidRegionError) Error() string {
+	return fmt.Sprintf("%s", e.Err)
+}
+
+// Error struct which implements the Error interface and allows
+// HTTP request handlers to generate the appropriate response
+type BoundaryNotFoundError struct {
+	Path []string
+}
+
+func (e *BoundaryNotFoundError) Error() string {
+	return fmt.Sprintf("no boundary found at: %s", strings.Join(e.Path, " > "))
+}
+
+// Error struct which implements the Error interface and allows
+// HTTP request handlers to generate the appropriate response
+type InvalidBoundaryError struct {
+	Err error
+}
+
+func (e *InvalidBoundaryError) Error() string {
+	return fmt.Sprintf("%s", e.Err)
+}
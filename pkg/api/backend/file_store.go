@@ -0,0 +1,330 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// The name of the snapshot kept in the data directory of a [StateLocationFileStore]
+const snapshotFile = "snapshot.json"
+
+// A change to the data store, written as a single line of the write-ahead log. Changes to states
+// also record who made the change and when, so that the state's history is replayed unchanged
+type logEntry struct {
+	Op       operation            `json:"op"`
+	Name     string               `json:"name,omitempty"`
+	Members  []string             `json:"members,omitempty"`
+	Path     []string             `json:"path,omitempty"`
+	State    *geospatial.State    `json:"state,omitempty"`
+	Boundary *geospatial.Boundary `json:"boundary,omitempty"`
+	Author   string               `json:"author,omitempty"`
+	Time     *time.Time           `json:"time,omitempty"`
+}
+
+// The log entry which records the change. States and regions which are changed or removed are named
+// by their keys, which resolve to the same state or region when the entry is replayed
+func newLogEntry(c change) logEntry {
+	switch c.op {
+	case opCreate, opUpdate, opDelete:
+		entry := logEntry{Op: c.op, Author: c.revision.Author, Time: &c.revision.Time}
+		if c.op != opCreate {
+			entry.Name = c.key
+		}
+		if c.op != opDelete {
+			entry.State = &c.revision.State
+		}
+		return entry
+	case opCreateRegion:
+		return logEntry{Op: c.op, Name: c.region.Name, Members: c.region.Members}
+	case opDeleteRegion:
+		return logEntry{Op: c.op, Name: c.key}
+	case opCreateBoundary:
+		return logEntry{Op: c.op, Path: c.path, Boundary: &c.boundary}
+	default:
+		return logEntry{Op: c.op, Path: c.path}
+	}
+}
+
+// Makes the change recorded by the log entry to the data store
+func (e logEntry) apply(store *StateLocationMemoryStore) error {
+	change := edit{author: e.Author}
+	if e.Time != nil {
+		change.time = *e.Time
+	}
+
+	var err error
+	switch {
+	case e.Op == opCreate && e.State != nil:
+		_, _, err = store.createState(change, *e.State)
+	case e.Op == opUpdate && e.State != nil:
+		_, _, err = store.updateState(change, e.Name, 0, *e.State)
+	case e.Op == opDelete:
+		_, err = store.deleteState(change, e.Name, 0)
+	case e.Op == opCreateRegion:
+		_, err = store.CreateRegion(geospatial.Region{Name: e.Name, States: e.Members})
+	case e.Op == opDeleteRegion:
+		err = store.DeleteRegion(e.Name)
+	case e.Op == opCreateBoundary && e.Boundary != nil:
+		_, err = store.CreateBoundary(e.Path, *e.Boundary)
+	case e.Op == opDeleteBoundary:
+		err = store.DeleteBoundary(e.Path)
+	default:
+		err = fmt.Errorf("unknown operation: %s", e.Op)
+	}
+	return err
+}
+
+// The snapshot written to the data directory, along with the sequence number of the
+// write-ahead log which holds the changes made after the snapshot was taken
+type fileSnapshot struct {
+	Log uint64 `json:"log"`
+	Snapshot
+}
+
+// A data store which keeps the states, regions and boundaries in memory, as [StateLocationMemoryStore] does,
+// and persists every change to a data directory so that it survives restarts. Each change is appended to a
+// write-ahead log and synced to disk before it is made in memory, and the log is compacted into a snapshot
+// of the whole data store after every compactAfter changes. Lookups are served from memory
+type StateLocationFileStore struct {
+	*StateLocationMemoryStore
+	dir          string
+	log          *os.File
+	sequence     uint64
+	entries      int
+	compactAfter int
+}
+
+// Constructor for the [StateLocationFileStore] struct opens the data store kept in the directory, creating
+// the directory if needed, and recovers its contents from the snapshot and write-ahead log found there.
+// A compactAfter of zero or less never compacts the log until the data store is closed
+func NewFileStore(dir string, compactAfter int) (*StateLocationFileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create data directory %s: %w", dir, err)
+	}
+
+	s := &StateLocationFileStore{dir: dir, compactAfter: compactAfter}
+	store, err := s.recover()
+	if err != nil {
+		return nil, err
+	}
+	store.journal = s
+	s.StateLocationMemoryStore = store
+
+	return s, nil
+}
+
+// Compacts the write-ahead log into a snapshot and closes the data store. The
+// data store must not be used after it is closed
+func (s *StateLocationFileStore) Close() error {
+	s.StateLocationMemoryStore.changes.Lock()
+	defer s.StateLocationMemoryStore.changes.Unlock()
+
+	err := s.compact()
+	if closeErr := s.log.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Appends the change to the write-ahead log and syncs it to disk before the change is made in memory, first
+// compacting the log once it holds compactAfter changes. If the change cannot be written, the log is cut back
+// to the end of the previous change so that the change is never replayed. Called with the change lock held
+func (s *StateLocationFileStore) persist(c change) error {
+	if s.compactAfter > 0 && s.entries >= s.compactAfter {
+		// the earlier changes are already on disk and the change is still appended to the current log,
+		// so a failed compaction is logged and tried again before the next change
+		if err := s.compact(); err != nil {
+			log.Printf("unable to compact the write-ahead log in %s: %s", s.dir, err)
+		}
+	}
+
+	data, err := json.Marshal(newLogEntry(c))
+	if err != nil {
+		return fmt.Errorf("unable to write to the write-ahead log: %w", err)
+	}
+	offset, err := s.log.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("unable to write to the write-ahead log: %w", err)
+	}
+
+	if _, err = s.log.Write(append(data, '\n')); err == nil {
+		err = s.log.Sync()
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to write to the write-ahead log: %w", err)
+		if truncateErr := s.log.Truncate(offset); truncateErr != nil {
+			return errors.Join(err, truncateErr)
+		}
+		if _, seekErr := s.log.Seek(offset, io.SeekStart); seekErr != nil {
+			return errors.Join(err, seekErr)
+		}
+		return err
+	}
+
+	s.entries++
+	return nil
+}
+
+// Writes a snapshot of the whole data store and starts a new, empty write-ahead log. The new log is created
+// and synced first, then the snapshot is written to a temporary file and renamed into place so that it is
+// never left partly written. The snapshot names the new log so that the changes in the old log are never
+// applied twice, so the data store switches to the new log as soon as the snapshot is renamed. If the
+// snapshot cannot be written, the new log is removed and the data store keeps appending to the old log,
+// which the snapshot on disk still names. Must be called with the change lock held
+func (s *StateLocationFileStore) compact() error {
+	data, err := json.Marshal(fileSnapshot{Log: s.sequence + 1, Snapshot: s.StateLocationMemoryStore.Snapshot()})
+	if err != nil {
+		return err
+	}
+
+	path := s.logPath(s.sequence + 1)
+	next, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err == nil {
+		if err = next.Sync(); err != nil {
+			next.Close()
+			os.Remove(path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create write-ahead log: %w", err)
+	}
+
+	tmp := filepath.Join(s.dir, snapshotFile+".tmp")
+	if err = writeFileSync(tmp, data); err == nil {
+		err = os.Rename(tmp, filepath.Join(s.dir, snapshotFile))
+	}
+	if err != nil {
+		next.Close()
+		os.Remove(path)
+		return fmt.Errorf("unable to write snapshot: %w", err)
+	}
+
+	s.log.Close()
+	previous := s.logPath(s.sequence)
+
+	s.log = next
+	s.sequence++
+	s.entries = 0
+
+	// the old log is kept until the rename is on disk, and removed as stale by recover otherwise
+	if err := syncDir(s.dir); err != nil {
+		return fmt.Errorf("unable to write snapshot: %w", err)
+	}
+	os.Remove(previous)
+	return nil
+}
+
+// Loads the snapshot from the data directory, if there is one, then replays the write-ahead log named by the
+// snapshot on top of it and opens the log for new changes. An entry at the end of the log which was only
+// partly written when the server stopped is discarded, as are any logs left over from an earlier compaction
+func (s *StateLocationFileStore) recover() (*StateLocationMemoryStore, error) {
+	store := NewMemoryStore()
+
+	var snapshot fileSnapshot
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		if err := store.Restore(snapshot.Snapshot); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read snapshot: %w", err)
+	}
+
+	log, err := os.OpenFile(s.logPath(snapshot.Log), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open write-ahead log: %w", err)
+	}
+
+	var offset int64
+	entries := 0
+	reader := bufio.NewReader(log)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Close()
+			return nil, fmt.Errorf("unable to read write-ahead log: %w", err)
+		}
+
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Close()
+			return nil, fmt.Errorf("invalid write-ahead log entry at offset %d: %w", offset, err)
+		}
+		if err := entry.apply(store); err != nil {
+			log.Close()
+			return nil, fmt.Errorf("unable to replay write-ahead log entry at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+		entries++
+	}
+
+	if err := log.Truncate(offset); err != nil {
+		log.Close()
+		return nil, fmt.Errorf("unable to repair write-ahead log: %w", err)
+	}
+	if _, err := log.Seek(offset, io.SeekStart); err != nil {
+		log.Close()
+		return nil, fmt.Errorf("unable to open write-ahead log: %w", err)
+	}
+
+	if stale, err := filepath.Glob(filepath.Join(s.dir, "wal-*.log")); err == nil {
+		for _, path := range stale {
+			if path != s.logPath(snapshot.Log) {
+				os.Remove(path)
+			}
+		}
+	}
+
+	s.log = log
+	s.sequence = snapshot.Log
+	s.entries = entries
+
+	return store, nil
+}
+
+func (s *StateLocationFileStore) logPath(sequence uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("wal-%d.log", sequence))
+}
+
+// Writes the file and syncs it to disk before closing it
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Syncs the directory so that files created or renamed within it survive a crash
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
@@ -0,0 +1,168 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	populate := func(t *testing.T, s *StateLocationFileStore) {
+		_, err := s.Create(geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.CreateRegion(geospatial.Region{Name: "Both", States: []string{"West", "East"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+		_, err = s.CreateBoundary([]string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err, "data store should add valid county with no errors")
+		_, err = s.Update("ws", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+		assert.Nil(t, err, "data store should update state with no errors")
+		assert.Nil(t, s.Delete("Far", 0), "data store should delete state with no errors")
+	}
+
+	t.Run("should recover every change after it is reopened", func(t *testing.T) {
+		for _, compactAfter := range []int{0, 1, 3} {
+			dir := t.TempDir()
+			s, err := NewFileStore(dir, compactAfter)
+			assert.Nil(t, err, "file store should open an empty directory")
+			populate(t, s)
+			want := s.Snapshot()
+			assert.Nil(t, s.log.Close(), "the log should close without compacting it")
+
+			reopened, err := NewFileStore(dir, compactAfter)
+			assert.Nilf(t, err, "file store compacted after %d changes should reopen", compactAfter)
+			assert.Equalf(t, want, reopened.Snapshot(), "file store compacted after %d changes should recover every change", compactAfter)
+
+			place, err := reopened.GetBoundary([]string{"western", "north"})
+			assert.Nil(t, err, "renamed state should keep its counties")
+			assert.Equal(t, []string{"Western"}, place.Path)
+			assert.Nil(t, reopened.Close(), "file store should close with no errors")
+		}
+	})
+
+	t.Run("should compact the log into a snapshot", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewFileStore(dir, 4)
+		assert.Nil(t, err, "file store should open an empty directory")
+		populate(t, s)
+		assert.Equal(t, 3, s.entries, "the log should only hold the changes since the last compaction")
+
+		logs, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+		assert.Equal(t, []string{filepath.Join(dir, "wal-1.log")}, logs, "the log compacted into the snapshot should be removed")
+
+		want := s.Snapshot()
+		assert.Nil(t, s.Close(), "file store should close with no errors")
+		info, err := os.Stat(filepath.Join(dir, "wal-2.log"))
+		assert.Nil(t, err, "closing the file store should start a new log")
+		assert.Equal(t, int64(0), info.Size(), "closing the file store should compact every change into the snapshot")
+
+		reopened, err := NewFileStore(dir, 4)
+		assert.Nil(t, err, "file store should reopen")
+		assert.Equal(t, want, reopened.Snapshot())
+		assert.Nil(t, reopened.Close(), "file store should close with no errors")
+	})
+
+	t.Run("should keep every change when the new log cannot be created", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewFileStore(dir, 2)
+		assert.Nil(t, err, "file store should open an empty directory")
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, "wal-1.log"), 0700), "test should block the next log with a directory")
+
+		populate(t, s)
+		want := s.Snapshot()
+		assert.Equal(t, uint64(0), s.sequence, "the data store should keep the old log when the new log cannot be created")
+		assert.Equal(t, 7, s.entries, "every change should be appended to the old log")
+		_, err = os.Stat(filepath.Join(dir, snapshotFile))
+		assert.True(t, errors.Is(err, os.ErrNotExist), "no snapshot should name a log which could not be created")
+		assert.Nil(t, s.log.Close(), "the log should close without compacting it")
+
+		reopened, err := NewFileStore(dir, 2)
+		assert.Nil(t, err, "file store should reopen")
+		assert.Equal(t, want, reopened.Snapshot(), "changes made after a failed compaction should be recovered")
+
+		_, err = reopened.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Equal(t, uint64(1), reopened.sequence, "the log should be compacted once the new log can be created")
+		assert.Nil(t, reopened.Close(), "file store should close with no errors")
+	})
+
+	t.Run("should discard a change which was only partly written", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewFileStore(dir, 0)
+		assert.Nil(t, err, "file store should open an empty directory")
+		populate(t, s)
+		want := s.Snapshot()
+		assert.Nil(t, s.log.Close(), "the log should close without compacting it")
+
+		log, err := os.OpenFile(filepath.Join(dir, "wal-0.log"), os.O_WRONLY|os.O_APPEND, 0600)
+		assert.Nil(t, err, "the log should exist")
+		_, err = log.WriteString(`{"op":"delete","name":"Wes`)
+		assert.Nil(t, err, "test should write a partial change")
+		assert.Nil(t, log.Close())
+
+		reopened, err := NewFileStore(dir, 0)
+		assert.Nil(t, err, "file store should reopen")
+		assert.Equal(t, want, reopened.Snapshot(), "the partial change should not be applied")
+
+		_, err = reopened.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Nil(t, reopened.log.Close(), "the log should close without compacting it")
+
+		reopened, err = NewFileStore(dir, 0)
+		assert.Nil(t, err, "changes written after the partial change was discarded should be readable")
+		_, err = reopened.GetByName("Far")
+		assert.Nil(t, err, "changes written after the partial change was discarded should be recovered")
+		assert.Nil(t, reopened.Close(), "file store should close with no errors")
+	})
+
+	t.Run("should not log changes which fail", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewFileStore(dir, 0)
+		assert.Nil(t, err, "file store should open an empty directory")
+		populate(t, s)
+
+		_, err = s.Create(geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		var conflict *ConflictError
+		assert.True(t, errors.As(err, &conflict), "duplicate state should produce ConflictError")
+		var notFound *StateNotFoundError
+		assert.True(t, errors.As(s.Delete("Missing", 0), &notFound), "missing state should produce StateNotFoundError")
+		assert.Equal(t, 7, s.entries, "failed changes should not be logged")
+		assert.Nil(t, s.Close(), "file store should close with no errors")
+	})
+
+	t.Run("should not make a change which cannot be written to the log", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewFileStore(dir, 0)
+		assert.Nil(t, err, "file store should open an empty directory")
+		populate(t, s)
+		want := s.Snapshot()
+		assert.Nil(t, s.log.Close(), "the log should close without compacting it")
+
+		_, err = s.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.NotNil(t, err, "Create should fail when the log cannot be written")
+		assert.NotNil(t, s.DeleteRegion("Both"), "DeleteRegion should fail when the log cannot be written")
+		_, err = s.GetByName("Far")
+		var notFound *StateNotFoundError
+		assert.True(t, errors.As(err, &notFound), "a change which was not written should not be made")
+		assert.Equal(t, want, s.Snapshot(), "a change which was not written should not be made")
+		assert.Equal(t, 7, s.entries, "a change which was not written should not be counted")
+	})
+
+	t.Run("should fail to open a corrupt log", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, "wal-0.log"), []byte("not json\n"), 0600))
+		_, err := NewFileStore(dir, 0)
+		assert.NotNil(t, err, "a complete log entry which cannot be read should not be discarded")
+	})
+}
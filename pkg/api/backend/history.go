@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// The kind of change to a state recorded by a [Revision]
+type ChangeType string
+
+const (
+	Created ChangeType = "created"
+	Updated ChangeType = "updated"
+	Deleted ChangeType = "deleted"
+)
+
+// A change made to a state in the data store: who made it, when, which fields changed and the state as it
+// was after the change, or as it was when it was removed for a [Deleted] change. Previous is the name of
+// the state before the change if the change renamed it. Version is the version of the data store after the
+// change, which is also the version of the changed state. Author is whoever the client making the change
+// claimed to be, which the data store records as given without checking it
+type Revision struct {
+	Version  uint64           `json:"version"`
+	Time     time.Time        `json:"time"`
+	Author   string           `json:"author,omitempty"`
+	Change   ChangeType       `json:"change"`
+	Previous string           `json:"previous,omitempty"`
+	Fields   []string         `json:"fields,omitempty"`
+	State    geospatial.State `json:"state"`
+}
+
+// Who is making a change to a state and when, along with the context of the request making it which
+// persisting the change is cancelled with. A zero time is replaced with the current time when the change
+// is recorded, while a change being replayed from disk keeps the time it was first made
+type edit struct {
+	ctx    context.Context
+	author string
+	time   time.Time
+}
+
+// Fills in the author and time of the edit on a revision which is about to be recorded. Revisions are kept
+// in version order, so a time earlier than the latest revision, e.g. after the system clock is set back, is
+// moved up to the time of the latest revision. Must be called with the change lock held
+func (s *StateLocationMemoryStore) revise(e edit, revision Revision) Revision {
+	revision.State.Version = revision.Version
+	revision.Author = e.author
+	revision.Time = e.time
+	if revision.Time.IsZero() {
+		revision.Time = time.Now()
+	}
+	revision.Time = revision.Time.UTC()
+	if last := len(s.history) - 1; last >= 0 && revision.Time.Before(s.history[last].Time) {
+		revision.Time = s.history[last].Time
+	}
+	return revision
+}
+
+// The fields which differ between the state before and after an update
+func changedFields(before, after geospatial.State) []string {
+	var fields []string
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if !slices.Equal(before.Border, after.Border) {
+		fields = append(fields, "border")
+	}
+	if before.Country != after.Country {
+		fields = append(fields, "country")
+	}
+	if !slices.Equal(before.Aliases, after.Aliases) {
+		fields = append(fields, "aliases")
+	}
+	if !maps.EqualFunc(before.Properties, after.Properties, func(a, b interface{}) bool { return reflect.DeepEqual(a, b) }) {
+		fields = append(fields, "properties")
+	}
+	return fields
+}
+
+// Gets every change made to the [geospatial.State] with the provided name or alias, oldest first, from when
+// it was created up to its latest change, following the state back through any renames. The history of a
+// deleted state is found by its name until another state is created with that name. Returns
+// [StateNotFoundError] if no state with the name is in the data store or has any recorded changes
+func (s *StateLocationMemoryStore) GetHistory(name string) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := s.resolve(name)
+	revisions := []Revision{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		revision := s.history[i]
+		if normalizeKey(revision.State.Name) != key {
+			continue
+		}
+
+		revision.State = copyState(revision.State)
+		revisions = append(revisions, revision)
+		if revision.Change == Created {
+			break
+		}
+		if revision.Previous != "" {
+			key = normalizeKey(revision.Previous)
+		}
+	}
+
+	if _, ok := s.states[s.resolve(name)]; !ok && len(revisions) == 0 {
+		return nil, &StateNotFoundError{name}
+	}
+	slices.Reverse(revisions)
+	return revisions, nil
+}
+
+// Gets the entire collection of [geospatial.State] objects as it was at the given time
+func (s *StateLocationMemoryStore) GetAllAsOf(at time.Time) ([]geospatial.State, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var states []geospatial.State
+	for _, state := range s.statesAsOf(at) {
+		states = append(states, copyState(state))
+	}
+	return states, nil
+}
+
+// Gets a single [geospatial.State] object by its name or any of its aliases as it was at the given time.
+// Returns [StateNotFoundError] if no state existed for the given name at that time
+func (s *StateLocationMemoryStore) GetByNameAsOf(name string, at time.Time) (geospatial.State, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := normalizeKey(name)
+	if state, ok := s.stateAsOf(key, at); ok {
+		return copyState(state), nil
+	}
+	for _, state := range s.statesAsOf(at) {
+		for _, alias := range state.Aliases {
+			if normalizeKey(alias) == key {
+				return copyState(state), nil
+			}
+		}
+	}
+	return geospatial.State{}, &StateNotFoundError{name}
+}
+
+// Gets the [geospatial.State] objects whose borders contained the coordinate at the given time
+func (s *StateLocationMemoryStore) GetContainingAsOf(coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var states []geospatial.State
+	for _, state := range s.statesAsOf(at) {
+		if state.Contains(coord) {
+			states = append(states, copyState(state))
+		}
+	}
+	return states, nil
+}
+
+// Adds the revision to the end of the history and indexes it. Must be called with the lock held
+func (s *StateLocationMemoryStore) appendRevision(revision Revision) {
+	s.history = append(s.history, revision)
+	s.indexRevision(len(s.history) - 1)
+}
+
+// Indexes the revision at the position in the history under the normalized name of the state and, if the
+// revision renamed the state, under the name it was renamed from. The positions indexed under each name are
+// in the order of the history, so their revisions are in time order. Must be called with the lock held
+func (s *StateLocationMemoryStore) indexRevision(i int) {
+	revision := s.history[i]
+	key := normalizeKey(revision.State.Name)
+	s.revisions[key] = append(s.revisions[key], i)
+	if previous := normalizeKey(revision.Previous); revision.Previous != "" && previous != key {
+		s.revisions[previous] = append(s.revisions[previous], i)
+	}
+}
+
+// Finds the state which had the normalized name at the given time from the last revision indexed under the
+// name at or before that time, found with a binary search. No state had the name if that revision deleted the
+// state or renamed it to another name. Must be called with the lock held
+func (s *StateLocationMemoryStore) stateAsOf(key string, at time.Time) (geospatial.State, bool) {
+	positions := s.revisions[key]
+	i := sort.Search(len(positions), func(i int) bool {
+		return s.history[positions[i]].Time.After(at)
+	})
+	if i == 0 {
+		return geospatial.State{}, false
+	}
+
+	revision := s.history[positions[i-1]]
+	if revision.Change == Deleted || normalizeKey(revision.State.Name) != key {
+		return geospatial.State{}, false
+	}
+	return revision.State, true
+}
+
+// Finds the states which were in the data store at the given time, keyed by their normalized names, by
+// looking up each name in the index of the history. States restored from a snapshot without a history are
+// not included. Must be called with the lock held
+func (s *StateLocationMemoryStore) statesAsOf(at time.Time) map[string]geospatial.State {
+	states := map[string]geospatial.State{}
+	for key := range s.revisions {
+		if state, ok := s.stateAsOf(key, at); ok {
+			states[key] = state
+		}
+	}
+	return states
+}
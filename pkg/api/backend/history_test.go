@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryMemoryStore(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	t.Run("should only follow a name back to the state most recently created with it", func(t *testing.T) {
+		s := NewMemoryStore()
+		_, err := s.CreateAs("alice", geospatial.State{Name: "West", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Nil(t, s.DeleteAs("alice", "West", 0), "data store should delete state with no errors")
+		_, err = s.CreateAs("bob", geospatial.State{Name: "west", Border: square(0, 0, 5)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+
+		history, err := s.GetHistory("WEST")
+		assert.Nil(t, err, "GetHistory should find the state ignoring case")
+		assert.Equal(t, 1, len(history), "history should not include the state deleted before it was created")
+		assert.Equal(t, "bob", history[0].Author)
+		assert.Equal(t, "west", history[0].State.Name)
+	})
+
+	t.Run("should not record changes which fail", func(t *testing.T) {
+		s := NewMemoryStore()
+		_, err := s.Create(geospatial.State{Name: "West", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "West", Border: square(0, 0, 10)})
+		assert.NotNil(t, err, "Create should produce ConflictError for an existing state")
+		_, err = s.Update("West", 5, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+		assert.NotNil(t, err, "Update should produce VersionMismatchError for a stale version")
+
+		history, err := s.GetHistory("West")
+		assert.Nil(t, err, "GetHistory should not produce an error for an existing state")
+		assert.Equal(t, 1, len(history), "failed changes should not be recorded")
+		assert.Nil(t, history[0].Fields, "created states should not list changed fields")
+	})
+
+	t.Run("should keep revisions in time order when the clock is set back", func(t *testing.T) {
+		s := NewMemoryStore()
+		now := time.Now()
+		_, _, err := s.createState(edit{time: now}, geospatial.State{Name: "West", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, _, err = s.updateState(edit{time: now.Add(-time.Hour)}, "West", 0, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+		assert.Nil(t, err, "data store should update state with no errors")
+
+		history, err := s.GetHistory("West")
+		assert.Nil(t, err, "GetHistory should not produce an error for an existing state")
+		assert.Equal(t, now.UTC(), history[1].Time, "revisions should never be earlier than the revision before them")
+
+		state, err := s.GetByNameAsOf("West", now)
+		assert.Nil(t, err, "GetByNameAsOf should find the state")
+		assert.Equal(t, geospatial.Polygon(square(0, 0, 5)), state.Border, "both changes were made as of the later time")
+	})
+
+	t.Run("should look up states by the names they had at the time", func(t *testing.T) {
+		s := NewMemoryStore()
+		start := time.Now()
+		at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+
+		_, _, err := s.createState(edit{time: at(0)}, geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, _, err = s.updateState(edit{time: at(1)}, "West", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "data store should update state with no errors")
+		_, _, err = s.createState(edit{time: at(2)}, geospatial.State{Name: "West", Border: square(20, 20, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.deleteState(edit{time: at(3)}, "Western", 0)
+		assert.Nil(t, err, "data store should delete state with no errors")
+
+		names := func(at time.Time) []string {
+			states, err := s.GetAllAsOf(at)
+			assert.Nil(t, err, "GetAllAsOf should not produce an error")
+			var names []string
+			for _, state := range states {
+				names = append(names, state.Name)
+			}
+			slices.Sort(names)
+			return names
+		}
+		assert.Empty(t, names(at(-1)), "no states were in the data store before the first change")
+		assert.Equal(t, []string{"West"}, names(at(0)))
+		assert.Equal(t, []string{"Western"}, names(at(1)), "a renamed state should only be found by its new name")
+		assert.Equal(t, []string{"West", "Western"}, names(at(2)), "a new state should be found by the name another state was renamed from")
+		assert.Equal(t, []string{"West"}, names(at(3)), "a deleted state should not be found")
+
+		state, err := s.GetByNameAsOf("ws", at(0))
+		assert.Nil(t, err, "GetByNameAsOf should find the state by an alias it had at the time")
+		assert.Equal(t, "West", state.Name)
+		_, err = s.GetByNameAsOf("ws", at(1))
+		assert.NotNil(t, err, "GetByNameAsOf should not find the state by an alias it no longer had")
+		_, err = s.GetByNameAsOf("west", at(1))
+		assert.NotNil(t, err, "GetByNameAsOf should not find the state by the name it was renamed from")
+		state, err = s.GetByNameAsOf("west", at(2))
+		assert.Nil(t, err, "GetByNameAsOf should find the state created with the name")
+		assert.Equal(t, geospatial.Polygon(square(20, 20, 1)), state.Border)
+
+		restored := NewMemoryStore()
+		assert.Nil(t, restored.Restore(s.Snapshot()), "snapshot should restore with no errors")
+		for hours := -1; hours <= 3; hours++ {
+			want, _ := s.GetAllAsOf(at(hours))
+			got, _ := restored.GetAllAsOf(at(hours))
+			assert.ElementsMatchf(t, want, got, "the restored history should find the same states %d hours in", hours)
+		}
+	})
+
+	t.Run("should produce an empty history for states restored without one", func(t *testing.T) {
+		s := NewMemoryStore()
+		assert.Nil(t, s.Restore(Snapshot{Version: 1, States: []SnapshotState{{State: geospatial.State{Name: "West", Border: square(0, 0, 10)}, Version: 1}}}), "snapshot should restore with no errors")
+
+		history, err := s.GetHistory("West")
+		assert.Nil(t, err, "GetHistory should not produce an error for an existing state")
+		assert.Empty(t, history)
+	})
+}
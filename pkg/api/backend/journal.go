@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// The changes which can be made to a data store
+type operation string
+
+const (
+	opCreate         operation = "create"
+	opUpdate         operation = "update"
+	opDelete         operation = "delete"
+	opCreateRegion   operation = "create-region"
+	opDeleteRegion   operation = "delete-region"
+	opCreateBoundary operation = "create-boundary"
+	opDeleteBoundary operation = "delete-boundary"
+)
+
+// A change to the data store which has been validated but not yet made in memory, along with the context of
+// the request making it. The key is the key of the state or region being changed, as it was before the change.
+// Changes to states carry the revision which records them, new regions are named along with their members as
+// in a [Snapshot], and changes to boundaries carry the path given for the boundary along with the new boundary
+type change struct {
+	ctx      context.Context
+	op       operation
+	key      string
+	revision Revision
+	region   SnapshotRegion
+	path     []string
+	boundary geospatial.Boundary
+}
+
+// Persists the changes to a [StateLocationMemoryStore] which keeps its contents on disk. Each change is
+// handed to the journal after it is validated and before it is made in memory, so that readers never see
+// a change which could be lost on restart. A change which the journal cannot persist is not made
+type journal interface {
+	persist(c change) error
+}
+
+// Gets every boundary beneath the state as it will be once the change to the state or one of its
+// boundaries is made, ordered so that each boundary comes after the boundary above it. Renaming a
+// state renames it in the path of each of its boundaries. Must be called with the change lock held
+func (s *StateLocationMemoryStore) boundariesAfter(c change) []geospatial.Boundary {
+	boundaries := s.snapshotBoundaries(c.key)
+
+	switch c.op {
+	case opDelete:
+		return nil
+	case opUpdate:
+		for i := range boundaries {
+			boundaries[i].Path = slices.Clone(boundaries[i].Path)
+			boundaries[i].Path[0] = c.revision.State.Name
+		}
+	case opCreateBoundary:
+		boundaries = append(boundaries, c.boundary)
+	case opDeleteBoundary:
+		removed := append([]string{c.key}, normalizeKeys(c.path[1:])...)
+		boundaries = slices.DeleteFunc(boundaries, func(boundary geospatial.Boundary) bool {
+			path := normalizeKeys(append(slices.Clone(boundary.Path), boundary.Name))
+			return len(path) >= len(removed) && slices.Equal(path[:len(removed)], removed)
+		})
+	}
+	return boundaries
+}
+
+// Gets the regions which have the state as a member as they will be once the change renaming the
+// state is made, naming their members as a [Snapshot] does. Must be called with the change lock held
+func (s *StateLocationMemoryStore) regionsAfter(c change) []SnapshotRegion {
+	var regions []SnapshotRegion
+	for key, members := range s.members {
+		if i := slices.Index(members, c.key); i >= 0 {
+			region := s.snapshotRegion(key)
+			region.Members[i] = c.revision.State.Name
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// Normalizes each of the names with [normalizeKey]
+func normalizeKeys(names []string) []string {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = normalizeKey(name)
+	}
+	return keys
+}
@@ -1,32 +1,54 @@
-// Package backend provides an in-memory thread-safe data store for creating and accessing [geospatial.State] objects.
-// This data store implements the methods that satisfy the [server.StateLocationDataProvider] interface used by
-// the state-server API webserver
+// Package backend provides thread-safe data stores for creating and accessing [geospatial.State] objects, kept
+// in memory or persisted to disk. These data stores implement the methods that satisfy the
+// [server.StateLocationDataProvider] interface used by the state-server API webserver
 package backend
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
-
 	"github.com/aaronireland/state-server/pkg/geospatial"
 )
 
+// The distance in degrees within which the borders of two states are
+// considered to run alongside each other when finding neighboring states
+const BorderTolerance = 0.05
+
+// The states, regions and boundaries in the data store are keyed by their names normalized with
+// [normalizeKey], while each object keeps the display name given by the client. Changes are made one at a
+// time under the change lock: each change is validated and handed to the journal, if there is one, before
+// the lock shared with readers is taken to make it, so lookups only wait while a change is made in memory.
+// The revisions in the history are indexed by the normalized names of the states they changed, so that the
+// states can be looked up as they were at a given time without replaying the whole history
 type StateLocationMemoryStore struct {
 	states    map[string]geospatial.State
-	formatter cases.Caser
+	aliases   map[string]string
+	index     nameIndex
+	neighbors map[string]map[string]float64
+	regions   map[string]geospatial.Region
+	members   map[string][]string
+	hierarchy map[string]*boundaryNode
+	history   []Revision
+	revisions map[string][]int
+	version   uint64
+	journal   journal
+	changes   sync.Mutex
 	mu        sync.RWMutex
 }
 
 // Constructor for the [StateLocationMemoryStore] struct instaniates the data store
-// with a string formatter for title-casing state names in a standardized manner
 func NewMemoryStore() *StateLocationMemoryStore {
-	formatter := cases.Title(language.AmericanEnglish)
-
 	return &StateLocationMemoryStore{
 		states:    map[string]geospatial.State{},
-		formatter: formatter,
+		aliases:   map[string]string{},
+		neighbors: map[string]map[string]float64{},
+		regions:   map[string]geospatial.Region{},
+		members:   map[string][]string{},
+		hierarchy: map[string]*boundaryNode{},
+		revisions: map[string][]int{},
 	}
 }
 
@@ -37,52 +59,749 @@ func (s *StateLocationMemoryStore) GetAll() ([]geospatial.State, error) {
 
 	var states []geospatial.State
 	for _, state := range s.states {
-		states = append(states, state)
+		states = append(states, copyState(state))
 	}
 	return states, nil
 }
 
-// Gets a single [geospatial.State] object from the data store.
+// Gets the [geospatial.State] objects from the data store whose borders contain the coordinate
+func (s *StateLocationMemoryStore) GetContaining(coord geospatial.Coordinate) ([]geospatial.State, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.containing(coord), nil
+}
+
+// Gets the [geospatial.State] objects whose borders contain the given coordinate along with the version
+// of the data store they were read at
+func (s *StateLocationMemoryStore) GetContainingWithVersion(coord geospatial.Coordinate) ([]geospatial.State, uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.containing(coord), s.version, nil
+}
+
+// finds the states whose borders contain the coordinate. Must be called with the lock held
+func (s *StateLocationMemoryStore) containing(coord geospatial.Coordinate) []geospatial.State {
+	var states []geospatial.State
+	for _, state := range s.states {
+		if state.Contains(coord) {
+			states = append(states, copyState(state))
+		}
+	}
+	return states
+}
+
+// Gets a single [geospatial.State] object from the data store by its name or any of its aliases.
 // Returns [StateNotFoundError] if no state exists for the given name
 func (s *StateLocationMemoryStore) GetByName(name string) (geospatial.State, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if state, ok := s.states[s.formatter.String(name)]; ok {
-		return state, nil
+	if state, ok := s.states[s.resolve(name)]; ok {
+		return copyState(state), nil
 	} else {
 		return geospatial.State{}, &StateNotFoundError{name}
 	}
 }
 
-// Validates a provided [geospatial.State] object and adds it to the data store.
-// Returns [InvalidStateError] is the [geospatial.State] provided is invalid.
+// Validates a provided [geospatial.State] object and adds it to the data store along with its properties
+// and aliases. Returns [InvalidStateError] is the [geospatial.State] provided is invalid, or [ConflictError]
+// if its name or any of its aliases is already the name or an alias of another state
 func (s *StateLocationMemoryStore) Create(state geospatial.State) (geospatial.State, error) {
+	return s.CreateAs("", state)
+}
+
+// Adds the state to the data store as [StateLocationMemoryStore.Create] does, recording the
+// author of the change in the state's history
+func (s *StateLocationMemoryStore) CreateAs(author string, state geospatial.State) (geospatial.State, error) {
+	created, _, err := s.createState(edit{ctx: context.Background(), author: author}, state)
+	return copyState(created), err
+}
+
+// adds the state to the data store and records the change, returning the state along with its revision
+func (s *StateLocationMemoryStore) createState(e edit, state geospatial.State) (geospatial.State, Revision, error) {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	created, key, err := s.validate(state, "")
+	if err != nil {
+		return geospatial.State{}, Revision{}, err
+	}
+	created.Version = s.version + 1
+
+	revision := s.revise(e, Revision{Version: created.Version, Change: Created, State: created})
+	if err := s.persist(change{ctx: e.ctx, op: opCreate, key: key, revision: revision}); err != nil {
+		return geospatial.State{}, Revision{}, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	state.Name = s.formatter.String(state.Name)
+	s.version = created.Version
+	s.insert(key, created)
+	s.hierarchy[key] = newBoundaryNode(geospatial.Boundary{Name: created.Name, Level: geospatial.LevelState})
+	s.refreshRegions(key)
+	s.appendRevision(revision)
+
+	return created, revision, nil
+}
+
+// Replaces the [geospatial.State] with the provided name or alias with the given state in a single step so
+// that lookups never miss the state while it changes. The state may be renamed, keeping its counties, places
+// and region memberships. Unless the version is zero, the state is only replaced if it is still at that
+// version. Returns [StateNotFoundError] if no state exists for the given name, [VersionMismatchError] if the
+// state has changed since the given version, [InvalidStateError] if the replacement is invalid or
+// [ConflictError] if its name or aliases belong to another state
+func (s *StateLocationMemoryStore) Update(name string, version uint64, state geospatial.State) (geospatial.State, error) {
+	return s.UpdateAs("", name, version, state)
+}
 
-	created, err := geospatial.NewState(state.Name, state.Border)
+// Replaces the state as [StateLocationMemoryStore.Update] does, recording the author of the
+// change in the state's history
+func (s *StateLocationMemoryStore) UpdateAs(author, name string, version uint64, state geospatial.State) (geospatial.State, error) {
+	updated, _, err := s.updateState(edit{ctx: context.Background(), author: author}, name, version, state)
+	return copyState(updated), err
+}
+
+// replaces the state in the data store and records the change, returning the state along with its revision
+func (s *StateLocationMemoryStore) updateState(e edit, name string, version uint64, state geospatial.State) (geospatial.State, Revision, error) {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	key := s.resolve(name)
+	current, ok := s.states[key]
+	if !ok {
+		return geospatial.State{}, Revision{}, &StateNotFoundError{name}
+	}
+	if version != 0 && version != current.Version {
+		return geospatial.State{}, Revision{}, &VersionMismatchError{Name: current.Name, Current: current.Version}
+	}
+
+	updated, updatedKey, err := s.validate(state, key)
 	if err != nil {
-		return geospatial.State{}, &InvalidStateError{err}
+		return geospatial.State{}, Revision{}, err
+	}
+	updated.Version = s.version + 1
+
+	revision := Revision{Version: updated.Version, Change: Updated, Fields: changedFields(current, updated), State: updated}
+	if updated.Name != current.Name {
+		revision.Previous = current.Name
 	}
+	revision = s.revise(e, revision)
+	if err := s.persist(change{ctx: e.ctx, op: opUpdate, key: key, revision: revision}); err != nil {
+		return geospatial.State{}, Revision{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if _, ok := s.states[created.Name]; ok {
-		return geospatial.State{}, &InvalidStateError{fmt.Errorf("duplicate state: %s", state.Name)}
+	s.version = updated.Version
+	s.remove(key)
+	s.insert(updatedKey, updated)
+
+	node := s.hierarchy[key]
+	node.rename(updated.Name)
+	delete(s.hierarchy, key)
+	s.hierarchy[updatedKey] = node
+
+	for _, members := range s.members {
+		for i, member := range members {
+			if member == key {
+				members[i] = updatedKey
+			}
+		}
 	}
+	s.refreshRegions(updatedKey)
+	s.appendRevision(revision)
+
+	return updated, revision, nil
+}
 
-	s.states[created.Name] = created
+// Removes the [geospatial.State] with the provided name or alias from the data store collection along
+// with its aliases and every boundary within the state. Unless the version is zero, the state is only
+// removed if it is still at that version. Returns [StateNotFoundError] if no state exists for the
+// given name or [VersionMismatchError] if the state has changed since the given version
+func (s *StateLocationMemoryStore) Delete(name string, version uint64) error {
+	return s.DeleteAs("", name, version)
+}
+
+// Removes the state as [StateLocationMemoryStore.Delete] does, recording the author of the
+// change in the state's history
+func (s *StateLocationMemoryStore) DeleteAs(author, name string, version uint64) error {
+	_, err := s.deleteState(edit{ctx: context.Background(), author: author}, name, version)
+	return err
+}
+
+// removes the state from the data store and records the change, returning the revision
+func (s *StateLocationMemoryStore) deleteState(e edit, name string, version uint64) (Revision, error) {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	key := s.resolve(name)
+	current, ok := s.states[key]
+	if !ok {
+		return Revision{}, &StateNotFoundError{name}
+	}
+	if version != 0 && version != current.Version {
+		return Revision{}, &VersionMismatchError{Name: current.Name, Current: current.Version}
+	}
+
+	revision := s.revise(e, Revision{Version: s.version + 1, Change: Deleted, State: current})
+	if err := s.persist(change{ctx: e.ctx, op: opDelete, key: key, revision: revision}); err != nil {
+		return Revision{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version = revision.Version
+	s.remove(key)
+	delete(s.hierarchy, key)
+	s.refreshRegions(key)
+	s.appendRevision(revision)
+
+	return revision, nil
+}
+
+// Hands the change to the journal, if the data store has one, before it is made in memory. Returns the
+// error of the context without persisting the change if the request making it is already done.
+// Must be called with the change lock held
+func (s *StateLocationMemoryStore) persist(c change) error {
+	if s.journal == nil {
+		return nil
+	}
+	if c.ctx == nil {
+		c.ctx = context.Background()
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return s.journal.persist(c)
+}
+
+// Gets the data store which holds the contents of the data stores in this package in memory,
+// so that changes made with a [ContextStore] can hand the context of the request to its journal
+func (s *StateLocationMemoryStore) memoryStore() *StateLocationMemoryStore {
+	return s
+}
+
+// The names which cannot be given to a state or used as an alias, since they are part of the paths of the
+// API rather than names of states, e.g. GET /api/v1/state/search
+var reservedNames = []string{"search"}
+
+// Validates a state to be added to the data store, returning the state as it will be stored along with its key.
+// The state with the replacing key, if any, is the one being replaced so its name and aliases may be reused.
+// Must be called with the change lock held
+func (s *StateLocationMemoryStore) validate(state geospatial.State, replacing string) (geospatial.State, string, error) {
+	valid, err := geospatial.NewState(displayName(state.Name), state.Border)
+	if err != nil {
+		return geospatial.State{}, "", &InvalidStateError{err}
+	}
+	if valid.Country = displayName(state.Country); valid.Country != "" && len(valid.Country) < 2 {
+		return geospatial.State{}, "", &InvalidStateError{fmt.Errorf("invalid name for country, minimum length is 2: %s", valid.Country)}
+	}
+	for _, reserved := range []string{"state", "country", "aliases"} {
+		if _, ok := state.Properties[reserved]; ok {
+			return geospatial.State{}, "", &InvalidStateError{fmt.Errorf("invalid property: %s is reserved", reserved)}
+		}
+	}
+	valid.Properties = copyProperties(state.Properties)
+
+	key := normalizeKey(valid.Name)
+	if slices.Contains(reservedNames, key) {
+		return geospatial.State{}, "", &InvalidStateError{fmt.Errorf("invalid name for state: %s is reserved", valid.Name)}
+	}
+	if existing, ok := s.states[key]; ok && key != replacing {
+		return geospatial.State{}, "", &ConflictError{fmt.Errorf("duplicate state: %s", existing.Name)}
+	}
+	if other, ok := s.aliases[key]; ok && other != replacing {
+		return geospatial.State{}, "", &ConflictError{fmt.Errorf("duplicate state: %s is an alias of %s", valid.Name, s.states[other].Name)}
+	}
+
+	if valid.Aliases, err = s.validateAliases(key, state.Aliases, replacing); err != nil {
+		return geospatial.State{}, "", err
+	}
+	return valid, key, nil
+}
+
+// Adds a validated state along with its aliases to the data store and records its neighbors.
+// Must be called with the write lock held
+func (s *StateLocationMemoryStore) insert(key string, state geospatial.State) {
+	for _, alias := range state.Aliases {
+		s.aliases[normalizeKey(alias)] = key
+	}
+	s.states[key] = state
+	s.index.add(state.Name, append([]string{state.Name}, state.Aliases...)...)
+	s.addNeighbors(key, state)
+}
+
+// Removes a state along with its aliases and neighbors from the data store, leaving its boundaries
+// and regions to the caller. Must be called with the write lock held
+func (s *StateLocationMemoryStore) remove(key string) {
+	state := s.states[key]
+	for _, alias := range state.Aliases {
+		delete(s.aliases, normalizeKey(alias))
+	}
+	delete(s.states, key)
+	s.index.remove(state.Name)
+	s.removeNeighbors(key)
+}
+
+// Searches the names and aliases of the states in the data store for the query, returning at most limit
+// matches ranked by how closely they match: exact matches, then names starting with the query, then names
+// containing the query, then names within a few typos of the query
+func (s *StateLocationMemoryStore) Search(query string, limit int) ([]SearchMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.index.search(query, limit), nil
+}
+
+// Translates a state name or alias into the key of the state in the data store. Names which
+// are not an alias are returned normalized as a state name. Must be called with the lock or the change lock held
+func (s *StateLocationMemoryStore) resolve(name string) string {
+	key := normalizeKey(name)
+	if _, ok := s.states[key]; ok {
+		return key
+	}
+	if canonical, ok := s.aliases[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// Checks that none of the aliases for a new state are already the name or an alias of another
+// state, other than the state being replaced, dropping blank aliases and any which repeat the
+// state's name or an earlier alias. Returns [ConflictError] for an alias which belongs to another
+// state. Must be called with the change lock held
+func (s *StateLocationMemoryStore) validateAliases(key string, aliases []string, replacing string) ([]string, error) {
+	var valid []string
+	seen := map[string]bool{key: true}
+
+	for _, alias := range aliases {
+		alias = displayName(alias)
+		key := normalizeKey(alias)
+		if alias == "" || seen[key] {
+			continue
+		}
+		if slices.Contains(reservedNames, key) {
+			return nil, &InvalidStateError{fmt.Errorf("invalid alias: %s is reserved", alias)}
+		}
+		if _, ok := s.states[key]; ok && key != replacing {
+			return nil, &ConflictError{fmt.Errorf("duplicate alias: %s is the name of another state", alias)}
+		}
+		if other, ok := s.aliases[key]; ok && other != replacing {
+			return nil, &ConflictError{fmt.Errorf("duplicate alias: %s is already an alias of %s", alias, s.states[other].Name)}
+		}
+		seen[key] = true
+		valid = append(valid, alias)
+	}
+	return valid, nil
+}
+
+// Copies the properties, along with any objects and arrays nested in them, so that the stored
+// state is not changed by changes to the caller's map
+func copyProperties(properties map[string]interface{}) map[string]interface{} {
+	if len(properties) == 0 {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		copied[key] = copyValue(value)
+	}
+	return copied
+}
+
+// Copies the objects and arrays nested in a property value so that the copy shares nothing with the value
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			copied[key] = copyValue(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, nested := range value {
+			copied[i] = copyValue(nested)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// Copies a state read from the data store so that callers can change its aliases and properties without
+// changing the stored state. Borders are never changed in place by the data store or its callers, so they
+// are shared with the stored state
+func copyState(state geospatial.State) geospatial.State {
+	state.Aliases = slices.Clone(state.Aliases)
+	state.Properties = copyProperties(state.Properties)
+	return state
+}
+
+// Gets the current version of the data store collection which increases every time
+// a [geospatial.State] is added to, changed or removed from the data store. Each state
+// is given the version of the collection at which it was last added or changed
+func (s *StateLocationMemoryStore) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.version
+}
+
+// Gets the states which share part of their border with the [geospatial.State] with the provided name,
+// ordered by name. Returns [StateNotFoundError] if no state exists for the given name
+func (s *StateLocationMemoryStore) GetNeighbors(name string) ([]geospatial.Neighbor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := s.resolve(name)
+	if _, ok := s.states[key]; !ok {
+		return nil, &StateNotFoundError{name}
+	}
+
+	neighbors := []geospatial.Neighbor{}
+	for neighbor, border := range s.neighbors[key] {
+		neighbors = append(neighbors, geospatial.Neighbor{Name: s.states[neighbor].Name, Border: border})
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i].Name < neighbors[j].Name
+	})
+
+	return neighbors, nil
+}
+
+// Compares the border of a newly added state with every other state in the data store and records
+// each pair of states which share part of their border. Must be called with the write lock held
+func (s *StateLocationMemoryStore) addNeighbors(key string, state geospatial.State) {
+	s.neighbors[key] = map[string]float64{}
+
+	for other, otherState := range s.states {
+		if other == key {
+			continue
+		}
+		if border := geospatial.SharedBorder(state.Border, otherState.Border, BorderTolerance); border > 0 {
+			s.neighbors[key][other] = border
+			s.neighbors[other][key] = border
+		}
+	}
+}
+
+// Removes every record of the state from the neighbors of other states.
+// Must be called with the write lock held
+func (s *StateLocationMemoryStore) removeNeighbors(key string) {
+	for neighbor := range s.neighbors[key] {
+		delete(s.neighbors[neighbor], key)
+	}
+	delete(s.neighbors, key)
+}
+
+// Gets the entire collection of [geospatial.Region] objects from the data store
+func (s *StateLocationMemoryStore) GetAllRegions() ([]geospatial.Region, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var regions []geospatial.Region
+	for _, region := range s.regions {
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// Gets a single [geospatial.Region] object from the data store.
+// Returns [RegionNotFoundError] if no region exists for the given name
+func (s *StateLocationMemoryStore) GetRegion(name string) (geospatial.Region, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if region, ok := s.regions[normalizeKey(name)]; ok {
+		return region, nil
+	} else {
+		return geospatial.Region{}, &RegionNotFoundError{name}
+	}
+}
+
+// Adds a region made up of the member states named in the provided [geospatial.Region] object to the
+// data store, computing its border from the borders of its members. Returns [InvalidRegionError] if the
+// region has no members or any member is not in the data store, or [ConflictError] if the region already exists
+func (s *StateLocationMemoryStore) CreateRegion(region geospatial.Region) (geospatial.Region, error) {
+	return s.createRegion(context.Background(), region)
+}
+
+// adds the region to the data store, persisting it with the context
+func (s *StateLocationMemoryStore) createRegion(ctx context.Context, region geospatial.Region) (geospatial.Region, error) {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	name, key := displayName(region.Name), normalizeKey(region.Name)
+	if name == "" {
+		return geospatial.Region{}, &InvalidRegionError{fmt.Errorf("region name is required")}
+	}
+	if len(region.States) == 0 {
+		return geospatial.Region{}, &InvalidRegionError{fmt.Errorf("region must have at least one state")}
+	}
+	if existing, ok := s.regions[key]; ok {
+		return geospatial.Region{}, &ConflictError{fmt.Errorf("duplicate region: %s", existing.Name)}
+	}
+
+	var members, names []string
+	seen := map[string]bool{}
+	for _, member := range region.States {
+		state := s.resolve(member)
+		if _, ok := s.states[state]; !ok {
+			return geospatial.Region{}, &InvalidRegionError{fmt.Errorf("no state found with name: %s", member)}
+		}
+		if !seen[state] {
+			seen[state] = true
+			members = append(members, state)
+			names = append(names, s.states[state].Name)
+		}
+	}
+	if err := s.persist(change{ctx: ctx, op: opCreateRegion, key: key, region: SnapshotRegion{Name: name, Members: names}}); err != nil {
+		return geospatial.Region{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.members[key] = members
+	s.regions[key] = s.buildRegion(name, members)
+
+	return s.regions[key], nil
+}
+
+// Removes the [geospatial.Region] with the provided name from the data store. The member states of the
+// region are not affected. Returns [RegionNotFoundError] if no region exists for the given name
+func (s *StateLocationMemoryStore) DeleteRegion(name string) error {
+	return s.deleteRegion(context.Background(), name)
+}
+
+// removes the region from the data store, persisting the change with the context
+func (s *StateLocationMemoryStore) deleteRegion(ctx context.Context, name string) error {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	key := normalizeKey(name)
+	if _, ok := s.regions[key]; !ok {
+		return &RegionNotFoundError{name}
+	}
+	if err := s.persist(change{ctx: ctx, op: opDeleteRegion, key: key}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.regions, key)
+	delete(s.members, key)
+
+	return nil
+}
+
+// Rebuilds every region which has the state as a member so that the region's border follows changes to
+// the state. Members which are no longer in the data store are left out of the region until they are
+// added again. Must be called with the write lock held
+func (s *StateLocationMemoryStore) refreshRegions(state string) {
+	for key, members := range s.members {
+		for _, member := range members {
+			if member == state {
+				s.regions[key] = s.buildRegion(s.regions[key].Name, members)
+				break
+			}
+		}
+	}
+}
+
+// Dissolves the borders of the region's members, given by their keys, which are in the data store.
+// Must be called with the lock held
+func (s *StateLocationMemoryStore) buildRegion(name string, members []string) geospatial.Region {
+	var states []geospatial.State
+	for _, member := range members {
+		if state, ok := s.states[member]; ok {
+			states = append(states, state)
+		}
+	}
+	return geospatial.NewRegion(name, states)
+}
+
+// A boundary in the hierarchy of geographies within a state, keyed by its normalized name beneath its parent
+type boundaryNode struct {
+	boundary geospatial.Boundary
+	children map[string]*boundaryNode
+}
+
+func newBoundaryNode(boundary geospatial.Boundary) *boundaryNode {
+	return &boundaryNode{boundary: boundary, children: map[string]*boundaryNode{}}
+}
+
+// Renames the boundary along with its name in the path of every boundary beneath it. Each boundary beneath
+// it is given a new path rather than changing its path in place, since the getters return the boundaries,
+// along with their paths, without copying them
+func (n *boundaryNode) rename(name string) {
+	n.boundary.Name = name
+
+	depth := len(n.boundary.Path)
+	var walk func(node *boundaryNode)
+	walk = func(node *boundaryNode) {
+		for _, child := range node.children {
+			child.boundary.Path = slices.Clone(child.boundary.Path)
+			child.boundary.Path[depth] = name
+			walk(child)
+		}
+	}
+	walk(n)
+}
+
+// Gets the [geospatial.Boundary] at the end of the path of names through the hierarchy, which starts
+// with the name of a state, e.g. a state and county name. Returns [StateNotFoundError] if the state does
+// not exist or [BoundaryNotFoundError] if any other boundary along the path does not exist
+func (s *StateLocationMemoryStore) GetBoundary(path []string) (geospatial.Boundary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(path) < 2 {
+		return geospatial.Boundary{}, &BoundaryNotFoundError{path}
+	}
+
+	node, err := s.findBoundary(path)
+	if err != nil {
+		return geospatial.Boundary{}, err
+	}
+	return node.boundary, nil
+}
+
+// Gets the boundaries directly beneath the state or boundary at the end of the path, ordered by name.
+// Returns [StateNotFoundError] or [BoundaryNotFoundError] if the path does not exist
+func (s *StateLocationMemoryStore) GetChildren(path []string) ([]geospatial.Boundary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node, err := s.findBoundary(path)
+	if err != nil {
+		return nil, err
+	}
+
+	children := []geospatial.Boundary{}
+	for _, child := range node.children {
+		children = append(children, child.boundary)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+
+	return children, nil
+}
+
+// Validates a provided [geospatial.Boundary] object and adds it to the data store beneath the state or
+// boundary at the end of the parent path, at the level beneath its parent. Returns [InvalidBoundaryError]
+// if the boundary is invalid, does not overlap its parent or its parent cannot be divided, or [ConflictError]
+// if the boundary already exists
+func (s *StateLocationMemoryStore) CreateBoundary(parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error) {
+	return s.createBoundary(context.Background(), parent, boundary)
+}
+
+// adds the boundary to the data store, persisting it with the context
+func (s *StateLocationMemoryStore) createBoundary(ctx context.Context, parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error) {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	node, err := s.findBoundary(parent)
+	if err != nil {
+		return geospatial.Boundary{}, err
+	}
+
+	level := node.boundary.Level.Child()
+	if level == "" {
+		return geospatial.Boundary{}, &InvalidBoundaryError{fmt.Errorf("%s %s cannot be divided further", node.boundary.Level, node.boundary.Name)}
+	}
+
+	created, err := geospatial.NewBoundary(displayName(boundary.Name), level, boundary.Border)
+	if err != nil {
+		return geospatial.Boundary{}, &InvalidBoundaryError{err}
+	}
+
+	key := normalizeKey(created.Name)
+	if existing, ok := node.children[key]; ok {
+		return geospatial.Boundary{}, &ConflictError{fmt.Errorf("duplicate %s: %s", level, existing.boundary.Name)}
+	}
+
+	border := node.boundary.Border
+	if node.boundary.Level == geospatial.LevelState {
+		border = s.states[normalizeKey(node.boundary.Name)].Border
+	}
+	if len(border.Intersection(created.Border)) == 0 {
+		return geospatial.Boundary{}, &InvalidBoundaryError{fmt.Errorf("%s %s does not lie within %s %s", level, created.Name, node.boundary.Level, node.boundary.Name)}
+	}
+
+	created.Path = append(append([]string{}, node.boundary.Path...), node.boundary.Name)
+	if err := s.persist(change{ctx: ctx, op: opCreateBoundary, key: s.resolve(parent[0]), path: parent, boundary: created}); err != nil {
+		return geospatial.Boundary{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	node.children[key] = newBoundaryNode(created)
 
 	return created, nil
 }
 
-// Removes the [geospatial.State] with the provided name from the data store collection if it exists.
-func (s *StateLocationMemoryStore) Delete(name string) error {
+// Removes the [geospatial.Boundary] at the end of the path from the data store along with every
+// boundary beneath it. Returns [StateNotFoundError] or [BoundaryNotFoundError] if the path does not exist
+func (s *StateLocationMemoryStore) DeleteBoundary(path []string) error {
+	return s.deleteBoundary(context.Background(), path)
+}
+
+// removes the boundary from the data store, persisting the change with the context
+func (s *StateLocationMemoryStore) deleteBoundary(ctx context.Context, path []string) error {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	if len(path) < 2 {
+		return &BoundaryNotFoundError{path}
+	}
+
+	parent, err := s.findBoundary(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+
+	key := normalizeKey(path[len(path)-1])
+	if _, ok := parent.children[key]; !ok {
+		return &BoundaryNotFoundError{path}
+	}
+	if err := s.persist(change{ctx: ctx, op: opDeleteBoundary, key: s.resolve(path[0]), path: path}); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.states, s.formatter.String(name))
+	delete(parent.children, key)
 
 	return nil
 }
+
+// Follows the path of names down through the hierarchy from the state at its start.
+// Must be called with the lock or the change lock held
+func (s *StateLocationMemoryStore) findBoundary(path []string) (*boundaryNode, error) {
+	if len(path) == 0 {
+		return nil, &BoundaryNotFoundError{path}
+	}
+
+	node, ok := s.hierarchy[s.resolve(path[0])]
+	if !ok {
+		return nil, &StateNotFoundError{path[0]}
+	}
+
+	for _, name := range path[1:] {
+		if node, ok = node.children[normalizeKey(name)]; !ok {
+			return nil, &BoundaryNotFoundError{path}
+		}
+	}
+	return node, nil
+}
@@ -43,7 +43,7 @@ func TestStatesMemoryStore(t *testing.T) {
 		assert.Equal(t, 0, len(s.states), "data store should contain no states")
 		created, err := s.Create(validState)
 		assert.Nil(t, err, "data store should add valid state with no errors")
-		assert.Equal(t, validState.Name, created.Name, "title-cased state name should match the state object appended")
+		assert.Equal(t, validState.Name, created.Name, "state name should match the state object appended")
 		assert.ElementsMatch(t, validState.Border, created.Border, "the coordinates for the state in the data store should match what was given")
 		assert.Equal(t, 1, len(s.states), "data should should contain one state")
 
@@ -51,13 +51,13 @@ func TestStatesMemoryStore(t *testing.T) {
 		assert.Nil(t, err, "data store GetAll function should not produce any errors")
 		assert.Equal(t, 1, len(states), "data store GetAll function should return the correct number of states")
 
-		for _, name := range []string{"valid", "VALID", "Valid", "vALiD"} {
+		for _, name := range []string{"valid", "VALID", "Valid", "vALiD", " Valid "} {
 			got, err := s.GetByName(name)
-			assert.Nil(t, err, "GetByName should be case-insensitive")
-			assert.Equal(t, created.Name, got.Name, "GetByName should return title-cased name")
+			assert.Nil(t, err, "GetByName should ignore casing and surrounding whitespace")
+			assert.Equal(t, created.Name, got.Name, "GetByName should return the name the state was created with")
 		}
 
-		for _, name := range []string{"", ".valid", "Valid ", "not here"} {
+		for _, name := range []string{"", ".valid", "Val id", "not here"} {
 			_, err := s.GetByName(name)
 			assert.NotNil(t, err, "GetByName should produce an error if state name not in data store")
 			var notFoundError *StateNotFoundError
@@ -65,7 +65,7 @@ func TestStatesMemoryStore(t *testing.T) {
 			assert.Contains(t, err.Error(), "no state found", "GetByName should produce descriptive error message")
 		}
 
-		err = s.Delete(strings.ToLower(created.Name))
+		err = s.Delete(strings.ToLower(created.Name), 0)
 		assert.Nil(t, err, "Delete should not produce an error")
 		assert.Equal(t, 0, len(s.states), "data store should contain no states")
 		states, err = s.GetAll()
@@ -82,7 +82,7 @@ func TestStatesMemoryStore(t *testing.T) {
 		assert.Equal(t, 0, len(s.states), "data should should contain no states after attempt to add invalid geospatial.State")
 	})
 
-	t.Run("should produce InvalidStateError for request to add existing geospatial.State object", func(t *testing.T) {
+	t.Run("should produce ConflictError for request to add existing geospatial.State object", func(t *testing.T) {
 		s := NewMemoryStore()
 
 		assert.Equal(t, 0, len(s.states), "data store should contain no states")
@@ -91,7 +91,568 @@ func TestStatesMemoryStore(t *testing.T) {
 		assert.Equal(t, 1, len(s.states), "data should should contain one state")
 
 		_, err = s.Create(created)
-		assert.NotNil(t, err, "Create should produce InvalidStateError for attempt to create existing state")
+		var conflictErr *ConflictError
+		assert.True(t, errors.As(err, &conflictErr), "Create should produce ConflictError for attempt to create existing state")
 		assert.Contains(t, err.Error(), "duplicate", "attempt to add duplicate state should produce informative error message")
 	})
+
+	t.Run("should keep the properties of a state", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		properties := map[string]interface{}{"abbreviation": "WA", "population": float64(7812880)}
+		withProperties := validState
+		withProperties.Properties = properties
+
+		created, err := s.Create(withProperties)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Equal(t, properties, created.Properties, "created state should have the given properties")
+
+		properties["abbreviation"] = "changed"
+		got, err := s.GetByName(validState.Name)
+		assert.Nil(t, err, "GetByName should not produce an error for an existing state")
+		assert.Equal(t, "WA", got.Properties["abbreviation"], "stored properties should not change with the caller's map")
+
+		nested := validState
+		nested.Name = "Nested"
+		nested.Properties = map[string]interface{}{"capital": map[string]interface{}{"name": "Olympia"}, "codes": []interface{}{"WA"}}
+		created, err = s.Create(nested)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		nested.Properties["capital"].(map[string]interface{})["name"] = "changed"
+		created.Properties["codes"].([]interface{})[0] = "changed"
+		got, err = s.GetByName("Nested")
+		assert.Nil(t, err, "GetByName should not produce an error for an existing state")
+		got.Properties["capital"].(map[string]interface{})["name"] = "changed again"
+		all, err := s.GetAll()
+		assert.Nil(t, err, "GetAll should not produce an error")
+		for _, state := range all {
+			if state.Name == "Nested" {
+				state.Properties["codes"].([]interface{})[0] = "changed again"
+			}
+		}
+
+		got, err = s.GetByName("Nested")
+		assert.Nil(t, err, "GetByName should not produce an error for an existing state")
+		assert.Equal(t, map[string]interface{}{"capital": map[string]interface{}{"name": "Olympia"}, "codes": []interface{}{"WA"}}, got.Properties, "nested properties should not change with the maps given to or returned by the data store")
+
+		reserved := validState
+		reserved.Name = "Reserved"
+		reserved.Properties = map[string]interface{}{"state": "Other"}
+		_, err = s.Create(reserved)
+		var invalidStateErr *InvalidStateError
+		assert.True(t, errors.As(err, &invalidStateErr), "the state property is reserved for the name of the state")
+	})
+
+	t.Run("should not name a state or alias after a path of the API", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		named := validState
+		named.Name = "Search"
+		_, err := s.Create(named)
+		var invalidStateErr *InvalidStateError
+		assert.True(t, errors.As(err, &invalidStateErr), "Create should produce InvalidStateError for a reserved name")
+
+		aliased := validState
+		aliased.Aliases = []string{" SEARCH "}
+		_, err = s.Create(aliased)
+		assert.True(t, errors.As(err, &invalidStateErr), "Create should produce InvalidStateError for a reserved alias")
+
+		all, err := s.GetAll()
+		assert.Nil(t, err, "GetAll should not produce an error")
+		assert.Empty(t, all, "states with reserved names should not be added")
+	})
+
+	t.Run("should find and delete states by their aliases", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		withAliases := validState
+		withAliases.Aliases = []string{"VA", " ", "42", "va", "Valid"}
+		created, err := s.Create(withAliases)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Equal(t, []string{"VA", "42"}, created.Aliases, "blank and repeated aliases should be dropped")
+
+		for _, alias := range []string{"VA", "va", "42"} {
+			got, err := s.GetByName(alias)
+			assert.Nilf(t, err, "GetByName should find the state by its alias %s", alias)
+			assert.Equal(t, created.Name, got.Name)
+		}
+
+		neighbors, err := s.GetNeighbors("va")
+		assert.Nil(t, err, "GetNeighbors should find the state by its alias")
+		assert.Empty(t, neighbors)
+
+		assert.Nil(t, s.Delete("42", 0), "Delete should not produce an error")
+		_, err = s.GetByName(validState.Name)
+		var notFoundError *StateNotFoundError
+		assert.True(t, errors.As(err, &notFoundError), "Delete should remove the state by its alias")
+		_, err = s.GetByName("VA")
+		assert.True(t, errors.As(err, &notFoundError), "Delete should remove the aliases of the state")
+	})
+
+	t.Run("should keep the display name given while normalizing lookups", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		for _, name := range []string{"District  of Columbia", "McAllen", "Québec"} {
+			state := validState
+			state.Name = name
+			_, err := s.Create(state)
+			assert.Nil(t, err, "data store should add valid state with no errors")
+		}
+
+		for lookup, want := range map[string]string{
+			"district of columbia": "District of Columbia",
+			"MCALLEN":              "McAllen",
+			"que\u0301bec":         "Québec",
+			"QUÉBEC":               "Québec",
+		} {
+			got, err := s.GetByName(lookup)
+			assert.Nilf(t, err, "GetByName should find %s", lookup)
+			assert.Equal(t, want, got.Name, "the display name should keep the casing the state was created with")
+		}
+
+		duplicate := validState
+		duplicate.Name = "québec"
+		_, err := s.Create(duplicate)
+		var conflictErr *ConflictError
+		assert.True(t, errors.As(err, &conflictErr), "names which normalize to the same key are duplicates")
+	})
+
+	t.Run("should search the names and aliases of stored states", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		withAliases := validState
+		withAliases.Aliases = []string{"VA"}
+		_, err := s.Create(withAliases)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+
+		matches, err := s.Search("vali", 10)
+		assert.Nil(t, err, "Search should not produce an error")
+		assert.Equal(t, []SearchMatch{{State: "Valid", Matched: "Valid", Kind: PrefixMatch}}, matches)
+
+		matches, _ = s.Search("va", 10)
+		assert.Equal(t, []SearchMatch{{State: "Valid", Matched: "VA", Kind: ExactMatch}}, matches, "aliases should be searched")
+
+		assert.Nil(t, s.Delete("Valid", 0))
+		matches, _ = s.Search("va", 10)
+		assert.Empty(t, matches, "deleted states should be removed from the index")
+	})
+
+	t.Run("should produce ConflictError for aliases which are already in use", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		withAliases := validState
+		withAliases.Aliases = []string{"VA", "42"}
+		_, err := s.Create(withAliases)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+
+		var conflictErr *ConflictError
+		for _, state := range []geospatial.State{
+			{Name: "Other", Border: validState.Border, Aliases: []string{"va"}},
+			{Name: "Other", Border: validState.Border, Aliases: []string{"valid"}},
+			{Name: "42", Border: validState.Border},
+		} {
+			_, err = s.Create(state)
+			assert.Truef(t, errors.As(err, &conflictErr), "Create should reject %s with aliases %v", state.Name, state.Aliases)
+			assert.Contains(t, err.Error(), "duplicate")
+		}
+
+		states, _ := s.GetAll()
+		assert.Equal(t, 1, len(states), "rejected states should not be added")
+		_, err = s.GetByName("Other")
+		assert.NotNil(t, err, "rejected states should not be added")
+	})
+
+	t.Run("should update states in place", func(t *testing.T) {
+		s := NewMemoryStore()
+		_, err := s.Create(geospatial.State{Name: "Valid", Border: validState.Border, Aliases: []string{"VA"}})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		other := geospatial.State{Name: "Other", Border: validState.Border, Aliases: []string{"OT"}}
+		_, err = s.Create(other)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+
+		renamed := geospatial.State{Name: "Renamed", Border: validState.Border, Aliases: []string{"VA", "Valid"}, Properties: map[string]interface{}{"sides": float64(6)}}
+		updated, err := s.Update("va", 0, renamed)
+		assert.Nil(t, err, "Update should not produce an error for a valid state")
+		assert.Equal(t, []string{"VA", "Valid"}, updated.Aliases, "the state may keep its own name and aliases")
+		assert.Equal(t, uint64(3), s.Version(), "updating a state should increase the version")
+
+		for _, name := range []string{"Renamed", "Valid", "VA"} {
+			got, err := s.GetByName(name)
+			assert.Nilf(t, err, "GetByName should find the updated state by %s", name)
+			assert.Equal(t, updated, got)
+		}
+		matches, _ := s.Search("renamed", 10)
+		assert.Equal(t, 1, len(matches), "the index should hold the new name")
+
+		var conflictErr *ConflictError
+		_, err = s.Update("Renamed", 0, other)
+		assert.True(t, errors.As(err, &conflictErr), "the state cannot take the name of another state")
+		_, err = s.Update("Renamed", 0, geospatial.State{Name: "Renamed", Border: validState.Border, Aliases: []string{"OT"}})
+		assert.True(t, errors.As(err, &conflictErr), "the state cannot take the alias of another state")
+		var invalidStateErr *InvalidStateError
+		_, err = s.Update("Renamed", 0, invalidState)
+		assert.True(t, errors.As(err, &invalidStateErr), "the state must be valid")
+
+		got, _ := s.GetByName("Renamed")
+		assert.Equal(t, updated, got, "failed updates should not change the state")
+
+		var notFoundErr *StateNotFoundError
+		_, err = s.Update("Missing", 0, renamed)
+		assert.True(t, errors.As(err, &notFoundErr), "Update should return StateNotFoundError for a missing state")
+	})
+
+	t.Run("should only change states which are still at the expected version", func(t *testing.T) {
+		s := NewMemoryStore()
+		created, err := s.Create(validState)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		other, err := s.Create(geospatial.State{Name: "Other", Border: validState.Border})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Greater(t, other.Version, created.Version, "each state should be given a new version")
+
+		updated, err := s.Update("Valid", created.Version, validState)
+		assert.Nil(t, err, "Update should succeed at the expected version")
+		assert.Greater(t, updated.Version, other.Version, "updating a state should give it a new version")
+
+		var mismatchErr *VersionMismatchError
+		_, err = s.Update("Valid", created.Version, validState)
+		assert.True(t, errors.As(err, &mismatchErr), "Update should fail for a stale version")
+		assert.Equal(t, updated.Version, mismatchErr.Current, "the error should include the current version")
+		err = s.Delete("Valid", created.Version)
+		assert.True(t, errors.As(err, &mismatchErr), "Delete should fail for a stale version")
+
+		got, err := s.GetByName("Valid")
+		assert.Nil(t, err, "the state should not be removed by a failed delete")
+		assert.Equal(t, updated.Version, got.Version, "the state should not be changed by a failed update")
+
+		assert.Nil(t, s.Delete("Valid", updated.Version), "Delete should succeed at the current version")
+		recreated, err := s.Create(validState)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Greater(t, recreated.Version, updated.Version, "versions should not be reused when a state is created again")
+	})
+
+	t.Run("should increase the data store version when the collection changes", func(t *testing.T) {
+		s := NewMemoryStore()
+		assert.Equal(t, uint64(0), s.Version(), "new data store should be at version 0")
+
+		_, err := s.Create(validState)
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Equal(t, uint64(1), s.Version(), "adding a state should increase the version")
+
+		_, err = s.Create(validState)
+		assert.NotNil(t, err, "Create should produce InvalidStateError for attempt to create existing state")
+		_, err = s.Create(invalidState)
+		assert.NotNil(t, err, "Create should produce InvalidStateError if invalid geospatial.State is given")
+		assert.Equal(t, uint64(1), s.Version(), "failed attempts to add a state should not change the version")
+
+		err = s.Delete("not here", 0)
+		var notFoundErr *StateNotFoundError
+		assert.True(t, errors.As(err, &notFoundErr), "Delete should produce StateNotFoundError for a state which does not exist")
+		assert.Equal(t, uint64(1), s.Version(), "deleting a state that does not exist should not change the version")
+
+		err = s.Delete(validState.Name, 0)
+		assert.Nil(t, err, "Delete should not produce an error")
+		assert.Equal(t, uint64(2), s.Version(), "removing a state should increase the version")
+	})
+
+	t.Run("should keep track of neighboring states as states are added and removed", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		for _, square := range []struct {
+			Name     string
+			Lng, Lat float64
+		}{{"West", 0, 0}, {"East", 1, 0}, {"North", 0, 1}, {"Far", 5, 5}} {
+			_, err := s.Create(geospatial.State{Name: square.Name, Border: []geospatial.Coordinate{
+				{Lng: square.Lng, Lat: square.Lat}, {Lng: square.Lng + 1, Lat: square.Lat}, {Lng: square.Lng + 1, Lat: square.Lat + 1},
+				{Lng: square.Lng, Lat: square.Lat + 1}, {Lng: square.Lng, Lat: square.Lat},
+			}})
+			assert.Nil(t, err, "data store should add valid state with no errors")
+		}
+
+		neighbors, err := s.GetNeighbors("west")
+		assert.Nil(t, err, "GetNeighbors should be case-insensitive")
+		assert.Equal(t, 2, len(neighbors), "west should neighbor east and north")
+		assert.Equal(t, "East", neighbors[0].Name, "neighbors should be ordered by name")
+		assert.Equal(t, "North", neighbors[1].Name, "neighbors should be ordered by name")
+		assert.Greater(t, neighbors[0].Border, float64(100), "neighbors should share about a degree of border")
+
+		neighbors, err = s.GetNeighbors("East")
+		assert.Nil(t, err, "GetNeighbors should not produce an error for an existing state")
+		assert.Equal(t, []string{"West"}, neighborNames(neighbors), "states which only meet at a corner are not neighbors")
+
+		neighbors, err = s.GetNeighbors("Far")
+		assert.Nil(t, err, "GetNeighbors should not produce an error for an existing state")
+		assert.NotNil(t, neighbors, "GetNeighbors should return an empty array for a state with no neighbors")
+		assert.Empty(t, neighbors, "far should not have any neighbors")
+
+		err = s.Delete("West", 0)
+		assert.Nil(t, err, "Delete should not produce an error")
+
+		neighbors, err = s.GetNeighbors("East")
+		assert.Nil(t, err, "GetNeighbors should not produce an error for an existing state")
+		assert.Empty(t, neighbors, "removed state should no longer be a neighbor")
+
+		_, err = s.GetNeighbors("West")
+		var notFoundError *StateNotFoundError
+		assert.True(t, errors.As(err, &notFoundError), "GetNeighbors should return StateNotFound error if state name not in data store")
+	})
+}
+
+func neighborNames(neighbors []geospatial.Neighbor) (names []string) {
+	for _, neighbor := range neighbors {
+		names = append(names, neighbor.Name)
+	}
+	return
+}
+
+func TestRegionsMemoryStore(t *testing.T) {
+	newSquare := func(name string, lng, lat float64) geospatial.State {
+		return geospatial.State{Name: name, Border: []geospatial.Coordinate{
+			{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + 1}, {Lng: lng + 1, Lat: lat + 1}, {Lng: lng + 1, Lat: lat}, {Lng: lng, Lat: lat},
+		}}
+	}
+
+	newStore := func(t *testing.T) *StateLocationMemoryStore {
+		s := NewMemoryStore()
+		for _, state := range []geospatial.State{newSquare("West", 0, 0), newSquare("East", 1, 0), newSquare("Far", 5, 5)} {
+			_, err := s.Create(state)
+			assert.Nil(t, err, "data store should add valid state with no errors")
+		}
+		return s
+	}
+
+	t.Run("should create a region with the dissolved border of its members", func(t *testing.T) {
+		s := newStore(t)
+
+		created, err := s.CreateRegion(geospatial.Region{Name: "Two  squares", States: []string{"west", "east", "West"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+		assert.Equal(t, "Two squares", created.Name, "region name should keep the casing given with its whitespace collapsed")
+		assert.Equal(t, []string{"West", "East"}, created.States, "members should be the names of the states and unique")
+		assert.Equal(t, 1, len(created.Border), "adjacent members should be dissolved")
+
+		got, err := s.GetRegion("TWO SQUARES")
+		assert.Nil(t, err, "GetRegion should be case-insensitive")
+		assert.Equal(t, created, got)
+
+		all, err := s.GetAllRegions()
+		assert.Nil(t, err, "GetAllRegions should not produce an error")
+		assert.Equal(t, 1, len(all))
+	})
+
+	t.Run("should produce InvalidRegionError for invalid regions", func(t *testing.T) {
+		s := newStore(t)
+		var invalidRegionErr *InvalidRegionError
+
+		_, err := s.CreateRegion(geospatial.Region{Name: "Nowhere", States: []string{"West", "Missing"}})
+		assert.True(t, errors.As(err, &invalidRegionErr), "region members must be in the data store")
+		assert.Contains(t, err.Error(), "Missing")
+
+		_, err = s.CreateRegion(geospatial.Region{Name: "Empty"})
+		assert.True(t, errors.As(err, &invalidRegionErr), "region must have members")
+
+		_, err = s.CreateRegion(geospatial.Region{Name: "Pair", States: []string{"West"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+		_, err = s.CreateRegion(geospatial.Region{Name: "pair", States: []string{"East"}})
+		var conflictErr *ConflictError
+		assert.True(t, errors.As(err, &conflictErr), "region must not already exist")
+		assert.Contains(t, err.Error(), "duplicate")
+	})
+
+	t.Run("should update regions when member states are removed and added", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateRegion(geospatial.Region{Name: "Pair", States: []string{"West", "East"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+
+		assert.Nil(t, s.Delete("East", 0))
+		got, _ := s.GetRegion("Pair")
+		assert.Equal(t, []string{"West"}, got.States, "deleted member should be left out of the region")
+		assert.False(t, got.Contains(geospatial.Coordinate{Lng: 1.5, Lat: 0.5}), "region border should no longer cover the deleted member")
+
+		_, err = s.Create(newSquare("East", 1, 1))
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		got, _ = s.GetRegion("Pair")
+		assert.Equal(t, []string{"West", "East"}, got.States, "replaced member should be added back to the region")
+		assert.True(t, got.Contains(geospatial.Coordinate{Lng: 1.5, Lat: 1.5}), "region border should follow the replaced member")
+		assert.False(t, got.Contains(geospatial.Coordinate{Lng: 1.5, Lat: 0.5}), "region border should not keep the old member border")
+	})
+
+	t.Run("should update regions and neighbors when member states are updated", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateRegion(geospatial.Region{Name: "Pair", States: []string{"West", "East"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+
+		_, err = s.Update("East", 0, newSquare("Middle", 5, 4))
+		assert.Nil(t, err, "Update should not produce an error for a valid state")
+
+		got, _ := s.GetRegion("Pair")
+		assert.Equal(t, []string{"West", "Middle"}, got.States, "renamed member should stay in the region")
+		assert.True(t, got.Contains(geospatial.Coordinate{Lng: 5.5, Lat: 4.5}), "region border should follow the updated member")
+		assert.Equal(t, 2, len(got.Border), "members which no longer touch should not be dissolved")
+
+		neighbors, _ := s.GetNeighbors("Far")
+		assert.Equal(t, []string{"Middle"}, neighborNames(neighbors), "neighbors should follow the updated border")
+		neighbors, _ = s.GetNeighbors("West")
+		assert.Empty(t, neighbors, "neighbors should follow the updated border")
+	})
+
+	t.Run("should delete regions without affecting their members", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateRegion(geospatial.Region{Name: "Pair", States: []string{"West", "East"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+
+		assert.Nil(t, s.DeleteRegion("pair"), "DeleteRegion should not produce an error")
+		_, err = s.GetRegion("Pair")
+		var notFoundErr *RegionNotFoundError
+		assert.True(t, errors.As(err, &notFoundErr), "GetRegion should return RegionNotFoundError for a deleted region")
+		err = s.DeleteRegion("Pair")
+		assert.True(t, errors.As(err, &notFoundErr), "DeleteRegion should return RegionNotFoundError for a region which does not exist")
+
+		_, err = s.GetByName("West")
+		assert.Nil(t, err, "member states should be kept")
+	})
+}
+
+func TestHierarchyMemoryStore(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	newStore := func(t *testing.T) *StateLocationMemoryStore {
+		s := NewMemoryStore()
+		_, err := s.Create(geospatial.State{Name: "Square", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		return s
+	}
+
+	t.Run("should create counties and places beneath a state", func(t *testing.T) {
+		s := newStore(t)
+
+		county, err := s.CreateBoundary([]string{"square"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err, "data store should add valid county with no errors")
+		assert.Equal(t, geospatial.LevelCounty, county.Level, "boundaries beneath a state are counties")
+		assert.Equal(t, "North", county.Name, "boundary name should keep the casing given")
+		assert.Equal(t, []string{"Square"}, county.Path)
+
+		place, err := s.CreateBoundary([]string{"Square", "NORTH"}, geospatial.Boundary{Name: "town", Border: square(1, 6, 1)})
+		assert.Nil(t, err, "data store should add valid place with no errors")
+		assert.Equal(t, geospatial.LevelPlace, place.Level, "boundaries beneath a county are places")
+		assert.Equal(t, []string{"Square", "North"}, place.Path)
+
+		got, err := s.GetBoundary([]string{"square", "north", "town"})
+		assert.Nil(t, err, "GetBoundary should be case-insensitive")
+		assert.Equal(t, place, got)
+
+		children, err := s.GetChildren([]string{"Square"})
+		assert.Nil(t, err, "GetChildren should not produce an error for an existing state")
+		assert.Equal(t, []geospatial.Boundary{county}, children)
+	})
+
+	t.Run("should produce errors for invalid boundaries and paths", func(t *testing.T) {
+		s := newStore(t)
+		var invalidBoundaryErr *InvalidBoundaryError
+		var stateNotFoundErr *StateNotFoundError
+		var boundaryNotFoundErr *BoundaryNotFoundError
+
+		_, err := s.CreateBoundary([]string{"Missing"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.True(t, errors.As(err, &stateNotFoundErr), "parent state must exist")
+
+		_, err = s.CreateBoundary([]string{"Square", "Missing"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.True(t, errors.As(err, &boundaryNotFoundErr), "parent county must exist")
+
+		_, err = s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "Away", Border: square(20, 20, 1)})
+		assert.True(t, errors.As(err, &invalidBoundaryErr), "county must lie within its state")
+
+		_, err = s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "Bad", Border: square(0, 0, 1)[:3]})
+		assert.True(t, errors.As(err, &invalidBoundaryErr), "county border must be valid")
+
+		_, err = s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err, "data store should add valid county with no errors")
+		_, err = s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "north", Border: square(0, 5, 5)})
+		var conflictErr *ConflictError
+		assert.True(t, errors.As(err, &conflictErr), "county must not already exist")
+
+		_, err = s.CreateBoundary([]string{"Square", "North"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err, "data store should add valid place with no errors")
+		_, err = s.CreateBoundary([]string{"Square", "North", "Town"}, geospatial.Boundary{Name: "Block", Border: square(1, 6, 0.5)})
+		assert.True(t, errors.As(err, &invalidBoundaryErr), "places cannot be divided further")
+
+		_, err = s.GetBoundary([]string{"Square"})
+		assert.True(t, errors.As(err, &boundaryNotFoundErr), "states are not boundaries")
+		_, err = s.GetChildren([]string{"Square", "South"})
+		assert.True(t, errors.As(err, &boundaryNotFoundErr), "GetChildren should return BoundaryNotFoundError for a missing county")
+	})
+
+	t.Run("should keep boundaries when their state is renamed", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err)
+		_, err = s.CreateBoundary([]string{"Square", "North"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err)
+
+		_, err = s.Update("Square", 0, geospatial.State{Name: "Block", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "Update should not produce an error for a valid state")
+
+		place, err := s.GetBoundary([]string{"Block", "North", "Town"})
+		assert.Nil(t, err, "boundaries should be found beneath the new name")
+		assert.Equal(t, []string{"Block", "North"}, place.Path, "boundary paths should follow the new name")
+		_, err = s.GetChildren([]string{"Square"})
+		var stateNotFoundErr *StateNotFoundError
+		assert.True(t, errors.As(err, &stateNotFoundErr), "boundaries should not be found beneath the old name")
+	})
+
+	t.Run("should not change boundaries already returned when their state is renamed", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err)
+		created, err := s.CreateBoundary([]string{"Square", "North"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err)
+		place, err := s.GetBoundary([]string{"Square", "North", "Town"})
+		assert.Nil(t, err)
+		counties, err := s.GetChildren([]string{"Square"})
+		assert.Nil(t, err)
+
+		held := make(chan []string)
+		go func() {
+			var paths []string
+			for i := 0; i < 100; i++ {
+				paths = append(paths, place.Path[0], created.Path[0], counties[0].Path[0])
+			}
+			held <- paths
+		}()
+		_, err = s.Update("Square", 0, geospatial.State{Name: "Block", Border: square(0, 0, 10)})
+		assert.Nil(t, err, "Update should not produce an error for a valid state")
+
+		for _, name := range <-held {
+			assert.Equal(t, "Square", name, "boundaries returned before the rename should keep their paths")
+		}
+		renamed, err := s.GetBoundary([]string{"Block", "North", "Town"})
+		assert.Nil(t, err, "boundaries should be found beneath the new name")
+		assert.Equal(t, []string{"Block", "North"}, renamed.Path)
+	})
+
+	t.Run("should delete boundaries along with everything beneath them", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err)
+		_, err = s.CreateBoundary([]string{"Square", "North"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err)
+
+		assert.Nil(t, s.DeleteBoundary([]string{"Square", "North"}), "DeleteBoundary should not produce an error")
+		_, err = s.GetBoundary([]string{"Square", "North", "Town"})
+		var boundaryNotFoundErr *BoundaryNotFoundError
+		assert.True(t, errors.As(err, &boundaryNotFoundErr), "places should be removed with their county")
+		err = s.DeleteBoundary([]string{"Square", "North"})
+		assert.True(t, errors.As(err, &boundaryNotFoundErr), "DeleteBoundary should return BoundaryNotFoundError for a boundary which does not exist")
+		var stateNotFoundErr *StateNotFoundError
+		err = s.DeleteBoundary([]string{"Missing", "North"})
+		assert.True(t, errors.As(err, &stateNotFoundErr), "DeleteBoundary should return StateNotFoundError for a state which does not exist")
+
+		_, err = s.CreateBoundary([]string{"Square"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err)
+		assert.Nil(t, s.Delete("Square", 0), "Delete should not produce an error")
+		_, err = s.Create(geospatial.State{Name: "Square", Border: square(0, 0, 10)})
+		assert.Nil(t, err)
+
+		children, err := s.GetChildren([]string{"Square"})
+		assert.Nil(t, err)
+		assert.Empty(t, children, "counties should be removed with their state")
+	})
 }
@@ -0,0 +1,186 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+)
+
+// How closely a search query matches the name or alias of a state. Lower values are better matches
+type MatchKind int
+
+const (
+	ExactMatch MatchKind = iota
+	PrefixMatch
+	SubstringMatch
+	FuzzyMatch
+)
+
+func (k MatchKind) String() string {
+	switch k {
+	case ExactMatch:
+		return "exact"
+	case PrefixMatch:
+		return "prefix"
+	case SubstringMatch:
+		return "substring"
+	default:
+		return "fuzzy"
+	}
+}
+
+// A state found by a search, along with the name or alias of the state which matched the query
+// and the number of single character edits between them for fuzzy matches
+type SearchMatch struct {
+	State    string
+	Matched  string
+	Kind     MatchKind
+	Distance int
+}
+
+// An index of the names and aliases of every state, kept sorted by their
+// search keys so that prefix matches can be found by binary search
+type nameIndex struct {
+	entries []indexEntry
+}
+
+type indexEntry struct {
+	key     string
+	display string
+	state   string
+}
+
+// Adds a name or alias of the state to the index
+func (idx *nameIndex) add(state string, names ...string) {
+	for _, name := range names {
+		entry := indexEntry{key: normalizeKey(name), display: name, state: state}
+		i := sort.Search(len(idx.entries), func(i int) bool {
+			return idx.entries[i].key >= entry.key
+		})
+		idx.entries = append(idx.entries, indexEntry{})
+		copy(idx.entries[i+1:], idx.entries[i:])
+		idx.entries[i] = entry
+	}
+}
+
+// Removes every name and alias of the state from the index
+func (idx *nameIndex) remove(state string) {
+	kept := idx.entries[:0]
+	for _, entry := range idx.entries {
+		if entry.state != state {
+			kept = append(kept, entry)
+		}
+	}
+	idx.entries = kept
+}
+
+// Finds the states whose names or aliases match the query, best match first. Names which start
+// with the query are found from the sorted index, then the rest of the index is scanned for names
+// which contain the query or are within a few typos of it. Each state is included once, for its
+// best matching name or alias. A limit of zero or less returns every match
+func (idx *nameIndex) search(query string, limit int) []SearchMatch {
+	query = normalizeKey(query)
+	if query == "" {
+		return []SearchMatch{}
+	}
+
+	best := map[string]SearchMatch{}
+	keep := func(entry indexEntry, kind MatchKind, distance int) {
+		match := SearchMatch{State: entry.state, Matched: entry.display, Kind: kind, Distance: distance}
+		if current, ok := best[entry.state]; !ok || betterMatch(match, current) {
+			best[entry.state] = match
+		}
+	}
+
+	start := sort.Search(len(idx.entries), func(i int) bool {
+		return idx.entries[i].key >= query
+	})
+	end := start
+	for ; end < len(idx.entries) && strings.HasPrefix(idx.entries[end].key, query); end++ {
+		if idx.entries[end].key == query {
+			keep(idx.entries[end], ExactMatch, 0)
+		} else {
+			keep(idx.entries[end], PrefixMatch, 0)
+		}
+	}
+
+	maxDistance := 1 + len([]rune(query))/4
+	for i, entry := range idx.entries {
+		if i >= start && i < end {
+			continue
+		}
+		if strings.Contains(entry.key, query) {
+			keep(entry, SubstringMatch, 0)
+		} else if distance := typoDistance(query, entry.key); distance <= maxDistance {
+			keep(entry, FuzzyMatch, distance)
+		}
+	}
+
+	matches := make([]SearchMatch, 0, len(best))
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return betterMatch(matches[i], matches[j])
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Orders matches by kind, then by distance, then by the length of the
+// matched name so that closer matches come first, then by state name
+func betterMatch(a, b SearchMatch) bool {
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	if len(a.Matched) != len(b.Matched) {
+		return len(a.Matched) < len(b.Matched)
+	}
+	return a.State < b.State
+}
+
+// The number of typos between the query and the name, taking the smallest of the edit distances to the
+// whole name and to the start of the name so that partially typed names can still match. The start of the
+// name is allowed to be a character longer or shorter than the query to allow for a missing or extra letter
+func typoDistance(query, name string) int {
+	q, n := []rune(query), []rune(name)
+	distance := editDistance(q, n)
+	for length := len(q) - 1; length <= len(q)+1; length++ {
+		if length > 0 && length < len(n) {
+			distance = min(distance, editDistance(q, n[:length]))
+		}
+	}
+	return distance
+}
+
+// The optimal string alignment distance between two strings: the number of insertions,
+// deletions, substitutions and transpositions of adjacent characters to turn one into the other
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
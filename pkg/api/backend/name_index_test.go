@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameIndex(t *testing.T) {
+	var idx nameIndex
+	idx.add("Pennsylvania", "Pennsylvania", "PA", "42")
+	idx.add("Kansas", "Kansas", "KS", "20")
+	idx.add("Arkansas", "Arkansas", "AR", "05")
+	idx.add("New York", "New  York", "NY", "36")
+
+	states := func(matches []SearchMatch) (names []string) {
+		for _, match := range matches {
+			names = append(names, match.State)
+		}
+		return
+	}
+
+	t.Run("should rank exact, prefix, substring and fuzzy matches", func(t *testing.T) {
+		assert.Equal(t, []SearchMatch{{State: "Pennsylvania", Matched: "Pennsylvania", Kind: PrefixMatch}}, idx.search("penn", 0))
+		assert.Equal(t, []SearchMatch{
+			{State: "Kansas", Matched: "Kansas", Kind: ExactMatch},
+			{State: "Arkansas", Matched: "Arkansas", Kind: SubstringMatch},
+		}, idx.search("KANSAS", 0))
+		assert.Equal(t, []SearchMatch{{State: "Pennsylvania", Matched: "Pennsylvania", Kind: FuzzyMatch, Distance: 1}}, idx.search("pensylvania", 0))
+		assert.Equal(t, []string{"Pennsylvania"}, states(idx.search("pnensyl", 0)), "transposed and partially typed names should match")
+	})
+
+	t.Run("should match aliases and collapse whitespace", func(t *testing.T) {
+		assert.Equal(t, []SearchMatch{{State: "Pennsylvania", Matched: "PA", Kind: ExactMatch}}, idx.search("pa", 1))
+		assert.Equal(t, []SearchMatch{{State: "New York", Matched: "New  York", Kind: ExactMatch}}, idx.search(" new york ", 1))
+	})
+
+	t.Run("should limit results and ignore empty queries", func(t *testing.T) {
+		assert.Equal(t, 1, len(idx.search("kansas", 1)))
+		assert.Empty(t, idx.search("  ", 0))
+		assert.Empty(t, idx.search("nunavut", 0))
+	})
+
+	t.Run("should remove every name of a state", func(t *testing.T) {
+		idx.remove("Kansas")
+		assert.Equal(t, []string{"Arkansas"}, states(idx.search("kansas", 0)))
+		assert.Empty(t, idx.search("ks", 0))
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"ohio", "ohio", 0},
+		{"ohio", "", 4},
+		{"ohio", "iowa", 4},
+		{"texas", "tesax", 2},
+		{"texas", "etxas", 1},
+		{"kansas", "arkansas", 2},
+	} {
+		assert.Equalf(t, tc.expected, editDistance([]rune(tc.a), []rune(tc.b)), "edit distance from %s to %s", tc.a, tc.b)
+	}
+}
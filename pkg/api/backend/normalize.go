@@ -0,0 +1,21 @@
+package backend
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Cleans up a name supplied by a client so that it can be stored and displayed: the name is put into
+// Unicode NFC form and its whitespace is trimmed and collapsed to single spaces, but its casing is kept
+func displayName(name string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(name)), " ")
+}
+
+// Translates a name into the key used to look it up in the data store, so that names which differ only
+// by casing, whitespace or the Unicode encoding of accented characters refer to the same thing. The
+// name is cleaned up as a display name, then case folded and put back into NFC form
+func normalizeKey(name string) string {
+	return norm.NFC.String(cases.Fold().String(displayName(name)))
+}
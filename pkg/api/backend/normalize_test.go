@@ -0,0 +1,23 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Run("should keep the casing of display names", func(t *testing.T) {
+		assert.Equal(t, "McAllen", displayName("McAllen"))
+		assert.Equal(t, "District of Columbia", displayName("  District \t of\nColumbia "))
+		assert.Equal(t, "Québec", displayName("Que\u0301bec"), "display names should be in NFC form")
+	})
+
+	t.Run("should normalize keys so that equivalent names match", func(t *testing.T) {
+		for _, name := range []string{"Québec", "QUÉBEC", "que\u0301bec", " QUE\u0301BEC  "} {
+			assert.Equal(t, "québec", normalizeKey(name), name)
+		}
+		assert.Equal(t, "strasse", normalizeKey("STRAẞE"), "case folding should expand characters without a single lowercase form")
+		assert.Equal(t, "", normalizeKey(" \t"))
+	})
+}
@@ -0,0 +1,186 @@
+package backend
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// A copy of everything in a data store at one point in time which can be saved and restored, e.g. to
+// persist the data store to disk or to migrate it into another backend. Boundaries are ordered so that
+// every boundary comes after the boundary or state above it, and the history is ordered by version
+type Snapshot struct {
+	Version    uint64                `json:"version"`
+	States     []SnapshotState       `json:"states"`
+	Regions    []SnapshotRegion      `json:"regions"`
+	Boundaries []geospatial.Boundary `json:"boundaries"`
+	History    []Revision            `json:"history,omitempty"`
+}
+
+// A state in a [Snapshot] along with the version at which it was last added or changed
+type SnapshotState struct {
+	geospatial.State
+	Version uint64 `json:"version"`
+}
+
+// A region in a [Snapshot]. Members are kept by name, including any
+// members which are not currently in the data store
+type SnapshotRegion struct {
+	Name    string   `json:"region"`
+	Members []string `json:"members"`
+}
+
+// Copies everything in the data store into a [Snapshot]
+func (s *StateLocationMemoryStore) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := Snapshot{Version: s.version, States: []SnapshotState{}, Regions: []SnapshotRegion{}, Boundaries: []geospatial.Boundary{}}
+	for _, state := range s.states {
+		snapshot.States = append(snapshot.States, SnapshotState{State: copyState(state), Version: state.Version})
+	}
+	sort.Slice(snapshot.States, func(i, j int) bool {
+		return snapshot.States[i].Version < snapshot.States[j].Version
+	})
+
+	for key := range s.regions {
+		snapshot.Regions = append(snapshot.Regions, s.snapshotRegion(key))
+	}
+	sort.Slice(snapshot.Regions, func(i, j int) bool {
+		return snapshot.Regions[i].Name < snapshot.Regions[j].Name
+	})
+
+	for _, state := range snapshot.States {
+		snapshot.Boundaries = append(snapshot.Boundaries, s.snapshotBoundaries(normalizeKey(state.Name))...)
+	}
+	snapshot.History = slices.Clone(s.history)
+	for i := range snapshot.History {
+		snapshot.History[i].State = copyState(snapshot.History[i].State)
+	}
+
+	return snapshot
+}
+
+// Copies the region into a [SnapshotRegion], naming members which are in the data store by their
+// display names and any others by their keys. Must be called with the lock held
+func (s *StateLocationMemoryStore) snapshotRegion(key string) SnapshotRegion {
+	var members []string
+	for _, member := range s.members[key] {
+		if state, ok := s.states[member]; ok {
+			member = state.Name
+		}
+		members = append(members, member)
+	}
+	return SnapshotRegion{Name: s.regions[key].Name, Members: members}
+}
+
+// Gets every boundary beneath the state, ordered so that each boundary comes after
+// the boundary above it. Must be called with the lock held
+func (s *StateLocationMemoryStore) snapshotBoundaries(key string) []geospatial.Boundary {
+	var boundaries []geospatial.Boundary
+
+	var walk func(node *boundaryNode)
+	walk = func(node *boundaryNode) {
+		children := make([]*boundaryNode, 0, len(node.children))
+		for _, child := range node.children {
+			children = append(children, child)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].boundary.Name < children[j].boundary.Name
+		})
+		for _, child := range children {
+			boundaries = append(boundaries, child.boundary)
+			walk(child)
+		}
+	}
+	if node, ok := s.hierarchy[key]; ok {
+		walk(node)
+	}
+
+	return boundaries
+}
+
+// Replaces everything in the data store with the contents of the [Snapshot], keeping the versions of the
+// states in the snapshot along with their history. Returns an error, leaving the data store unchanged, if the snapshot is invalid
+func (s *StateLocationMemoryStore) Restore(snapshot Snapshot) error {
+	restored, err := restoreSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	s.changes.Lock()
+	defer s.changes.Unlock()
+
+	s.replace(restored)
+	return nil
+}
+
+// Builds a new data store holding the contents of the [Snapshot].
+// Returns an error if the snapshot is invalid
+func restoreSnapshot(snapshot Snapshot) (*StateLocationMemoryStore, error) {
+	restored := NewMemoryStore()
+
+	for _, state := range snapshot.States {
+		valid, key, err := restored.validate(state.State, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		valid.Version = state.Version
+		restored.insert(key, valid)
+		restored.hierarchy[key] = newBoundaryNode(geospatial.Boundary{Name: valid.Name, Level: geospatial.LevelState})
+	}
+	restored.version = snapshot.Version
+
+	for _, region := range snapshot.Regions {
+		key := normalizeKey(region.Name)
+		if key == "" {
+			return nil, fmt.Errorf("invalid snapshot: region name is required")
+		}
+		var members []string
+		for _, member := range region.Members {
+			members = append(members, restored.resolve(member))
+		}
+		restored.members[key] = members
+		restored.regions[key] = restored.buildRegion(displayName(region.Name), members)
+	}
+
+	for _, boundary := range snapshot.Boundaries {
+		parent, err := restored.findBoundary(boundary.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		parent.children[normalizeKey(boundary.Name)] = newBoundaryNode(boundary)
+	}
+
+	restored.history = slices.Clone(snapshot.History)
+	sort.SliceStable(restored.history, func(i, j int) bool {
+		return restored.history[i].Version < restored.history[j].Version
+	})
+	for i := range restored.history {
+		restored.history[i].State = copyState(restored.history[i].State)
+		restored.history[i].State.Version = restored.history[i].Version
+		restored.indexRevision(i)
+	}
+
+	return restored, nil
+}
+
+// Replaces everything in the data store with the contents of a restored data store.
+// Must be called with the change lock held
+func (s *StateLocationMemoryStore) replace(restored *StateLocationMemoryStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states = restored.states
+	s.aliases = restored.aliases
+	s.index = restored.index
+	s.neighbors = restored.neighbors
+	s.regions = restored.regions
+	s.members = restored.members
+	s.hierarchy = restored.hierarchy
+	s.history = restored.history
+	s.revisions = restored.revisions
+	s.version = restored.version
+}
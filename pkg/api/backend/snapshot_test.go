@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotMemoryStore(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	newStore := func(t *testing.T) *StateLocationMemoryStore {
+		s := NewMemoryStore()
+		_, err := s.Create(geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}, Properties: map[string]interface{}{"fips": "01"}})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.CreateRegion(geospatial.Region{Name: "Both", States: []string{"West", "East"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+		_, err = s.CreateBoundary([]string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err, "data store should add valid county with no errors")
+		_, err = s.CreateBoundary([]string{"West", "North"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err, "data store should add valid place with no errors")
+		_, err = s.Update("East", 0, geospatial.State{Name: "East", Border: square(10, 0, 10), Aliases: []string{"ES"}})
+		assert.Nil(t, err, "data store should update state with no errors")
+		return s
+	}
+
+	t.Run("should restore everything in the snapshot, including versions", func(t *testing.T) {
+		s := newStore(t)
+
+		data, err := json.Marshal(s.Snapshot())
+		assert.Nil(t, err, "snapshot should marshal to json")
+		var snapshot Snapshot
+		assert.Nil(t, json.Unmarshal(data, &snapshot), "snapshot should unmarshal from json")
+
+		restored := NewMemoryStore()
+		assert.Nil(t, restored.Restore(snapshot), "snapshot should restore with no errors")
+		assert.Equal(t, s.Version(), restored.Version())
+		assert.Equal(t, s.Snapshot(), restored.Snapshot())
+
+		state, err := restored.GetByName("es")
+		assert.Nil(t, err, "restored states should be found by alias")
+		assert.Equal(t, "East", state.Name)
+		assert.Equal(t, uint64(3), state.Version, "restored states should keep their version")
+
+		region, err := restored.GetRegion("both")
+		assert.Nil(t, err, "restored regions should be found")
+		assert.Equal(t, []string{"West", "East"}, region.States)
+
+		place, err := restored.GetBoundary([]string{"west", "north", "town"})
+		assert.Nil(t, err, "restored boundaries should be found")
+		assert.Equal(t, []string{"West", "North"}, place.Path)
+
+		neighbors, err := restored.GetNeighbors("West")
+		assert.Nil(t, err, "restored states should have neighbors")
+		assert.Equal(t, 1, len(neighbors))
+	})
+
+	t.Run("should leave the data store unchanged when the snapshot is invalid", func(t *testing.T) {
+		s := newStore(t)
+		snapshot := s.Snapshot()
+		snapshot.Boundaries = append(snapshot.Boundaries, geospatial.Boundary{Name: "Lost", Path: []string{"Missing"}})
+
+		restored := newStore(t)
+		before := restored.Snapshot()
+		assert.NotNil(t, restored.Restore(snapshot), "snapshot with a boundary beneath a missing state should be invalid")
+		assert.Equal(t, before, restored.Snapshot())
+	})
+}
@@ -0,0 +1,681 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	_ "modernc.org/sqlite"
+)
+
+// The schema migrations of the database behind a [StateLocationSQLiteStore], applied in order when the
+// database is opened. The number of migrations already applied is kept in the database's user_version.
+// Names and aliases are stored both normalized, as the keys used for lookups, and as given by the client.
+// Borders are stored as WKB polygons alongside the bounding box used to prefilter location queries
+var sqliteMigrations = []string{
+	`CREATE TABLE states (
+		key        TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		version    INTEGER NOT NULL,
+		border     BLOB NOT NULL,
+		properties TEXT,
+		min_lng    REAL NOT NULL,
+		min_lat    REAL NOT NULL,
+		max_lng    REAL NOT NULL,
+		max_lat    REAL NOT NULL
+	);
+	CREATE INDEX states_name ON states (name);
+	CREATE INDEX states_bbox ON states (min_lng, max_lng, min_lat, max_lat);
+
+	CREATE TABLE aliases (
+		alias    TEXT PRIMARY KEY,
+		state    TEXT NOT NULL,
+		name     TEXT NOT NULL,
+		position INTEGER NOT NULL
+	);
+	CREATE INDEX aliases_state ON aliases (state, position);
+
+	CREATE TABLE regions (
+		key  TEXT PRIMARY KEY,
+		name TEXT NOT NULL
+	);
+	CREATE TABLE region_members (
+		region   TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		member   TEXT NOT NULL,
+		PRIMARY KEY (region, position)
+	);
+
+	CREATE TABLE boundaries (
+		id      INTEGER PRIMARY KEY,
+		state   TEXT NOT NULL,
+		path    TEXT NOT NULL,
+		name    TEXT NOT NULL,
+		level   TEXT NOT NULL,
+		border  BLOB NOT NULL,
+		min_lng REAL NOT NULL,
+		min_lat REAL NOT NULL,
+		max_lng REAL NOT NULL,
+		max_lat REAL NOT NULL
+	);
+	CREATE INDEX boundaries_state ON boundaries (state);
+
+	CREATE TABLE meta (
+		key   TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);`,
+
+	`CREATE TABLE revisions (
+		version    INTEGER PRIMARY KEY,
+		time       TEXT NOT NULL,
+		author     TEXT NOT NULL,
+		change     TEXT NOT NULL,
+		name       TEXT NOT NULL,
+		previous   TEXT NOT NULL,
+		fields     TEXT,
+		aliases    TEXT,
+		properties TEXT,
+		border     BLOB NOT NULL
+	);
+	CREATE INDEX revisions_name ON revisions (name);`,
+
+	`ALTER TABLE states ADD COLUMN country TEXT;
+	ALTER TABLE revisions ADD COLUMN country TEXT;`,
+}
+
+// A data store which keeps the states, regions and boundaries in a SQLite database, so that they survive
+// restarts and can be inspected with ordinary SQL tools. Location queries are answered by the database,
+// using the bounding box of each border to find the candidate states. All other lookups are served from
+// memory, as [StateLocationMemoryStore] does, and each change is written to the database in a single
+// transaction before it is made in memory
+type StateLocationSQLiteStore struct {
+	*StateLocationMemoryStore
+	db *sql.DB
+}
+
+// The query for the candidate states of a location query: those whose bounding box contains the location,
+// found with the states_bbox index. The borders of the candidates are then checked for the location
+const containingQuery = `SELECT key, name, version, border, country, properties FROM states
+	WHERE min_lng <= ? AND max_lng >= ? AND min_lat <= ? AND max_lat >= ?`
+
+// Constructor for the [StateLocationSQLiteStore] struct opens the database file, creating it if needed,
+// migrates its schema to the latest version and loads its contents
+func NewSQLiteStore(path string) (*StateLocationSQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("unable to open data store %s: %w", path, err)
+	}
+	if err := migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to open data store %s: %w", path, err)
+	}
+
+	s := &StateLocationSQLiteStore{StateLocationMemoryStore: NewMemoryStore(), db: db}
+	if err := s.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.StateLocationMemoryStore.journal = s
+
+	return s, nil
+}
+
+// Gets the [geospatial.State] objects from the database whose borders contain the coordinate,
+// only checking the borders of the states whose bounding box contains it
+func (s *StateLocationSQLiteStore) GetContaining(coord geospatial.Coordinate) ([]geospatial.State, error) {
+	states, _, err := s.locate(context.Background(), coord)
+	return states, err
+}
+
+// Gets the [geospatial.State] objects from the database whose borders contain the coordinate along with
+// the version of the data store they were read at
+func (s *StateLocationSQLiteStore) GetContainingWithVersion(coord geospatial.Coordinate) ([]geospatial.State, uint64, error) {
+	return s.locate(context.Background(), coord)
+}
+
+// Reads the states whose borders contain the coordinate, along with the version of the data store, in a
+// single read-only transaction run with the context of the request
+func (s *StateLocationSQLiteStore) locate(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var version int64
+	if err := tx.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'version'`).Scan(&version); err != nil && err != sql.ErrNoRows {
+		return nil, 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, containingQuery, coord.Lng, coord.Lng, coord.Lat, coord.Lat)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var states []geospatial.State
+	var keys []string
+	for rows.Next() {
+		key, state, err := scanState(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		if state.Contains(coord) {
+			states = append(states, state)
+			keys = append(keys, key)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i, key := range keys {
+		aliases, err := tx.QueryContext(ctx, `SELECT name FROM aliases WHERE state = ? ORDER BY position`, key)
+		if err != nil {
+			return nil, 0, err
+		}
+		for aliases.Next() {
+			var alias string
+			if err := aliases.Scan(&alias); err != nil {
+				aliases.Close()
+				return nil, 0, err
+			}
+			states[i].Aliases = append(states[i].Aliases, alias)
+		}
+		aliases.Close()
+		if err := aliases.Err(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return states, uint64(version), nil
+}
+
+// Replaces everything in the data store with the contents of the [Snapshot], e.g. to migrate another
+// data store into the database, keeping the versions of the states along with their history. Returns
+// an error, leaving the data store unchanged, if the snapshot is invalid
+func (s *StateLocationSQLiteStore) Import(snapshot Snapshot) error {
+	restored, err := restoreSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	memory := s.StateLocationMemoryStore
+	memory.changes.Lock()
+	defer memory.changes.Unlock()
+
+	ctx := context.Background()
+	err = s.transaction(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"states", "aliases", "regions", "region_members", "boundaries", "revisions", "meta"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return err
+			}
+		}
+		return insertSnapshot(ctx, tx, restored.Snapshot())
+	})
+	if err != nil {
+		return err
+	}
+
+	memory.replace(restored)
+	return nil
+}
+
+// Closes the database. The data store must not be used after it is closed
+func (s *StateLocationSQLiteStore) Close() error {
+	s.StateLocationMemoryStore.changes.Lock()
+	defer s.StateLocationMemoryStore.changes.Unlock()
+
+	return s.db.Close()
+}
+
+// Writes the change to the database in a single transaction before the change is made in memory. Changes to
+// a state write the state, its aliases, its revision and the version of the data store, along with every
+// boundary beneath the state and every region it belongs to when the state is renamed. The transaction is
+// rolled back if the context of the change is done before it commits. Called with the change lock held
+func (s *StateLocationSQLiteStore) persist(c change) error {
+	memory, ctx := s.StateLocationMemoryStore, c.ctx
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		switch c.op {
+		case opCreateRegion:
+			return replaceRegion(ctx, tx, c.key, c.region)
+		case opDeleteRegion:
+			return replaceRegion(ctx, tx, c.key, SnapshotRegion{})
+		case opCreateBoundary, opDeleteBoundary:
+			return replaceBoundaries(ctx, tx, c.key, memory.boundariesAfter(c))
+		}
+
+		for _, table := range []string{"states WHERE key", "aliases WHERE state"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" = ?", c.key); err != nil {
+				return err
+			}
+		}
+		if c.op != opDelete {
+			if err := insertState(ctx, tx, c.revision.State); err != nil {
+				return err
+			}
+		}
+		if c.op == opDelete || c.revision.Previous != "" {
+			if err := replaceBoundaries(ctx, tx, c.key, memory.boundariesAfter(c)); err != nil {
+				return err
+			}
+		}
+		if c.revision.Previous != "" {
+			for _, region := range memory.regionsAfter(c) {
+				if err := replaceRegion(ctx, tx, normalizeKey(region.Name), region); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := writeRevision(ctx, tx, c.revision); err != nil {
+			return err
+		}
+		return setVersion(ctx, tx, c.revision.Version)
+	})
+}
+
+// Runs the function in a database transaction which is rolled back if the context is done before it commits.
+// Returns the error of the context in place of the error of the database if the transaction was interrupted
+func (s *StateLocationSQLiteStore) transaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err == nil {
+		if err = fn(tx); err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("unable to write to the data store: %w", err)
+	}
+	return nil
+}
+
+// Inserts everything in the [Snapshot] into the empty tables of the database
+func insertSnapshot(ctx context.Context, tx *sql.Tx, snapshot Snapshot) error {
+	for _, state := range snapshot.States {
+		state.State.Version = state.Version
+		if err := insertState(ctx, tx, state.State); err != nil {
+			return err
+		}
+	}
+	for _, region := range snapshot.Regions {
+		if err := replaceRegion(ctx, tx, normalizeKey(region.Name), region); err != nil {
+			return err
+		}
+	}
+	boundaries := map[string][]geospatial.Boundary{}
+	for _, boundary := range snapshot.Boundaries {
+		key := normalizeKey(boundary.Path[0])
+		boundaries[key] = append(boundaries[key], boundary)
+	}
+	for key, beneath := range boundaries {
+		if err := replaceBoundaries(ctx, tx, key, beneath); err != nil {
+			return err
+		}
+	}
+	for _, revision := range snapshot.History {
+		if err := writeRevision(ctx, tx, revision); err != nil {
+			return err
+		}
+	}
+	return setVersion(ctx, tx, snapshot.Version)
+}
+
+// Inserts a state along with its aliases into the states and aliases tables
+func insertState(ctx context.Context, tx *sql.Tx, state geospatial.State) error {
+	properties, err := nullJSON(state.Properties, state.Properties == nil)
+	if err != nil {
+		return err
+	}
+
+	key := normalizeKey(state.Name)
+	bounds := state.Border.Bounds()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO states (key, name, version, border, country, properties, min_lng, min_lat, max_lng, max_lat)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key, state.Name, int64(state.Version), state.Border.MarshalWKB(), nullString(state.Country), properties,
+		bounds.Min.Lng, bounds.Min.Lat, bounds.Max.Lng, bounds.Max.Lat,
+	)
+	if err != nil {
+		return err
+	}
+
+	for position, alias := range state.Aliases {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO aliases (alias, state, name, position) VALUES (?, ?, ?, ?)`,
+			normalizeKey(alias), key, alias, position,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the region with the given key along with its members, removing it if the region has no name
+func replaceRegion(ctx context.Context, tx *sql.Tx, key string, region SnapshotRegion) error {
+	for _, table := range []string{"regions WHERE key", "region_members WHERE region"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" = ?", key); err != nil {
+			return err
+		}
+	}
+	if region.Name == "" {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO regions (key, name) VALUES (?, ?)`, key, region.Name); err != nil {
+		return err
+	}
+	for position, member := range region.Members {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO region_members (region, position, member) VALUES (?, ?, ?)`,
+			key, position, member,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the boundaries beneath the state with the given key, in order so that every boundary is
+// inserted after the boundary above it. Boundaries are kept under the key of the state in their path
+func replaceBoundaries(ctx context.Context, tx *sql.Tx, key string, boundaries []geospatial.Boundary) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM boundaries WHERE state = ?`, key); err != nil {
+		return err
+	}
+
+	for _, boundary := range boundaries {
+		path, err := json.Marshal(boundary.Path)
+		if err != nil {
+			return err
+		}
+		bounds := boundary.Border.Bounds()
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO boundaries (state, path, name, level, border, min_lng, min_lat, max_lng, max_lat)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			normalizeKey(boundary.Path[0]), string(path), boundary.Name, string(boundary.Level), boundary.Border.MarshalWKB(),
+			bounds.Min.Lng, bounds.Min.Lat, bounds.Max.Lng, bounds.Max.Lat,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sets the version of the data store in the meta table
+func setVersion(ctx context.Context, tx *sql.Tx, version uint64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO meta (key, value) VALUES ('version', ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`,
+		int64(version),
+	)
+	return err
+}
+
+// Reads everything in the database into a [Snapshot] and restores it into memory
+func (s *StateLocationSQLiteStore) load() error {
+	snapshot, err := s.read(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to read data store: %w", err)
+	}
+	return s.StateLocationMemoryStore.Restore(snapshot)
+}
+
+// Reads everything in the database into a [Snapshot]
+func (s *StateLocationSQLiteStore) read(ctx context.Context) (Snapshot, error) {
+	snapshot := Snapshot{States: []SnapshotState{}, Regions: []SnapshotRegion{}, Boundaries: []geospatial.Boundary{}}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return snapshot, err
+	}
+	defer tx.Rollback()
+
+	var version int64
+	if err := tx.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'version'`).Scan(&version); err != nil && err != sql.ErrNoRows {
+		return snapshot, err
+	}
+	snapshot.Version = uint64(version)
+
+	aliases := map[string][]string{}
+	err = query(ctx, tx, `SELECT state, name FROM aliases ORDER BY state, position`, func(rows *sql.Rows) error {
+		var key, alias string
+		if err := rows.Scan(&key, &alias); err != nil {
+			return err
+		}
+		aliases[key] = append(aliases[key], alias)
+		return nil
+	})
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = query(ctx, tx, `SELECT key, name, version, border, country, properties FROM states ORDER BY version`, func(rows *sql.Rows) error {
+		key, state, err := scanState(rows)
+		if err != nil {
+			return err
+		}
+		state.Aliases = aliases[key]
+		snapshot.States = append(snapshot.States, SnapshotState{State: state, Version: state.Version})
+		return nil
+	})
+	if err != nil {
+		return snapshot, err
+	}
+
+	members := map[string][]string{}
+	err = query(ctx, tx, `SELECT region, member FROM region_members ORDER BY region, position`, func(rows *sql.Rows) error {
+		var key, member string
+		if err := rows.Scan(&key, &member); err != nil {
+			return err
+		}
+		members[key] = append(members[key], member)
+		return nil
+	})
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = query(ctx, tx, `SELECT key, name FROM regions`, func(rows *sql.Rows) error {
+		var key, name string
+		if err := rows.Scan(&key, &name); err != nil {
+			return err
+		}
+		snapshot.Regions = append(snapshot.Regions, SnapshotRegion{Name: name, Members: members[key]})
+		return nil
+	})
+	if err != nil {
+		return snapshot, err
+	}
+	sort.Slice(snapshot.Regions, func(i, j int) bool {
+		return snapshot.Regions[i].Name < snapshot.Regions[j].Name
+	})
+
+	err = query(ctx, tx, `SELECT path, name, level, border FROM boundaries ORDER BY id`, func(rows *sql.Rows) error {
+		var path, name, level string
+		var border []byte
+		if err := rows.Scan(&path, &name, &level, &border); err != nil {
+			return err
+		}
+
+		boundary := geospatial.Boundary{Name: name, Level: geospatial.Level(level)}
+		if err := json.Unmarshal([]byte(path), &boundary.Path); err != nil {
+			return fmt.Errorf("invalid path for boundary %s: %w", name, err)
+		}
+		if err := boundary.Border.UnmarshalWKB(border); err != nil {
+			return fmt.Errorf("invalid border for boundary %s: %w", name, err)
+		}
+		snapshot.Boundaries = append(snapshot.Boundaries, boundary)
+		return nil
+	})
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = query(ctx, tx, `SELECT version, time, author, change, name, previous, fields, aliases, properties, border, country
+		FROM revisions ORDER BY version`, func(rows *sql.Rows) error {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return err
+		}
+		snapshot.History = append(snapshot.History, revision)
+		return nil
+	})
+
+	return snapshot, err
+}
+
+// Inserts a revision into the revisions table
+func writeRevision(ctx context.Context, tx *sql.Tx, revision Revision) error {
+	fields, err := nullJSON(revision.Fields, len(revision.Fields) == 0)
+	if err != nil {
+		return err
+	}
+	aliases, err := nullJSON(revision.State.Aliases, len(revision.State.Aliases) == 0)
+	if err != nil {
+		return err
+	}
+	properties, err := nullJSON(revision.State.Properties, len(revision.State.Properties) == 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO revisions (version, time, author, change, name, previous, fields, aliases, properties, border, country)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		int64(revision.Version), revision.Time.Format(time.RFC3339Nano), revision.Author, string(revision.Change),
+		revision.State.Name, revision.Previous, fields, aliases, properties, revision.State.Border.MarshalWKB(),
+		nullString(revision.State.Country),
+	)
+	return err
+}
+
+// Encodes the value as JSON for a column which is NULL when the value is empty
+func nullJSON(value interface{}, empty bool) (sql.NullString, error) {
+	if empty {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// Stores an empty string as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// Reads a row of the revisions table
+func scanRevision(rows *sql.Rows) (Revision, error) {
+	var revision Revision
+	var version int64
+	var at, change string
+	var country sql.NullString
+	var fields, aliases, properties, border []byte
+	err := rows.Scan(&version, &at, &revision.Author, &change, &revision.State.Name, &revision.Previous, &fields, &aliases, &properties, &border, &country)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	revision.Version = uint64(version)
+	revision.State.Country = country.String
+	revision.Change = ChangeType(change)
+	if revision.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+		return Revision{}, fmt.Errorf("invalid time for revision %d: %w", version, err)
+	}
+	if err := revision.State.Border.UnmarshalWKB(border); err != nil {
+		return Revision{}, fmt.Errorf("invalid border for revision %d: %w", version, err)
+	}
+	for _, column := range []struct {
+		data  []byte
+		value interface{}
+	}{{fields, &revision.Fields}, {aliases, &revision.State.Aliases}, {properties, &revision.State.Properties}} {
+		if column.data == nil {
+			continue
+		}
+		if err := json.Unmarshal(column.data, column.value); err != nil {
+			return Revision{}, fmt.Errorf("invalid revision %d: %w", version, err)
+		}
+	}
+	return revision, nil
+}
+
+// Runs the query and calls the function for each row of the result
+func query(ctx context.Context, tx *sql.Tx, statement string, fn func(rows *sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Reads a row of the states table, without the aliases of the state
+func scanState(rows *sql.Rows) (string, geospatial.State, error) {
+	var key string
+	var version int64
+	var border, properties []byte
+	var country sql.NullString
+	var state geospatial.State
+	if err := rows.Scan(&key, &state.Name, &version, &border, &country, &properties); err != nil {
+		return "", geospatial.State{}, err
+	}
+
+	state.Version = uint64(version)
+	state.Country = country.String
+	if err := state.Border.UnmarshalWKB(border); err != nil {
+		return "", geospatial.State{}, fmt.Errorf("invalid border for state %s: %w", state.Name, err)
+	}
+	if properties != nil {
+		if err := json.Unmarshal(properties, &state.Properties); err != nil {
+			return "", geospatial.State{}, fmt.Errorf("invalid properties for state %s: %w", state.Name, err)
+		}
+	}
+	return key, state, nil
+}
+
+// Applies any schema migrations which have not yet been applied to the database
+func migrate(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("database schema version %d is newer than the latest supported version %d", version, len(sqliteMigrations))
+	}
+
+	for i, migration := range sqliteMigrations[version:] {
+		if _, err := tx.ExecContext(ctx, migration); err != nil {
+			return fmt.Errorf("unable to apply schema migration %d: %w", version+i+1, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, len(sqliteMigrations))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
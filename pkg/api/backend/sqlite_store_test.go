@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	populate := func(t *testing.T, s writableStore) {
+		_, err := s.Create(geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS", "01"}, Properties: map[string]interface{}{"capital": "Westville"}})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.Create(geospatial.State{Name: "Far", Border: square(50, 50, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		_, err = s.CreateRegion(geospatial.Region{Name: "Both", States: []string{"West", "East", "Far"}})
+		assert.Nil(t, err, "data store should add valid region with no errors")
+		_, err = s.CreateBoundary([]string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+		assert.Nil(t, err, "data store should add valid county with no errors")
+		_, err = s.CreateBoundary([]string{"ws", "north"}, geospatial.Boundary{Name: "Town", Border: square(1, 6, 1)})
+		assert.Nil(t, err, "data store should add valid place with no errors")
+		_, err = s.Update("ws", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10), Aliases: []string{"WS", "01"}, Properties: map[string]interface{}{"capital": "Westville"}})
+		assert.Nil(t, err, "data store should update state with no errors")
+		assert.Nil(t, s.Delete("Far", 0), "data store should delete state with no errors")
+	}
+
+	t.Run("should recover every change after it is reopened", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.sqlite")
+		s, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+		assert.Nil(t, s.DeleteBoundary([]string{"Western", "North", "Town"}), "data store should delete boundary with no errors")
+		want := s.Snapshot()
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+
+		reopened, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should reopen")
+		assert.Equal(t, want, reopened.Snapshot(), "sqlite store should recover every change")
+
+		region, err := reopened.GetRegion("both")
+		assert.Nil(t, err, "regions should be recovered")
+		assert.Equal(t, []string{"Western", "East"}, region.States, "regions should follow renamed and deleted members")
+		assert.Nil(t, reopened.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should store names, aliases and borders in queryable columns", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.sqlite")
+		s, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+
+		db, err := sql.Open("sqlite", path)
+		assert.Nil(t, err, "database should open with the sql package")
+		defer db.Close()
+
+		var name string
+		var minLng, maxLat float64
+		var border []byte
+		err = db.QueryRow(`SELECT s.name, s.min_lng, s.max_lat, s.border FROM aliases a JOIN states s ON s.key = a.state WHERE a.alias = 'ws'`).Scan(&name, &minLng, &maxLat, &border)
+		assert.Nil(t, err, "state should be found by its normalized alias")
+		assert.Equal(t, "Western", name)
+		assert.Equal(t, []float64{0, 10}, []float64{minLng, maxLat}, "bounding box should be stored with the state")
+
+		var polygon geospatial.Polygon
+		assert.Nil(t, polygon.UnmarshalWKB(border), "border should be stored as WKB")
+		assert.Equal(t, geospatial.Polygon(square(0, 0, 10)), polygon)
+
+		var version int
+		assert.Nil(t, db.QueryRow(`PRAGMA user_version`).Scan(&version))
+		assert.Equal(t, len(sqliteMigrations), version, "every schema migration should be applied")
+	})
+
+	t.Run("should find the states containing a location", func(t *testing.T) {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "states.sqlite"))
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+
+		states, err := s.GetContaining(geospatial.Coordinate{Lng: 5, Lat: 5})
+		assert.Nil(t, err, "location query should not produce an error")
+		assert.Equal(t, 1, len(states))
+		assert.Equal(t, "Western", states[0].Name)
+		assert.Equal(t, []string{"WS", "01"}, states[0].Aliases)
+		assert.Equal(t, "Westville", states[0].Properties["capital"])
+
+		states, err = s.GetContaining(geospatial.Coordinate{Lng: 50.5, Lat: 50.5})
+		assert.Nil(t, err, "location query should not produce an error")
+		assert.Equal(t, 0, len(states), "deleted states should not be found")
+
+		states, version, err := s.GetContainingWithVersion(geospatial.Coordinate{Lng: 15, Lat: 5})
+		assert.Nil(t, err, "location query should not produce an error")
+		assert.Equal(t, 1, len(states))
+		assert.Equal(t, "East", states[0].Name)
+		assert.Equal(t, s.Version(), version, "location queries should read the version of the database")
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should prefilter location queries with the bounding box index", func(t *testing.T) {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "states.sqlite"))
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+
+		rows, err := s.db.Query(`EXPLAIN QUERY PLAN `+containingQuery, 5.0, 5.0, 5.0, 5.0)
+		assert.Nil(t, err, "the location query should be explained")
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			assert.Nil(t, rows.Scan(&id, &parent, &unused, &detail))
+			plan = append(plan, detail)
+		}
+		assert.Nil(t, rows.Close())
+		assert.Equal(t, 1, len(plan), "the location query should only read the states table")
+		assert.Contains(t, plan[0], "USING INDEX states_bbox", "the location query should search the bounding box index")
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should migrate a memory store snapshot", func(t *testing.T) {
+		memory := NewMemoryStore()
+		populate(t, memory)
+
+		path := filepath.Join(t.TempDir(), "states.sqlite")
+		s, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should create a new database")
+		_, err = s.Create(geospatial.State{Name: "Replaced", Border: square(80, 80, 1)})
+		assert.Nil(t, err, "data store should add valid state with no errors")
+		assert.Nil(t, s.Import(memory.Snapshot()), "snapshot should be imported with no errors")
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+
+		reopened, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should reopen")
+		assert.Equal(t, memory.Snapshot(), reopened.Snapshot(), "imported snapshot should replace everything in the database")
+		assert.Nil(t, reopened.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should not make a change which cannot be written to the database", func(t *testing.T) {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "states.sqlite"))
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+		want := s.Snapshot()
+		assert.Nil(t, s.db.Close(), "the database should close")
+
+		_, err = s.Update("ws", 0, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+		assert.NotNil(t, err, "Update should fail when the database cannot be written")
+		state, err := s.GetByName("ws")
+		assert.Nil(t, err, "the state should still be found by its old name")
+		assert.Equal(t, "Western", state.Name, "lookups should not see a change which was not written")
+		assert.NotNil(t, s.Import(Snapshot{}), "Import should fail when the database cannot be written")
+		assert.Equal(t, want, s.Snapshot(), "a change which was not written should not be made")
+	})
+
+	t.Run("should not make a change whose request is cancelled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.sqlite")
+		s, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+		want := s.Snapshot()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = s.persist(change{ctx: ctx, op: opCreateRegion, key: "west", region: SnapshotRegion{Name: "West", Members: []string{"Western"}}})
+		assert.True(t, errors.Is(err, context.Canceled), "the transaction should not be run for a cancelled context")
+
+		store := WithContext(s)
+		_, err = store.Update(WithAuthor(ctx, "alice"), "ws", 0, geospatial.State{Name: "West", Border: square(0, 0, 5)})
+		assert.True(t, errors.Is(err, context.Canceled), "Update should return the error of the context")
+		assert.Equal(t, want, s.Snapshot(), "a change whose request is cancelled should not be made")
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+
+		reopened, err := NewSQLiteStore(path)
+		assert.Nil(t, err, "sqlite store should reopen")
+		assert.Equal(t, want, reopened.Snapshot(), "a change whose request is cancelled should not be written")
+		assert.Nil(t, reopened.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should return the same errors as the memory store", func(t *testing.T) {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "states.sqlite"))
+		assert.Nil(t, err, "sqlite store should create a new database")
+		populate(t, s)
+
+		var conflict *ConflictError
+		_, err = s.Create(geospatial.State{Name: "Other", Border: square(80, 80, 1), Aliases: []string{"ws"}})
+		assert.True(t, errors.As(err, &conflict), "duplicate alias should produce ConflictError")
+		var stateNotFound *StateNotFoundError
+		assert.True(t, errors.As(s.Delete("Far", 0), &stateNotFound), "missing state should produce StateNotFoundError")
+		var mismatch *VersionMismatchError
+		_, err = s.Update("East", 1, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+		assert.True(t, errors.As(err, &mismatch), "stale version should produce VersionMismatchError")
+		assert.Nil(t, s.Close(), "sqlite store should close with no errors")
+	})
+
+	t.Run("should not open a database with a newer schema", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.sqlite")
+		db, err := sql.Open("sqlite", path)
+		assert.Nil(t, err, "database should open with the sql package")
+		_, err = db.Exec(`PRAGMA user_version = 99`)
+		assert.Nil(t, err)
+		assert.Nil(t, db.Close())
+
+		_, err = NewSQLiteStore(path)
+		assert.NotNil(t, err, "database with a newer schema should not be opened")
+	})
+}
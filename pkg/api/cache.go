@@ -0,0 +1,26 @@
+package api
+
+import (
+	"strings"
+)
+
+// Checks an If-Match or If-None-Match request header value against the entity tag of
+// the current representation using the weak comparison function from RFC 9110
+// [See: 8.8.3.2 Comparison](https://www.rfc-editor.org/rfc/rfc9110#section-8.8.3.2)
+func ETagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
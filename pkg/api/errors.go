@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/render"
@@ -41,6 +43,42 @@ func InternalServerError(err error) render.Renderer {
 	}
 }
 
+// The non-standard status code, also used by nginx, for a request which the client cancelled before it
+// could be answered. The client never reads the response, but the status is still logged
+const StatusClientClosedRequest = 499
+
+// creates the go-chi renderer for HTTP 499 responses
+func ClientClosedRequestError(err error) render.Renderer {
+	return &ErrorResponse{
+		Err:            err,
+		HTTPStatusCode: StatusClientClosedRequest,
+		StatusText:     "Client Closed Request",
+		ErrorText:      err.Error(),
+	}
+}
+
+// creates the go-chi renderer for HTTP 504 responses
+func GatewayTimeoutError(err error) render.Renderer {
+	return &ErrorResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusGatewayTimeout,
+		StatusText:     "Gateway Timeout",
+		ErrorText:      err.Error(),
+	}
+}
+
+// creates the go-chi renderer for an error returned by the data store: HTTP 499 if the request was cancelled,
+// HTTP 504 if it passed its deadline before the data store answered, or HTTP 500 for any other error
+func DataStoreError(err error) render.Renderer {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClientClosedRequestError(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return GatewayTimeoutError(err)
+	}
+	return InternalServerError(err)
+}
+
 // creates the go-chi renderer for HTTP 400 responses
 func BadRequestError(err error) render.Renderer {
 	return &ErrorResponse{
@@ -50,3 +88,43 @@ func BadRequestError(err error) render.Renderer {
 		ErrorText:      err.Error(),
 	}
 }
+
+// creates the go-chi renderer for HTTP 409 responses
+func ConflictError(err error) render.Renderer {
+	return &ErrorResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusConflict,
+		StatusText:     "Conflict",
+		ErrorText:      err.Error(),
+	}
+}
+
+// creates the go-chi renderer for HTTP 412 responses
+func PreconditionFailedError(err error) render.Renderer {
+	return &ErrorResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusPreconditionFailed,
+		StatusText:     "Precondition Failed",
+		ErrorText:      err.Error(),
+	}
+}
+
+// creates the go-chi renderer for HTTP 413 responses
+func RequestTooLargeError(err error) render.Renderer {
+	return &ErrorResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusRequestEntityTooLarge,
+		StatusText:     "Request Entity Too Large",
+		ErrorText:      err.Error(),
+	}
+}
+
+// creates the go-chi renderer for HTTP 415 responses
+func UnsupportedMediaTypeError(err error) render.Renderer {
+	return &ErrorResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusUnsupportedMediaType,
+		StatusText:     "Unsupported Media Type",
+		ErrorText:      err.Error(),
+	}
+}
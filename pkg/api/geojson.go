@@ -4,20 +4,125 @@
 package api
 
 import (
+	"encoding/json"
+	"mime"
 	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
 
 	"github.com/aaronireland/state-server/pkg/geospatial"
 )
 
-// GeoJSON schema for the geometry object of a feature
+// The RFC 7946 media type for GeoJSON
+const ContentTypeGeoJSON = "application/geo+json"
+
+// GeoJSON schema for the geometry object of a feature. Polygon geometries hold their
+// rings in Coordinates while MultiPolygon geometries hold their polygons in MultiPolygon
 type Geometry struct {
-	Type        string                    `json:"type"`
-	Coordinates [][]geospatial.Coordinate `json:"coordinates"`
+	Type         string                    `json:"type"`
+	Coordinates  [][]geospatial.Coordinate `json:"coordinates"`
+	MultiPolygon geospatial.MultiPolygon   `json:"-"`
+}
+
+// Translates the [geospatial.MultiPolygon] into a GeoJSON geometry, using a Polygon
+// geometry if it only contains a single polygon
+func NewGeometry(m geospatial.MultiPolygon) Geometry {
+	if len(m) != 1 {
+		return Geometry{Type: "MultiPolygon", MultiPolygon: m}
+	}
+
+	var rings [][]geospatial.Coordinate
+	for _, ring := range m[0] {
+		rings = append(rings, ring)
+	}
+	return Geometry{Type: "Polygon", Coordinates: rings}
+}
+
+type multiPolygonGeometry struct {
+	Type        string                  `json:"type"`
+	Coordinates geospatial.MultiPolygon `json:"coordinates"`
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	if g.Type == "MultiPolygon" {
+		return json.Marshal(multiPolygonGeometry{g.Type, g.MultiPolygon})
+	}
+
+	type geometry Geometry
+	return json.Marshal(geometry(g))
+}
+
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+
+	if probe.Type == "MultiPolygon" {
+		var multi multiPolygonGeometry
+		if err := json.Unmarshal(data, &multi); err != nil {
+			return err
+		}
+		*g = Geometry{Type: multi.Type, MultiPolygon: multi.Coordinates}
+		return nil
+	}
+
+	type geometry Geometry
+	return json.Unmarshal(data, (*geometry)(g))
 }
 
-// GeoJSON schema for the properties object of a feature
+// GeoJSON schema for the properties object of a feature. The name of the state is held in the
+// state property, its country in the country property, its aliases in the aliases property and
+// any other properties of the state are held alongside them
 type Properties struct {
-	State string `json:"state"`
+	State   string
+	Country string
+	Aliases []string
+	Other   map[string]interface{}
+}
+
+func (p Properties) MarshalJSON() ([]byte, error) {
+	properties := make(map[string]interface{}, len(p.Other)+3)
+	for key, value := range p.Other {
+		properties[key] = value
+	}
+	properties["state"] = p.State
+	if p.Country != "" {
+		properties["country"] = p.Country
+	}
+	if len(p.Aliases) > 0 {
+		properties["aliases"] = p.Aliases
+	}
+
+	return json.Marshal(properties)
+}
+
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	var properties map[string]interface{}
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return err
+	}
+
+	name, _ := properties["state"].(string)
+	country, _ := properties["country"].(string)
+	aliases, _ := properties["aliases"].([]interface{})
+	delete(properties, "state")
+	delete(properties, "country")
+	delete(properties, "aliases")
+
+	*p = Properties{State: name, Country: country}
+	for _, alias := range aliases {
+		if alias, ok := alias.(string); ok {
+			p.Aliases = append(p.Aliases, alias)
+		}
+	}
+	if len(properties) > 0 {
+		p.Other = properties
+	}
+	return nil
 }
 
 // GeoJSON schema for a feature object
@@ -41,3 +146,52 @@ func (sr Feature) Render(w http.ResponseWriter, r *http.Request) error {
 func (scr FeatureCollection) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
+
+// Translates the [geospatial.State] object from the [geospatial] package into a GeoJSON feature
+func NewFeature(state geospatial.State) Feature {
+	return Feature{
+		Type: "Feature",
+		Geometry: Geometry{
+			Type:        "Polygon",
+			Coordinates: [][]geospatial.Coordinate{state.Border},
+		},
+		Properties: Properties{State: state.Name, Country: state.Country, Aliases: state.Aliases, Other: state.Properties},
+	}
+}
+
+// Translates an array of GeoJSON features into a GeoJSON FeatureCollection
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+}
+
+// Checks the request Accept header for the RFC 7946 GeoJSON media type
+func AcceptsGeoJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accepted); err == nil && mediaType == ContentTypeGeoJSON {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshals the given GeoJSON object into the response body with the RFC 7946 media type
+// and any response status set with the go-chi renderer
+func GeoJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		render.Render(w, r, InternalServerError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeGeoJSON)
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	w.Write(body)
+}
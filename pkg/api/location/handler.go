@@ -1,28 +1,70 @@
 package location
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"net/url"
+	"runtime"
+	"sort"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aaronireland/state-server/pkg/api"
 	"github.com/aaronireland/state-server/pkg/geospatial"
 	"github.com/go-chi/render"
 )
 
+// The caching policy for location lookup responses which can be revalidated using the ETag
+const cacheControl = "public, max-age=60"
+
+// Identifies this run of the server in the ETag of location lookup responses. A data store kept in memory
+// starts again from version zero when the server restarts, so the version alone could match a response
+// cached before the restart
+var epoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
 type RouteHandler struct {
 	store DataProvider
 }
 
+// gets every [geospatial.State] object in the data store, or as they were at the given time unless it is zero
+func (h RouteHandler) getStates(ctx context.Context, asOf time.Time) ([]geospatial.State, error) {
+	if asOf.IsZero() {
+		return h.store.GetAll(ctx)
+	}
+	return h.store.GetAllAsOf(ctx, asOf)
+}
+
+// gets the [geospatial.State] objects whose borders contain the coordinate, or whose borders contained it
+// at the given time unless it is zero
+func (h RouteHandler) getContaining(ctx context.Context, coord geospatial.Coordinate, asOf time.Time) ([]geospatial.State, error) {
+	if asOf.IsZero() {
+		return h.store.GetContaining(ctx, coord)
+	}
+	return h.store.GetContainingAsOf(ctx, coord, asOf)
+}
+
 // checks each [geospatial.State] object to see if the given geographic coordinate is contained within
 // its borders
 func getStateForLocation(states []geospatial.State, coord geospatial.Coordinate) (inStates []string) {
 
+	for _, state := range getStatesContaining(states, coord) {
+		inStates = append(inStates, state.Name)
+	}
+
+	return
+}
+
+// filters the [geospatial.State] objects down to those whose borders contain the given coordinate
+func getStatesContaining(states []geospatial.State, coord geospatial.Coordinate) (inStates []geospatial.State) {
+
 	for _, state := range states {
 		if state.Contains(coord) {
-			inStates = append(inStates, state.Name)
+			inStates = append(inStates, state)
 		}
 	}
 
@@ -30,51 +72,468 @@ func getStateForLocation(states []geospatial.State, coord geospatial.Coordinate)
 }
 
 // HTTP Request handler for the POST / endpoint which returns a list of state names or HTTP 404 error response
-// for the coordinate given in the request latitude and longitude form fields
+// for the coordinate given in the request. The location is given as latitude and longitude form fields, a
+// JSON object with latitude and longitude fields or a GeoJSON Point. Clients which accept application/geo+json
+// receive a GeoJSON FeatureCollection of the matching states. The optional asOf query parameter checks the
+// location against the states as they were at that time
 func (h RouteHandler) CheckLocationStates(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
+	asOf, err := api.ParseAsOf(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	coord, err := decodeLocation(r)
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	states, err := h.getContaining(r.Context(), coord, asOf)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	renderLocationStates(w, r, states, coord)
+}
+
+// HTTP Request handler for the GET /api/v1/locate endpoint which returns a list of state names or HTTP 404
+// error response for the coordinate given in the lat and lng query parameters. Responses are cacheable and
+// tagged with the version of the data store the states were read at so clients and edge caches can
+// revalidate them. The optional asOf query parameter locates the coordinate in the states as they were at
+// that time, in which case the response is not tagged
+func (h RouteHandler) LocateStates(w http.ResponseWriter, r *http.Request) {
+	coord, err := ParseLocationQuery(r.URL.Query())
 	if err != nil {
 		render.Render(w, r, api.BadRequestError(err))
 		return
 	}
 
-	params, err := url.ParseQuery(string(body))
+	asOf, err := api.ParseAsOf(r.URL.Query())
 	if err != nil {
 		render.Render(w, r, api.BadRequestError(err))
 		return
 	}
 
-	var latitude, longitude float64
-	if val, ok := params["latitude"]; ok {
-		if lat, err := strconv.ParseFloat(val[0], 64); err != nil {
-			render.Render(w, r, api.BadRequestError(fmt.Errorf("invalid latitude: %v", val[0])))
+	if !asOf.IsZero() {
+		states, err := h.store.GetContainingAsOf(r.Context(), coord, asOf)
+		if err != nil {
+			render.Render(w, r, api.DataStoreError(err))
 			return
-		} else {
-			latitude = lat
 		}
+		renderLocationStates(w, r, states, coord)
+		return
 	}
 
-	if val, ok := params["longitude"]; ok {
-		if lng, err := strconv.ParseFloat(val[0], 64); err != nil {
-			render.Render(w, r, api.BadRequestError(fmt.Errorf("invalid longitude: %v", val[0])))
-			return
+	states, version, err := h.store.GetContainingWithVersion(r.Context(), coord)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%d"`, epoch, version)
+	if api.AcceptsGeoJSON(r) {
+		etag = fmt.Sprintf(`"%s-%d-geojson"`, epoch, version)
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept")
+
+	if api.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	renderLocationStates(w, r, states, coord)
+}
+
+// renders the names of the states in which the coordinate is contained, a GeoJSON FeatureCollection of
+// those states if the client accepts application/geo+json or an HTTP 404 error response if there are none
+func renderLocationStates(w http.ResponseWriter, r *http.Request, locationInStates []geospatial.State, coord geospatial.Coordinate) {
+	if len(locationInStates) == 0 {
+		render.Render(w, r, api.NotFoundError(fmt.Errorf("%s not within any state", coord.String())))
+		return
+	}
+
+	if api.AcceptsGeoJSON(r) {
+		var features []api.Feature
+		for _, state := range locationInStates {
+			features = append(features, api.NewFeature(state))
+		}
+		api.GeoJSON(w, r, api.NewFeatureCollection(features))
+		return
+	}
+
+	var names []string
+	for _, state := range locationInStates {
+		names = append(names, state.Name)
+	}
+	render.JSON(w, r, names)
+}
+
+// checks each point against the same snapshot of [geospatial.State] objects, spreading the work across
+// a pool of goroutines. The results are returned in the same order as the given points
+func locateAll(states []geospatial.State, points []PointRequest) []PointResult {
+	results := make([]PointResult, len(points))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = locate(states, points[i])
+			}
+		}()
+	}
+
+	for i := range points {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func locate(states []geospatial.State, point PointRequest) PointResult {
+	result := PointResult{ID: point.ID, States: []string{}}
+
+	coord, err := point.Coordinate()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if inStates := getStateForLocation(states, coord); len(inStates) > 0 {
+		result.States = inStates
+	}
+	return result
+}
+
+// The largest batch request body given as a JSON array, which is read in full before any point is located.
+// Batches of newline delimited JSON are located as they are read and have no limit
+var maxBatchBytes int64 = 32 << 20
+
+// The number of points of a newline delimited JSON batch which are located at once while their results
+// wait to be written in order
+var streamWindow = 4 * runtime.GOMAXPROCS(0)
+
+// locates each point read from the stream against the same snapshot of [geospatial.State] objects, up to
+// streamWindow points at a time, and passes the results to emit in the same order as the points as soon as
+// they are ready. Flush is set when no other result is ready yet. Returns the first error from reading the
+// stream or from emit
+func streamPoints(states []geospatial.State, next func() (PointRequest, error), emit func(result PointResult, flush bool) error) error {
+	pending := make(chan chan PointResult, streamWindow)
+	done := make(chan struct{})
+
+	var readErr error
+	go func() {
+		defer close(pending)
+		for {
+			point, err := next()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				readErr = err
+				return
+			}
+
+			result := make(chan PointResult, 1)
+			select {
+			case pending <- result:
+			case <-done:
+				return
+			}
+			go func() {
+				result <- locate(states, point)
+			}()
+		}
+	}()
+
+	for result := range pending {
+		if err := emit(<-result, len(pending) == 0); err != nil {
+			close(done)
+			for range pending {
+			}
+			return err
+		}
+	}
+	return readErr
+}
+
+// HTTP Request handler for the POST /api/v1/locate/batch endpoint which returns the state names for each
+// point given in the request body as either a JSON array or newline delimited JSON. Results are rendered
+// in the same order as the request. A request with the application/x-ndjson content type is streamed: each
+// point is located as it is read and its result is written as newline delimited JSON as soon as it is
+// ready, ending with an error result if a point cannot be read. The optional asOf query parameter locates
+// the points in the states as they were at that time
+func (h RouteHandler) BatchLocationStates(w http.ResponseWriter, r *http.Request) {
+	asOf, err := api.ParseAsOf(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == ContentTypeNDJSON {
+		h.streamLocationStates(w, r, asOf)
+		return
+	}
+
+	points, err := decodePoints(http.MaxBytesReader(w, r.Body, maxBatchBytes))
+	if err != nil {
+		var tooLargeErr *http.MaxBytesError
+		if errors.As(err, &tooLargeErr) {
+			render.Render(w, r, api.RequestTooLargeError(fmt.Errorf("batch exceeds %d bytes, send larger batches as %s", maxBatchBytes, ContentTypeNDJSON)))
 		} else {
-			longitude = lng
+			render.Render(w, r, api.BadRequestError(err))
 		}
+		return
 	}
 
-	states, err := h.store.GetAll()
+	states, err := h.getStates(r.Context(), asOf)
 	if err != nil {
-		render.Render(w, r, api.InternalServerError(err))
+		render.Render(w, r, api.DataStoreError(err))
 		return
 	}
 
-	coord := geospatial.LatLng(latitude, longitude)
-	locationInStates := getStateForLocation(states, coord)
-	if len(locationInStates) == 0 {
+	render.JSON(w, r, locateAll(states, points))
+}
+
+// streams the results for a batch of points given as newline delimited JSON. Errors reading the first point
+// are rendered as error responses, while errors reading any later point, after results have been written,
+// end the stream with a result holding the error
+func (h RouteHandler) streamLocationStates(w http.ResponseWriter, r *http.Request, asOf time.Time) {
+	states, err := h.getStates(r.Context(), asOf)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	stream := newPointStream(r.Body)
+	first, err := stream.next()
+	if errors.Is(err, io.EOF) {
+		err = fmt.Errorf("no points given")
+	}
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	read := false
+	next := func() (PointRequest, error) {
+		if !read {
+			read = true
+			return first, nil
+		}
+		return stream.next()
+	}
+	// results are written while the rest of the body is still being read
+	controller := http.NewResponseController(w)
+	_ = controller.EnableFullDuplex()
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	var writeErr error
+	err = streamPoints(states, next, func(result PointResult, flush bool) error {
+		if writeErr = encoder.Encode(result); writeErr == nil && flush {
+			if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				writeErr = err
+			}
+		}
+		return writeErr
+	})
+	if err != nil && writeErr == nil {
+		if encoder.Encode(PointResult{States: []string{}, Error: err.Error()}) == nil {
+			controller.Flush()
+		}
+	}
+}
+
+// walks the route through each [geospatial.State] object and orders every section of the route
+// which lies within a state by the distance along the route at which the state is entered
+func traverseStates(states []geospatial.State, route geospatial.LineString) RouteResponse {
+	traversals := []StateTraversal{}
+	for _, state := range states {
+		for _, section := range state.Traverse(route) {
+			traversals = append(traversals, StateTraversal{
+				State:    state.Name,
+				Entry:    section.Entry,
+				Exit:     section.Exit,
+				Start:    section.Start,
+				End:      section.End,
+				Distance: section.Distance(),
+			})
+		}
+	}
+
+	sort.SliceStable(traversals, func(i, j int) bool {
+		return traversals[i].Start < traversals[j].Start
+	})
+
+	return RouteResponse{Distance: route.Length(), States: traversals}
+}
+
+// HTTP Request handler for the POST /api/v1/locate/route endpoint which returns the ordered list of states
+// that the GeoJSON LineString given in the request body passes through, with the coordinates where the
+// route enters and exits each state and the distance travelled within it. The optional asOf query parameter
+// traverses the states as they were at that time
+func (h RouteHandler) TraverseRoute(w http.ResponseWriter, r *http.Request) {
+	asOf, err := api.ParseAsOf(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	route, err := decodeRoute(r.Body)
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	states, err := h.getStates(r.Context(), asOf)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	render.Render(w, r, traverseStates(states, route))
+}
+
+// clips the area against each [geospatial.State] object's border and measures the overlap, skipping
+// states whose borders are nowhere near the area
+func overlapStates(states []geospatial.State, area geospatial.MultiPolygon) OverlapResponse {
+	bounds := area.Bounds()
+	overlaps := []StateOverlap{}
+
+	for _, state := range states {
+		if !bounds.Intersects(state.Border.Bounds()) {
+			continue
+		}
+
+		overlap := state.Border.MultiPolygon().Intersection(area).Area()
+		if overlap <= 0 {
+			continue
+		}
+
+		overlaps = append(overlaps, StateOverlap{
+			State:      state.Name,
+			Area:       overlap,
+			Percentage: 100 * overlap / state.Border.Area(),
+		})
+	}
+
+	sort.SliceStable(overlaps, func(i, j int) bool {
+		return overlaps[i].Area > overlaps[j].Area
+	})
+
+	return OverlapResponse{Area: area.Area(), States: overlaps}
+}
+
+// HTTP Request handler for the POST /api/v1/locate/intersects endpoint which returns each state that
+// overlaps the GeoJSON Polygon given in the request body, with the area of the overlap and the percentage
+// of the state's area which it covers. The optional asOf query parameter measures the overlap with the
+// states as they were at that time
+func (h RouteHandler) IntersectStates(w http.ResponseWriter, r *http.Request) {
+	asOf, err := api.ParseAsOf(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	area, err := decodeArea(r.Body)
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	states, err := h.getStates(r.Context(), asOf)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	render.Render(w, r, overlapStates(states, area))
+}
+
+// HTTP Request handler for the GET /api/v1/locate/hierarchy endpoint which returns the full path through the
+// hierarchy of geographies, i.e. country, state, county and place, for each state in which the coordinate given
+// in the lat and lng query parameters is contained, or an HTTP 404 error response if it is not within any state.
+// Paths start at the state for states whose country is not known
+func (h RouteHandler) LocateHierarchy(w http.ResponseWriter, r *http.Request) {
+	coord, err := ParseLocationQuery(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	inStates, err := h.store.GetContaining(r.Context(), coord)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	if len(inStates) == 0 {
 		render.Render(w, r, api.NotFoundError(fmt.Errorf("%s not within any state", coord.String())))
 		return
 	}
 
-	render.JSON(w, r, locationInStates)
+	sort.Slice(inStates, func(i, j int) bool {
+		return inStates[i].Name < inStates[j].Name
+	})
+
+	var paths []HierarchyPath
+	for _, state := range inStates {
+		statePaths, err := h.descendHierarchy(r.Context(), HierarchyPath{{Level: geospatial.LevelState, Name: state.Name}}, coord)
+		if err != nil {
+			render.Render(w, r, api.DataStoreError(err))
+			return
+		}
+		for _, path := range statePaths {
+			if state.Country != "" {
+				path = append(HierarchyPath{{Level: geospatial.LevelCountry, Name: state.Country}}, path...)
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	render.JSON(w, r, paths)
+}
+
+// extends the path, which starts at a state, with each boundary beneath the end of the path which contains
+// the coordinate, until there are no smaller boundaries containing it. Overlapping boundaries each produce
+// their own path
+func (h RouteHandler) descendHierarchy(ctx context.Context, path HierarchyPath, coord geospatial.Coordinate) ([]HierarchyPath, error) {
+	names := make([]string, len(path))
+	for i, level := range path {
+		names[i] = level.Name
+	}
+
+	children, err := h.store.GetChildren(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []HierarchyPath
+	for _, child := range children {
+		if !child.Contains(coord) {
+			continue
+		}
+
+		extended := append(append(HierarchyPath{}, path...), HierarchyLevel{Level: child.Level, Name: child.Name})
+		childPaths, err := h.descendHierarchy(ctx, extended, coord)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, childPaths...)
+	}
+
+	if len(paths) == 0 {
+		return []HierarchyPath{path}, nil
+	}
+	return paths, nil
 }
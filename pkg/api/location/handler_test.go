@@ -1,12 +1,16 @@
 package location
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aaronireland/state-server/pkg/api"
 	"github.com/aaronireland/state-server/pkg/geospatial"
@@ -14,14 +18,37 @@ import (
 )
 
 type mockDataProvider struct {
-	Err    error
-	States []geospatial.State
+	Err      error
+	States   []geospatial.State
+	Children map[string][]geospatial.Boundary
+	Current  uint64
+	Past     []geospatial.State
 }
 
-func (m mockDataProvider) GetAll() ([]geospatial.State, error) {
+func (m mockDataProvider) GetAll(ctx context.Context) ([]geospatial.State, error) {
 	return m.States, m.Err
 }
 
+func (m mockDataProvider) GetContaining(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, error) {
+	return getStatesContaining(m.States, coord), m.Err
+}
+
+func (m mockDataProvider) GetAllAsOf(ctx context.Context, at time.Time) ([]geospatial.State, error) {
+	return m.Past, m.Err
+}
+
+func (m mockDataProvider) GetContainingAsOf(ctx context.Context, coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error) {
+	return getStatesContaining(m.Past, coord), m.Err
+}
+
+func (m mockDataProvider) GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error) {
+	return m.Children[strings.Join(path, "/")], m.Err
+}
+
+func (m mockDataProvider) GetContainingWithVersion(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error) {
+	return getStatesContaining(m.States, coord), m.Current, m.Err
+}
+
 func TestLocationHandler(t *testing.T) {
 
 	squareState, err := geospatial.NewState(
@@ -183,3 +210,767 @@ func TestLocationHandlerBadBackend(t *testing.T) {
 	assert.Equalf(t, "Internal Server Error", errResp.StatusText, "unexpected error response status: %s", errResp.StatusText)
 	assert.Equal(t, errResp.ErrorText, "uh-oh data store no good", "error response missing expected error description")
 }
+
+func TestBatchLocationHandler(t *testing.T) {
+
+	squareState, err := geospatial.NewState(
+		"square",
+		[]geospatial.Coordinate{
+			{Lng: float64(0), Lat: float64(0)},
+			{Lng: float64(10), Lat: float64(0)},
+			{Lng: float64(10), Lat: float64(10)},
+			{Lng: float64(0), Lat: float64(10)},
+			{Lng: float64(0), Lat: float64(0)},
+		},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{squareState},
+	}
+
+	handler := http.HandlerFunc(RouteHandler{testStore}.BatchLocationStates)
+
+	t.Run("should return ordered results for a json array", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		body := strings.NewReader(`[
+			{"id": "a", "latitude": 6, "longitude": 5},
+			{"id": 2, "latitude": 6, "longitude": -5},
+			{"id": "c", "latitude": 6}
+		]`)
+		req, err := http.NewRequest("POST", "/api/v1/locate/batch", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var results []PointResult
+		err = json.NewDecoder(rr.Body).Decode(&results)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, 3, len(results), "expected one result per point")
+
+		assert.Equal(t, `"a"`, string(results[0].ID), "results should be in request order")
+		assert.Equal(t, []string{"square"}, results[0].States, "point should be within the square state")
+
+		assert.Equal(t, `2`, string(results[1].ID), "caller supplied ids should be echoed back as given")
+		assert.Empty(t, results[1].States, "point should not be within any state")
+		assert.Empty(t, results[1].Error, "no match is not an error")
+
+		assert.Equal(t, `"c"`, string(results[2].ID), "results should be in request order")
+		assert.Contains(t, results[2].Error, "longitude is required", "missing coordinates should be reported per point")
+	})
+
+	t.Run("should stream ndjson results for an ndjson request", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		body := strings.NewReader("{\"id\": \"a\", \"latitude\": 6, \"longitude\": 5}\n{\"id\": \"b\", \"latitude\": 1, \"longitude\": 1}\n")
+		req, err := http.NewRequest("POST", "/api/v1/locate/batch", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", ContentTypeNDJSON)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, ContentTypeNDJSON, rr.Header().Get("Content-Type"), "response should be newline delimited json")
+
+		decoder := json.NewDecoder(rr.Body)
+		for _, id := range []string{`"a"`, `"b"`} {
+			var result PointResult
+			err := decoder.Decode(&result)
+			assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+			assert.Equal(t, id, string(result.ID), "results should be in request order")
+			assert.Equal(t, []string{"square"}, result.States, "point should be within the square state")
+		}
+		assert.False(t, decoder.More(), "expected one result per point")
+	})
+
+	t.Run("should return BadRequestError for invalid json", func(t *testing.T) {
+		for _, payload := range []string{"", "[{\"id\": 1}", "{\"id\": 1}\nceci n'est pas un json"} {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/api/v1/locate/batch", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", payload)
+		}
+	})
+
+	t.Run("should end an ndjson stream with an error result for an invalid point", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		body := strings.NewReader("{\"id\": \"a\", \"latitude\": 6, \"longitude\": 5}\nceci n'est pas un json\n")
+		req, err := http.NewRequest("POST", "/api/v1/locate/batch", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", ContentTypeNDJSON)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "results already streamed should be kept")
+
+		decoder := json.NewDecoder(rr.Body)
+		var result PointResult
+		assert.Nil(t, decoder.Decode(&result), "should be able to decode the first result")
+		assert.Equal(t, `"a"`, string(result.ID), "points before the invalid point should be located")
+		assert.Nil(t, decoder.Decode(&result), "should be able to decode the error result")
+		assert.Contains(t, result.Error, "invalid json at point 1", "the stream should end with the error for the invalid point")
+		assert.False(t, decoder.More(), "no results should follow the error")
+	})
+
+	t.Run("should return BadRequestError for an ndjson stream with no valid first point", func(t *testing.T) {
+		for _, payload := range []string{"", "ceci n'est pas un json"} {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/api/v1/locate/batch", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			req.Header.Set("Content-Type", ContentTypeNDJSON)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", payload)
+		}
+	})
+
+	t.Run("should write each ndjson result before the rest of the stream is read", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		body, points := io.Pipe()
+		req, err := http.NewRequest("POST", server.URL, body)
+		assert.Nil(t, err, "should generate valid http request")
+		req.Header.Set("Content-Type", ContentTypeNDJSON)
+
+		go fmt.Fprintln(points, `{"id": "a", "latitude": 6, "longitude": 5}`)
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err, "request should be sent")
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		var result PointResult
+		assert.Nil(t, decoder.Decode(&result), "the first result should arrive while the stream is still open")
+		assert.Equal(t, `"a"`, string(result.ID))
+
+		fmt.Fprintln(points, `{"id": "b", "latitude": 6, "longitude": -5}`)
+		points.Close()
+		assert.Nil(t, decoder.Decode(&result), "the second result should follow")
+		assert.Equal(t, `"b"`, string(result.ID))
+		assert.False(t, decoder.More(), "expected one result per point")
+	})
+
+	t.Run("should return RequestTooLargeError for a json array over the limit", func(t *testing.T) {
+		limit := maxBatchBytes
+		maxBatchBytes = 64
+		defer func() { maxBatchBytes = limit }()
+
+		rr := httptest.NewRecorder()
+		body := strings.NewReader(`[` + strings.Repeat(`{"latitude": 1, "longitude": 1},`, 10) + `{"latitude": 1, "longitude": 1}]`)
+		req, err := http.NewRequest("POST", "/api/v1/locate/batch", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, "request should respond with 413 Request Entity Too Large")
+	})
+
+	t.Run("should return InternalServerError for backend error", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{Err: fmt.Errorf("uh-oh data store no good")}}.BatchLocationStates)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/api/v1/locate/batch", strings.NewReader(`[{"latitude": 1, "longitude": 1}]`))
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
+
+func TestLocateAll(t *testing.T) {
+	squareState, err := geospatial.NewState(
+		"square",
+		[]geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	var points []PointRequest
+	for i := 0; i < 1000; i++ {
+		lat, lng := float64(i%20)-4.5, float64(5)
+		points = append(points, PointRequest{ID: json.RawMessage(fmt.Sprint(i)), Latitude: &lat, Longitude: &lng})
+	}
+
+	results := locateAll([]geospatial.State{squareState}, points)
+	assert.Equal(t, len(points), len(results), "expected one result per point")
+	for i, result := range results {
+		assert.Equal(t, fmt.Sprint(i), string(result.ID), "results should be in the same order as the points")
+		lat := *points[i].Latitude
+		assert.Equalf(t, lat > 0 && lat < 10, len(result.States) == 1, "unexpected result for latitude %v", lat)
+	}
+}
+
+func TestStreamPoints(t *testing.T) {
+	squareState, err := geospatial.NewState(
+		"square",
+		[]geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	count := int64(10 * streamWindow)
+	var read atomic.Int64
+	next := func() (PointRequest, error) {
+		i := read.Load()
+		if i == count {
+			return PointRequest{}, io.EOF
+		}
+		lat, lng := float64(i%20)-4.5, float64(5)
+		point := PointRequest{ID: json.RawMessage(fmt.Sprint(i)), Latitude: &lat, Longitude: &lng}
+		read.Add(1)
+		return point, nil
+	}
+
+	var results []PointResult
+	err = streamPoints([]geospatial.State{squareState}, next, func(result PointResult, flush bool) error {
+		// the result being written and the point waiting for room in the window are both outside of it
+		assert.LessOrEqualf(t, read.Load()-int64(len(results)), int64(streamWindow+2), "no more than %d points should be waiting to be written", streamWindow)
+		results = append(results, result)
+		return nil
+	})
+	assert.Nil(t, err, "streamPoints should not produce an error")
+	assert.Equal(t, int(count), len(results), "expected one result per point")
+	for i, result := range results {
+		assert.Equal(t, fmt.Sprint(i), string(result.ID), "results should be in the same order as the points")
+	}
+
+	stop := fmt.Errorf("client went away")
+	read.Store(0)
+	written := 0
+	err = streamPoints([]geospatial.State{squareState}, next, func(result PointResult, flush bool) error {
+		written++
+		return stop
+	})
+	assert.Equal(t, stop, err, "streamPoints should stop at the first error from emit")
+	assert.Equal(t, 1, written, "no results should be written after an error")
+}
+
+func TestLocationHandlerContentTypes(t *testing.T) {
+
+	squareState, err := geospatial.NewState(
+		"square",
+		[]geospatial.Coordinate{
+			{Lng: float64(0), Lat: float64(0)},
+			{Lng: float64(10), Lat: float64(0)},
+			{Lng: float64(10), Lat: float64(10)},
+			{Lng: float64(0), Lat: float64(10)},
+			{Lng: float64(0), Lat: float64(0)},
+		},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{squareState},
+	}
+
+	handler := http.HandlerFunc(RouteHandler{testStore}.CheckLocationStates)
+
+	t.Run("should accept json and GeoJSON bodies", func(t *testing.T) {
+		payloads := map[string]string{
+			"application/json":                `{"latitude": 6, "longitude": 5}`,
+			"application/json; charset=utf-8": `{"type": "Point", "coordinates": [5, 6]}`,
+			"application/geo+json":            `{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [5, 6]}}`,
+		}
+
+		for contentType, payload := range payloads {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			req.Header.Set("Content-Type", contentType)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusOK, rr.Code, "request should respond with 200 OK: %s", payload)
+
+			var matches []string
+			err = json.NewDecoder(rr.Body).Decode(&matches)
+			assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+			assert.Equalf(t, []string{"square"}, matches, "location should be within the square state: %s", payload)
+		}
+	})
+
+	t.Run("should return BadRequestError for missing or unsupported coordinates", func(t *testing.T) {
+		payloads := map[string][]string{
+			"application/x-www-form-urlencoded": {"latitude=6", "longitude=5", ""},
+			"application/json": {
+				`{"latitude": 6}`,
+				`{"longitude": 5}`,
+				`{"type": "Point"}`,
+				`{"type": "Feature", "properties": {}}`,
+				`{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[5, 6], [6, 7]]}}`,
+				`{"type": "MultiPoint", "coordinates": [[5, 6]]}`,
+				`{"type": "Point", "coordinates": [5]}`,
+				`ceci n'est pas un json`,
+			},
+		}
+
+		for contentType, bodies := range payloads {
+			for _, payload := range bodies {
+				rr := httptest.NewRecorder()
+				req, err := http.NewRequest("POST", "/", strings.NewReader(payload))
+				assert.Nil(t, err, "should generate valid http request")
+
+				req.Header.Set("Content-Type", contentType)
+				handler.ServeHTTP(rr, req)
+
+				assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", payload)
+
+				var errResp api.ErrorResponse
+				err = json.NewDecoder(rr.Body).Decode(&errResp)
+				assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+				assert.Equalf(t, "Bad Request", errResp.StatusText, "unexpected error response status: %s", errResp.StatusText)
+			}
+		}
+	})
+
+	t.Run("should render GeoJSON FeatureCollection when requested", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/", strings.NewReader("longitude=5&latitude=6"))
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "text/html, application/geo+json;q=0.9")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, api.ContentTypeGeoJSON, rr.Header().Get("Content-Type"), "response should be GeoJSON")
+
+		var collection api.FeatureCollection
+		err = json.NewDecoder(rr.Body).Decode(&collection)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, "FeatureCollection", collection.Type, "response should be a valid RFC 7946 JSON type")
+		assert.Equal(t, 1, len(collection.Features), "response should contain the matching state")
+		assert.Equal(t, "square", collection.Features[0].Properties.State, "response should contain the matching state")
+	})
+}
+
+func TestLocateStatesHandler(t *testing.T) {
+
+	squareState, err := geospatial.NewState(
+		"square",
+		[]geospatial.Coordinate{
+			{Lng: float64(0), Lat: float64(0)},
+			{Lng: float64(10), Lat: float64(0)},
+			{Lng: float64(10), Lat: float64(10)},
+			{Lng: float64(0), Lat: float64(10)},
+			{Lng: float64(0), Lat: float64(0)},
+		},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	testStore := mockDataProvider{
+		States:  []geospatial.State{squareState},
+		Current: 7,
+	}
+
+	handler := http.HandlerFunc(RouteHandler{testStore}.LocateStates)
+
+	t.Run("should return cacheable json for match", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, `"`+epoch+`-7"`, rr.Header().Get("ETag"), "ETag should be derived from the data store version")
+		assert.Contains(t, rr.Header().Get("Cache-Control"), "max-age", "response should be cacheable")
+
+		var matches []string
+		err = json.NewDecoder(rr.Body).Decode(&matches)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, []string{"square"}, matches, "location should be within the square state")
+	})
+
+	t.Run("should return 304 Not Modified for matching ETag", func(t *testing.T) {
+		for _, ifNoneMatch := range []string{`"` + epoch + `-7"`, `W/"` + epoch + `-7"`, `"` + epoch + `-3", "` + epoch + `-7"`, "*"} {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5", nil)
+			assert.Nil(t, err, "should generate valid http request")
+
+			req.Header.Set("If-None-Match", ifNoneMatch)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusNotModified, rr.Code, "request should respond with 304 Not Modified: %s", ifNoneMatch)
+			assert.Empty(t, rr.Body.String(), "304 response should have no body")
+		}
+	})
+
+	t.Run("should return full response for stale ETag", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{States: testStore.States, Current: 8}}.LocateStates)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("If-None-Match", `"`+epoch+`-7"`)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, `"`+epoch+`-8"`, rr.Header().Get("ETag"), "ETag should change with the data store version")
+	})
+
+	t.Run("should tag GeoJSON representation separately", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Accept", api.ContentTypeGeoJSON)
+		req.Header.Set("If-None-Match", `"`+epoch+`-7"`)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, `"`+epoch+`-7-geojson"`, rr.Header().Get("ETag"), "GeoJSON representation should have its own ETag")
+		assert.Equal(t, api.ContentTypeGeoJSON, rr.Header().Get("Content-Type"), "response should be GeoJSON")
+	})
+
+	t.Run("should return full response for the same version from an earlier run of the server", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("If-None-Match", `"7"`)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "ETag without the epoch of this run should not match")
+	})
+
+	t.Run("should return cacheable 404 for no match", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=-5", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+		assert.Equal(t, `"`+epoch+`-7"`, rr.Header().Get("ETag"), "ETag should be derived from the data store version")
+	})
+
+	t.Run("should return BadRequestError for missing or invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"", "lat=6", "lng=5", "lat=oops&lng=5", "lat=6&lng=oops", "latitude=6&longitude=5"} {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/api/v1/locate?"+query, nil)
+			assert.Nil(t, err, "should generate valid http request")
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", query)
+		}
+	})
+
+	t.Run("should locate the coordinate in the states as they were at the asOf time", func(t *testing.T) {
+		moved, err := geospatial.NewState("moved", []geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: -10, Lat: 0}, {Lng: -10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}})
+		assert.Nil(t, err, "given coordinates should produce a valid state")
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{States: testStore.States, Current: 7, Past: []geospatial.State{moved}}}.LocateStates)
+
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=-5&asOf=2024-01-02T15:04:05Z", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("If-None-Match", `"7"`)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Empty(t, rr.Header().Get("ETag"), "past states should not be tagged with the current version")
+
+		var matches []string
+		err = json.NewDecoder(rr.Body).Decode(&matches)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, []string{"moved"}, matches, "location should be within the state as it was at the asOf time")
+
+		rr = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5&asOf=yesterday", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request with an invalid asOf should respond with 400 Bad Request")
+	})
+
+	t.Run("should return uncached InternalServerError for backend error", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{Err: fmt.Errorf("uh-oh data store no good")}}.LocateStates)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/locate?lat=6&lng=5", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+		assert.Empty(t, rr.Header().Get("ETag"), "error responses should not be cached")
+	})
+}
+
+func TestTraverseRouteHandler(t *testing.T) {
+
+	westState, err := geospatial.NewState(
+		"west",
+		[]geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	eastState, err := geospatial.NewState(
+		"east",
+		[]geospatial.Coordinate{{Lng: 10, Lat: 0}, {Lng: 20, Lat: 0}, {Lng: 20, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{eastState, westState},
+	}
+
+	handler := http.HandlerFunc(RouteHandler{testStore}.TraverseRoute)
+
+	t.Run("should return states in the order they are entered", func(t *testing.T) {
+		payloads := []string{
+			`{"type": "LineString", "coordinates": [[5, 5], [15, 5], [25, 5]]}`,
+			`{"type": "Feature", "properties": {}, "geometry": {"type": "LineString", "coordinates": [[5, 5], [15, 5], [25, 5]]}}`,
+		}
+
+		for _, payload := range payloads {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/api/v1/locate/route", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			req.Header.Set("Content-Type", api.ContentTypeGeoJSON)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+			var route RouteResponse
+			err = json.NewDecoder(rr.Body).Decode(&route)
+			assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+			assert.Equal(t, 2, len(route.States), "route should pass through both states")
+			assert.Equal(t, "west", route.States[0].State, "route should enter the west state first")
+			assert.Equal(t, "east", route.States[1].State, "route should enter the east state second")
+			assert.Equal(t, geospatial.Coordinate{Lng: 5, Lat: 5}, route.States[0].Entry, "route should start within the west state")
+			assert.InDelta(t, 10, route.States[0].Exit.Lng, 1e-9, "route should leave the west state at the shared border")
+			assert.InDelta(t, 10, route.States[1].Entry.Lng, 1e-9, "route should enter the east state at the shared border")
+			assert.InDelta(t, 20, route.States[1].Exit.Lng, 1e-9, "route should leave the east state at its eastern border")
+			assert.InDelta(t, route.States[0].End, route.States[1].Start, 1e-9, "route should enter the east state where it leaves the west state")
+			assert.Greater(t, route.Distance, route.States[0].Distance+route.States[1].Distance, "part of the route lies outside any state")
+		}
+	})
+
+	t.Run("should return an empty list for a route outside every state", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/api/v1/locate/route", strings.NewReader(`{"type": "LineString", "coordinates": [[-5, -5], [-15, -5]]}`))
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var route RouteResponse
+		err = json.NewDecoder(rr.Body).Decode(&route)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.NotNil(t, route.States, "states should be an empty list")
+		assert.Empty(t, route.States, "route should not pass through any state")
+	})
+
+	t.Run("should return BadRequestError for invalid LineString", func(t *testing.T) {
+		payloads := []string{
+			`{"type": "LineString", "coordinates": [[5, 5]]}`,
+			`{"type": "LineString"}`,
+			`{"type": "Point", "coordinates": [5, 5]}`,
+			`{"type": "Feature", "geometry": {"type": "Point", "coordinates": [5, 5]}}`,
+			`ceci n'est pas un json`,
+		}
+
+		for _, payload := range payloads {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/api/v1/locate/route", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", payload)
+		}
+	})
+
+	t.Run("should return InternalServerError for backend error", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{Err: fmt.Errorf("uh-oh data store no good")}}.TraverseRoute)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/api/v1/locate/route", strings.NewReader(`{"type": "LineString", "coordinates": [[5, 5], [15, 5]]}`))
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
+
+func TestIntersectStatesHandler(t *testing.T) {
+
+	westState, err := geospatial.NewState(
+		"west",
+		[]geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	eastState, err := geospatial.NewState(
+		"east",
+		[]geospatial.Coordinate{{Lng: 10, Lat: 0}, {Lng: 20, Lat: 0}, {Lng: 20, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	farState, err := geospatial.NewState(
+		"far",
+		[]geospatial.Coordinate{{Lng: 50, Lat: 50}, {Lng: 60, Lat: 50}, {Lng: 60, Lat: 60}, {Lng: 50, Lat: 60}, {Lng: 50, Lat: 50}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{eastState, westState, farState},
+	}
+
+	handler := http.HandlerFunc(RouteHandler{testStore}.IntersectStates)
+
+	t.Run("should return overlapping states with the largest overlap first", func(t *testing.T) {
+		payloads := []string{
+			`{"type": "Polygon", "coordinates": [[[8, 2], [8, 8], [20, 8], [20, 2], [8, 2]]]}`,
+			`{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[8, 2], [20, 2], [20, 8], [8, 8], [8, 2]]]}}`,
+		}
+
+		for _, payload := range payloads {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/api/v1/locate/intersects", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			req.Header.Set("Content-Type", api.ContentTypeGeoJSON)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+			var overlaps OverlapResponse
+			err = json.NewDecoder(rr.Body).Decode(&overlaps)
+			assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+			assert.Equal(t, 2, len(overlaps.States), "polygon should overlap both adjacent states")
+			assert.Equal(t, "east", overlaps.States[0].State, "largest overlap should be first")
+			assert.Equal(t, "west", overlaps.States[1].State, "smallest overlap should be last")
+			assert.InDelta(t, 60, overlaps.States[0].Percentage, 1, "polygon covers about 60 percent of the east state")
+			assert.InDelta(t, 12, overlaps.States[1].Percentage, 1, "polygon covers about 12 percent of the west state")
+			assert.InEpsilon(t, overlaps.Area, overlaps.States[0].Area+overlaps.States[1].Area, 0.01, "polygon lies entirely within the two states")
+		}
+	})
+
+	t.Run("should subtract polygon holes from the overlap", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		payload := `{"type": "Polygon", "coordinates": [[[0, 0], [0, 10], [10, 10], [10, 0], [0, 0]], [[2, 2], [8, 2], [8, 8], [2, 8], [2, 2]]]}`
+		req, err := http.NewRequest("POST", "/api/v1/locate/intersects", strings.NewReader(payload))
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var overlaps OverlapResponse
+		err = json.NewDecoder(rr.Body).Decode(&overlaps)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, 1, len(overlaps.States), "polygon should only overlap the west state")
+		assert.InDelta(t, 64, overlaps.States[0].Percentage, 1, "the hole leaves about 64 percent of the west state covered")
+	})
+
+	t.Run("should return BadRequestError for invalid Polygon", func(t *testing.T) {
+		payloads := []string{
+			`{"type": "Polygon", "coordinates": []}`,
+			`{"type": "Polygon", "coordinates": [[[0, 0], [1, 1], [0, 1]]]}`,
+			`{"type": "LineString", "coordinates": [[5, 5], [6, 6]]}`,
+			`ceci n'est pas un json`,
+		}
+
+		for _, payload := range payloads {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/api/v1/locate/intersects", strings.NewReader(payload))
+			assert.Nil(t, err, "should generate valid http request")
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", payload)
+		}
+	})
+
+	t.Run("should return InternalServerError for backend error", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{Err: fmt.Errorf("uh-oh data store no good")}}.IntersectStates)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/api/v1/locate/intersects", strings.NewReader(`{"type": "Polygon", "coordinates": [[[0, 0], [0, 1], [1, 1], [0, 0]]]}`))
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
+
+func TestLocateHierarchyHandler(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	state, err := geospatial.NewState("Square", square(0, 0, 10))
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+	north, err := geospatial.NewBoundary("North", geospatial.LevelCounty, square(0, 5, 5))
+	assert.Nil(t, err, "given coordinates should produce a valid boundary")
+	town, err := geospatial.NewBoundary("Town", geospatial.LevelPlace, square(1, 6, 1))
+	assert.Nil(t, err, "given coordinates should produce a valid boundary")
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{state},
+		Children: map[string][]geospatial.Boundary{
+			"Square":       {north},
+			"Square/North": {town},
+		},
+	}
+	handler := http.HandlerFunc(RouteHandler{testStore}.LocateHierarchy)
+
+	t.Run("should return the full hierarchy path for the location", func(t *testing.T) {
+		for query, expected := range map[string][]HierarchyPath{
+			"lat=6.5&lng=1.5": {{{geospatial.LevelState, "Square"}, {geospatial.LevelCounty, "North"}, {geospatial.LevelPlace, "Town"}}},
+			"lat=6.5&lng=4.5": {{{geospatial.LevelState, "Square"}, {geospatial.LevelCounty, "North"}}},
+			"lat=2.5&lng=4.5": {{{geospatial.LevelState, "Square"}}},
+		} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/hierarchy?"+query, nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+			var paths []HierarchyPath
+			assert.Nil(t, json.NewDecoder(rr.Body).Decode(&paths), "should be able to decode response json")
+			assert.Equalf(t, expected, paths, "unexpected hierarchy for %s", query)
+		}
+	})
+
+	t.Run("should start the hierarchy path at the country of the state", func(t *testing.T) {
+		inCountry := state
+		inCountry.Country = "Squareland"
+		store := mockDataProvider{States: []geospatial.State{inCountry}, Children: testStore.Children}
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(RouteHandler{store}.LocateHierarchy).ServeHTTP(rr, httptest.NewRequest("GET", "/hierarchy?lat=6.5&lng=4.5", nil))
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var paths []HierarchyPath
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&paths), "should be able to decode response json")
+		assert.Equal(t, []HierarchyPath{{{geospatial.LevelCountry, "Squareland"}, {geospatial.LevelState, "Square"}, {geospatial.LevelCounty, "North"}}}, paths, "states are beneath their country")
+	})
+
+	t.Run("should respond with 404 outside every state and 400 for an invalid location", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/hierarchy?lat=50&lng=50", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/hierarchy?lng=50", nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+	})
+
+	t.Run("should respond with 500 for backend error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		store := mockDataProvider{States: []geospatial.State{state}, Err: fmt.Errorf("test internal server error")}
+		http.HandlerFunc(RouteHandler{store}.LocateHierarchy).ServeHTTP(rr, httptest.NewRequest("GET", "/hierarchy?lat=6.5&lng=1.5", nil))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
@@ -4,6 +4,9 @@
 package location
 
 import (
+	"context"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 
 	"github.com/aaronireland/state-server/pkg/geospatial"
@@ -12,7 +15,12 @@ import (
 // Injects the dependcies required by the handler for the
 // backend data store
 type DataProvider interface {
-	GetAll() ([]geospatial.State, error)
+	GetAll(ctx context.Context) ([]geospatial.State, error)
+	GetContaining(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, error)
+	GetAllAsOf(ctx context.Context, at time.Time) ([]geospatial.State, error)
+	GetContainingAsOf(ctx context.Context, coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error)
+	GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error)
+	GetContainingWithVersion(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error)
 }
 
 // Maps the handler to required REST API endpoint
@@ -24,3 +32,17 @@ func Router(store DataProvider) chi.Router {
 
 	return router
 }
+
+// Maps the handlers to the REST API endpoints for the location API
+func LocateRouter(store DataProvider) chi.Router {
+	router := chi.NewRouter()
+	handler := RouteHandler{store}
+
+	router.Get("/", handler.LocateStates)
+	router.Get("/hierarchy", handler.LocateHierarchy)
+	router.Post("/batch", handler.BatchLocationStates)
+	router.Post("/route", handler.TraverseRoute)
+	router.Post("/intersects", handler.IntersectStates)
+
+	return router
+}
@@ -51,4 +51,26 @@ func TestStatesRouter(t *testing.T) {
 		assert.Nil(t, err, "router should reject GETfor basepath")
 		assert.Equal(t, http.StatusOK, resp.StatusCode, "router should give a 200 response")
 	})
+
+	t.Run("batch POST request is valid", func(t *testing.T) {
+		testServer := httptest.NewServer(LocateRouter(testStore))
+		defer testServer.Close()
+
+		req, err := http.NewRequest("POST", testServer.URL+"/batch", strings.NewReader(`[{"latitude": 2, "longitude": 3}]`))
+		assert.Nil(t, err, "should be a valid request")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err, "router should handle POST for batch path")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "router should give a 200 response")
+	})
+
+	t.Run("locate GET request is valid", func(t *testing.T) {
+		testServer := httptest.NewServer(LocateRouter(testStore))
+		defer testServer.Close()
+
+		req, err := http.NewRequest("GET", testServer.URL+"/?lat=2&lng=3", nil)
+		assert.Nil(t, err, "should be a valid request")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err, "router should handle GET for locate path")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "router should give a 200 response")
+	})
 }
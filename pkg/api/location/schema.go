@@ -0,0 +1,298 @@
+package location
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// A single geographic point submitted to the batch location endpoint. The ID is
+// supplied by the caller and echoed back as-is in the matching [PointResult]
+type PointRequest struct {
+	ID        json.RawMessage `json:"id,omitempty"`
+	Latitude  *float64        `json:"latitude"`
+	Longitude *float64        `json:"longitude"`
+}
+
+// Validates that both coordinates were given and translates the point
+// into a [geospatial.Coordinate]
+func (p PointRequest) Coordinate() (geospatial.Coordinate, error) {
+	if p.Latitude == nil {
+		return geospatial.Coordinate{}, fmt.Errorf("latitude is required")
+	}
+	if p.Longitude == nil {
+		return geospatial.Coordinate{}, fmt.Errorf("longitude is required")
+	}
+	return geospatial.LatLng(*p.Latitude, *p.Longitude), nil
+}
+
+// The JSON body of a location request which is either a latitude and longitude object, a
+// GeoJSON Point geometry or a GeoJSON Feature with a Point geometry
+type LocationRequest struct {
+	PointRequest
+	geometryRequest[geospatial.Coordinate]
+}
+
+// Validates the request and translates the location into a [geospatial.Coordinate]
+func (l LocationRequest) Coordinate() (geospatial.Coordinate, error) {
+	if l.Type == "" {
+		return l.PointRequest.Coordinate()
+	}
+	return l.coordinates("Point")
+}
+
+// The JSON body of a request for a single GeoJSON geometry, given either as a bare geometry
+// object or as a GeoJSON Feature containing the geometry
+type geometryRequest[T any] struct {
+	Type        string              `json:"type"`
+	Geometry    *geometryRequest[T] `json:"geometry"`
+	Coordinates *T                  `json:"coordinates"`
+}
+
+// Validates that the request contains the expected type of geometry and returns its coordinates
+func (g geometryRequest[T]) coordinates(geometryType string) (coords T, err error) {
+	switch g.Type {
+	case "Feature":
+		if g.Geometry == nil {
+			return coords, fmt.Errorf("feature geometry is required")
+		}
+		if g.Geometry.Type != geometryType {
+			return coords, fmt.Errorf("unsupported feature geometry: %q, expecting %s", g.Geometry.Type, geometryType)
+		}
+		return g.Geometry.coordinates(geometryType)
+	case geometryType:
+		if g.Coordinates == nil {
+			return coords, fmt.Errorf("%s coordinates are required", geometryType)
+		}
+		return *g.Coordinates, nil
+	default:
+		return coords, fmt.Errorf("unsupported GeoJSON type: %q, expecting Feature or %s", g.Type, geometryType)
+	}
+}
+
+// Reads a GeoJSON LineString geometry or Feature from the request body
+func decodeRoute(body io.Reader) (geospatial.LineString, error) {
+	var route geometryRequest[geospatial.LineString]
+	if err := json.NewDecoder(body).Decode(&route); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	return route.coordinates("LineString")
+}
+
+// Reads a GeoJSON Polygon geometry or Feature from the request body. The first ring
+// of the polygon is its exterior boundary and any other rings are holes
+func decodeArea(body io.Reader) (geospatial.MultiPolygon, error) {
+	var area geometryRequest[[]geospatial.Polygon]
+	if err := json.NewDecoder(body).Decode(&area); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	rings, err := area.coordinates("Polygon")
+	if err != nil {
+		return nil, err
+	}
+	if len(rings) == 0 {
+		return nil, fmt.Errorf("polygon must contain at least one ring")
+	}
+	return geospatial.MultiPolygon{rings}, nil
+}
+
+// Reads the location from the request body based on its content type. JSON bodies are decoded
+// as a [LocationRequest], anything else is parsed as latitude and longitude form fields
+func decodeLocation(r *http.Request) (geospatial.Coordinate, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return geospatial.Coordinate{}, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json", api.ContentTypeGeoJSON:
+		var location LocationRequest
+		if err := json.Unmarshal(body, &location); err != nil {
+			return geospatial.Coordinate{}, fmt.Errorf("invalid json: %w", err)
+		}
+		return location.Coordinate()
+	default:
+		return parseLocationForm(string(body))
+	}
+}
+
+// Parses the latitude and longitude form fields of a URL encoded request body
+func parseLocationForm(body string) (geospatial.Coordinate, error) {
+	params, err := url.ParseQuery(body)
+	if err != nil {
+		return geospatial.Coordinate{}, err
+	}
+
+	return parseCoordinateParams(params, "latitude", "longitude")
+}
+
+// Parses the lat and lng query parameters of a GET request URL into a [geospatial.Coordinate]
+func ParseLocationQuery(params url.Values) (geospatial.Coordinate, error) {
+	return parseCoordinateParams(params, "lat", "lng")
+}
+
+func parseCoordinateParams(params url.Values, latKey, lngKey string) (geospatial.Coordinate, error) {
+	var point PointRequest
+	if val, ok := params[latKey]; ok {
+		lat, err := strconv.ParseFloat(val[0], 64)
+		if err != nil {
+			return geospatial.Coordinate{}, fmt.Errorf("invalid latitude: %v", val[0])
+		}
+		point.Latitude = &lat
+	}
+
+	if val, ok := params[lngKey]; ok {
+		lng, err := strconv.ParseFloat(val[0], 64)
+		if err != nil {
+			return geospatial.Coordinate{}, fmt.Errorf("invalid longitude: %v", val[0])
+		}
+		point.Longitude = &lng
+	}
+
+	return point.Coordinate()
+}
+
+// The state(s) in which a single point of a batch request is contained
+type PointResult struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	States []string        `json:"states"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// The section of a route which lies within a single state. Start and End are the distances in
+// kilometers along the route at which the route enters and exits the state
+type StateTraversal struct {
+	State    string                `json:"state"`
+	Entry    geospatial.Coordinate `json:"entry"`
+	Exit     geospatial.Coordinate `json:"exit"`
+	Start    float64               `json:"start"`
+	End      float64               `json:"end"`
+	Distance float64               `json:"distance"`
+}
+
+// The ordered list of states which a route passes through along with the total
+// distance of the route in kilometers
+type RouteResponse struct {
+	Distance float64          `json:"distance"`
+	States   []StateTraversal `json:"states"`
+}
+
+// render method which hooks into the go-chi renderer
+func (rr RouteResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// The area shared by a state and the polygon given in a request, in square kilometers,
+// and the percentage of the state's total area which that represents
+type StateOverlap struct {
+	State      string  `json:"state"`
+	Area       float64 `json:"area"`
+	Percentage float64 `json:"percentage"`
+}
+
+// The states which overlap the polygon given in a request, largest overlap first,
+// along with the total area of the polygon in square kilometers
+type OverlapResponse struct {
+	Area   float64        `json:"area"`
+	States []StateOverlap `json:"states"`
+}
+
+// render method which hooks into the go-chi renderer
+func (or OverlapResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// A single level of the hierarchy of geographies in which a location is contained
+type HierarchyLevel struct {
+	Level geospatial.Level `json:"level"`
+	Name  string           `json:"name"`
+}
+
+// The geographies in which a location is contained, from the country down to the smallest boundary
+type HierarchyPath []HierarchyLevel
+
+// Decodes the points of a batch request which are given either as a JSON
+// array or as a stream of newline delimited JSON objects
+func decodePoints(body io.Reader) ([]PointRequest, error) {
+	reader := bufio.NewReader(body)
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("no points given")
+		}
+		return nil, err
+	}
+
+	var points []PointRequest
+	if first == '[' {
+		if err := json.NewDecoder(reader).Decode(&points); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return points, nil
+	}
+
+	stream := newPointStream(reader)
+	for {
+		point, err := stream.next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// Reads the points of a batch request given as newline delimited JSON one at a time, so that
+// the points can be located as they arrive rather than after the whole request has been read
+type pointStream struct {
+	decoder *json.Decoder
+	count   int
+}
+
+func newPointStream(body io.Reader) *pointStream {
+	return &pointStream{decoder: json.NewDecoder(body)}
+}
+
+// Decodes the next point in the stream. Returns [io.EOF] once every point has been read
+func (s *pointStream) next() (PointRequest, error) {
+	var point PointRequest
+	if err := s.decoder.Decode(&point); errors.Is(err, io.EOF) {
+		return point, io.EOF
+	} else if err != nil {
+		return point, fmt.Errorf("invalid json at point %d: %w", s.count, err)
+	}
+	s.count++
+	return point, nil
+}
+
+// Returns the first non-whitespace byte in the reader without consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = reader.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package regions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/api/location"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+type RouteHandler struct {
+	store DataProvider
+}
+
+// HTTP request handler for the /api/v1/region/{name} endpoint
+func (h RouteHandler) GetRegion(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	region, err := h.store.GetRegion(r.Context(), name)
+	if err != nil {
+		renderRegionError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, NewRegionResponse(region))
+}
+
+// HTTP request handler for the POST /api/v1/region endpoint creates the [geospatial.Region] object
+// from the given member states and adds it to the data store
+func (h RouteHandler) CreateRegion(w http.ResponseWriter, r *http.Request) {
+	region := &CreateRegionRequest{}
+	if err := render.Bind(r, region); err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	created, err := h.store.CreateRegion(r.Context(), geospatial.Region(*region))
+	if err != nil {
+		renderRegionError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.Render(w, r, NewRegionResponse(created))
+}
+
+// HTTP request handler for the DELETE /api/v1/region/{name} endpoint removes a given region from
+// the data store. Its member states are left as they are
+func (h RouteHandler) DeleteRegion(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.store.DeleteRegion(r.Context(), name); err != nil {
+		renderRegionError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HTTP request handler for the GET /api/v1/region endpoint renders the entire list of regions
+// in the data store to a GeoJSON feature collection
+func (h RouteHandler) ListRegions(w http.ResponseWriter, r *http.Request) {
+	regions, err := h.store.GetAllRegions(r.Context())
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	var features []Feature
+	for _, region := range regions {
+		features = append(features, NewRegionResponse(region))
+	}
+
+	render.Render(w, r, NewRegionCollectionResponse(features))
+}
+
+// HTTP request handler for the GET /api/v1/region/locate endpoint finds the region(s) in which the
+// location given by the lat and lng query parameters is contained. Renders the names of the regions,
+// or a GeoJSON FeatureCollection of the regions if the client accepts GeoJSON
+func (h RouteHandler) LocateRegions(w http.ResponseWriter, r *http.Request) {
+	coord, err := location.ParseLocationQuery(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	regions, err := h.store.GetAllRegions(r.Context())
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	var features []Feature
+	names := []string{}
+	for _, region := range regions {
+		if region.Contains(coord) {
+			features = append(features, NewRegionResponse(region))
+			names = append(names, region.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		render.Render(w, r, api.NotFoundError(fmt.Errorf("%s not within any region", coord.String())))
+		return
+	}
+
+	if api.AcceptsGeoJSON(r) {
+		api.GeoJSON(w, r, NewRegionCollectionResponse(features))
+		return
+	}
+	render.JSON(w, r, names)
+}
+
+// maps the errors returned by the data store for a region to error responses
+func renderRegionError(w http.ResponseWriter, r *http.Request, err error) {
+	var notFoundErr *backend.RegionNotFoundError
+	var invalidRegionErr *backend.InvalidRegionError
+	var conflictErr *backend.ConflictError
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		render.Render(w, r, api.NotFoundError(err))
+	case errors.As(err, &invalidRegionErr):
+		render.Render(w, r, api.BadRequestError(err))
+	case errors.As(err, &conflictErr):
+		render.Render(w, r, api.ConflictError(err))
+	default:
+		render.Render(w, r, api.DataStoreError(err))
+	}
+}
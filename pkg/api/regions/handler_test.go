@@ -0,0 +1,207 @@
+package regions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockDataProvider struct {
+	Err     error
+	Regions []geospatial.Region
+}
+
+func (m mockDataProvider) GetAllRegions(ctx context.Context) ([]geospatial.Region, error) {
+	return m.Regions, m.Err
+}
+
+func (m mockDataProvider) GetRegion(ctx context.Context, name string) (geospatial.Region, error) {
+	if m.Err != nil {
+		return geospatial.Region{}, m.Err
+	}
+	for _, region := range m.Regions {
+		if strings.EqualFold(region.Name, name) {
+			return region, nil
+		}
+	}
+	return geospatial.Region{}, &backend.RegionNotFoundError{Name: name}
+}
+
+func (m mockDataProvider) CreateRegion(ctx context.Context, region geospatial.Region) (geospatial.Region, error) {
+	if m.Err != nil {
+		return geospatial.Region{}, m.Err
+	}
+	return region, nil
+}
+
+func (m mockDataProvider) DeleteRegion(ctx context.Context, name string) error {
+	return m.Err
+}
+
+func squareRegion(name string, lng, lat float64) geospatial.Region {
+	return geospatial.NewRegion(name, []geospatial.State{{Name: name + " State", Border: geospatial.Polygon{
+		{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + 10}, {Lng: lng + 10, Lat: lat + 10}, {Lng: lng + 10, Lat: lat}, {Lng: lng, Lat: lat},
+	}}})
+}
+
+func withName(req *http.Request, name string) *http.Request {
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("name", name)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func TestRegionHandlers(t *testing.T) {
+	testStore := mockDataProvider{Regions: []geospatial.Region{squareRegion("South", 0, 0), squareRegion("Big", -5, -5)}}
+	handler := RouteHandler{testStore}
+
+	t.Run("should render a region as a GeoJSON feature", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handler.GetRegion).ServeHTTP(rr, withName(httptest.NewRequest("GET", "/South", nil), "South"))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var feature Feature
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&feature), "should be able to decode response json")
+		assert.Equal(t, "South", feature.Properties.Region)
+		assert.Equal(t, []string{"South State"}, feature.Properties.States)
+		assert.Equal(t, "Polygon", feature.Geometry.Type)
+	})
+
+	t.Run("should respond with 404 for an unknown region", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handler.GetRegion).ServeHTTP(rr, withName(httptest.NewRequest("GET", "/North", nil), "North"))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+	})
+
+	t.Run("should list every region", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handler.ListRegions).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+		var collection FeatureCollection
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&collection), "should be able to decode response json")
+		assert.Equal(t, 2, len(collection.Features))
+
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(RouteHandler{mockDataProvider{}}.ListRegions).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+		assert.JSONEq(t, `{"type":"FeatureCollection","features":[]}`, rr.Body.String(), "expect an empty collection")
+	})
+
+	t.Run("should create a region", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"region": "Pair", "states": ["West", "East"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		http.HandlerFunc(handler.CreateRegion).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, "request should respond with 201 Created")
+
+		var feature Feature
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&feature), "should be able to decode response json")
+		assert.Equal(t, "Pair", feature.Properties.Region)
+	})
+
+	t.Run("should respond with 400 for invalid regions", func(t *testing.T) {
+		for _, body := range []string{`{"region": "Pair"}`, `{"states": ["West"]}`, `not json`} {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			http.HandlerFunc(handler.CreateRegion).ServeHTTP(rr, req)
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request with body %s should respond with 400 Bad Request", body)
+		}
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"region": "Pair", "states": ["Missing"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		store := mockDataProvider{Err: &backend.InvalidRegionError{Err: fmt.Errorf("no state found with name: Missing")}}
+		http.HandlerFunc(RouteHandler{store}.CreateRegion).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+	})
+
+	t.Run("should delete a region", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handler.DeleteRegion).ServeHTTP(rr, withName(httptest.NewRequest("DELETE", "/South", nil), "South"))
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		rr = httptest.NewRecorder()
+		store := mockDataProvider{Err: fmt.Errorf("boom")}
+		http.HandlerFunc(RouteHandler{store}.DeleteRegion).ServeHTTP(rr, withName(httptest.NewRequest("DELETE", "/South", nil), "South"))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
+
+func TestLocateRegionsHandler(t *testing.T) {
+	testStore := mockDataProvider{Regions: []geospatial.Region{squareRegion("South", 0, 0), squareRegion("Big", -5, -5)}}
+	handler := http.HandlerFunc(RouteHandler{testStore}.LocateRegions)
+
+	t.Run("should return the names of the regions containing the location", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/locate?lat=2&lng=2", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		var names []string
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&names), "should be able to decode response json")
+		assert.ElementsMatch(t, []string{"South", "Big"}, names)
+	})
+
+	t.Run("should return GeoJSON when requested", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/locate?lat=-2&lng=-2", nil)
+		req.Header.Set("Accept", api.ContentTypeGeoJSON)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, api.ContentTypeGeoJSON, rr.Header().Get("Content-Type"))
+		var collection FeatureCollection
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&collection), "should be able to decode response json")
+		assert.Equal(t, 1, len(collection.Features))
+		assert.Equal(t, "Big", collection.Features[0].Properties.Region)
+	})
+
+	t.Run("should respond with 404 outside every region and 400 for an invalid location", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/locate?lat=50&lng=50", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/locate?lat=2", nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+	})
+}
+
+func TestRegionHandlersWithMemoryStore(t *testing.T) {
+	store := backend.NewMemoryStore()
+	_, err := store.Create(geospatial.State{Name: "Square", Border: geospatial.Polygon{
+		{Lng: 0, Lat: 0}, {Lng: 0, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 0}, {Lng: 0, Lat: 0},
+	}})
+	assert.Nil(t, err, "data store should add valid state with no errors")
+
+	router := Router(backend.WithContext(store))
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	region := `{"region": "Block", "states": ["Square"]}`
+	assert.Equal(t, http.StatusCreated, request("POST", "/", region).Code, "request should respond with 201 Created")
+	assert.Equal(t, http.StatusConflict, request("POST", "/", region).Code, "duplicate regions should respond with 409 Conflict")
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/", `{"region": "Other", "states": ["Missing"]}`).Code, "regions of missing states should respond with 400 Bad Request")
+
+	assert.Equal(t, http.StatusOK, request("DELETE", "/block", "").Code, "request should respond with 200 OK")
+	assert.Equal(t, http.StatusNotFound, request("DELETE", "/block", "").Code, "deleting a missing region should respond with 404 Not Found")
+	assert.Equal(t, http.StatusNotFound, request("GET", "/block", "").Code, "request should respond with 404 Not Found")
+}
@@ -0,0 +1,35 @@
+// Package regions provides the REST API endpoints needed for CRUD operations on
+// named regions made up of geospatial state objects in the backend data store,
+// and for finding the region(s) in which a given location is contained
+package regions
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// Injects the dependcies required by the handler for the
+// backend data store.
+type DataProvider interface {
+	GetAllRegions(ctx context.Context) ([]geospatial.Region, error)
+	GetRegion(ctx context.Context, name string) (geospatial.Region, error)
+	CreateRegion(ctx context.Context, region geospatial.Region) (geospatial.Region, error)
+	DeleteRegion(ctx context.Context, name string) error
+}
+
+// Maps the handler to the REST API endpoints for the region API
+func Router(store DataProvider) chi.Router {
+	router := chi.NewRouter()
+	handler := RouteHandler{store}
+
+	router.Get("/", handler.ListRegions)
+	router.Post("/", handler.CreateRegion)
+	router.Get("/locate", handler.LocateRegions)
+	router.Get("/{name}", handler.GetRegion)
+	router.Delete("/{name}", handler.DeleteRegion)
+
+	return router
+}
@@ -0,0 +1,93 @@
+package regions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// GeoJSON schema for the properties object of a region feature
+type Properties struct {
+	Region string   `json:"region"`
+	States []string `json:"states"`
+}
+
+// GeoJSON schema for a feature object representing a region
+type Feature struct {
+	Type       string       `json:"type"`
+	Properties Properties   `json:"properties"`
+	Geometry   api.Geometry `json:"geometry"`
+}
+
+// GeoJSON schema for a FeatureCollection object to represent a collection of several regions
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// render method which hooks into the go-chi renderer
+func (f Feature) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (fc FeatureCollection) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Translates the [geospatial.Region] object from the [geospatial] package into a GeoJSON feature
+func NewRegionResponse(region geospatial.Region) Feature {
+	return Feature{
+		Type:       "Feature",
+		Properties: Properties{Region: region.Name, States: region.States},
+		Geometry:   api.NewGeometry(region.Border),
+	}
+}
+
+// Translates an array of GeoJSON region features into a GeoJSON FeatureCollection
+func NewRegionCollectionResponse(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+}
+
+// Adds the Bind method to the [geospatial.Region] object to hook into the go-chi renderer.
+// Only the name and member states are given, the border is computed by the data store
+type CreateRegionRequest geospatial.Region
+
+func (crr *CreateRegionRequest) UnmarshalJSON(data []byte) error {
+	required := struct {
+		Name   *string  `json:"region"`
+		States []string `json:"states"`
+	}{}
+
+	if err := json.Unmarshal(data, &required); err != nil {
+		return err
+	} else {
+		var missing []string
+		if required.Name == nil {
+			missing = append(missing, "region is required")
+		}
+		if len(required.States) == 0 {
+			missing = append(missing, "states are required")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("invalid json: %s", strings.Join(missing, ", "))
+		}
+	}
+	crr.Name = *required.Name
+	crr.States = required.States
+
+	return nil
+}
+
+func (crr *CreateRegionRequest) Bind(r *http.Request) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package states
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// Translates the version of a state into the strong entity tag rendered in the ETag header
+func etag(version uint64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// Checks the entity tags of an If-Match header against the current version of the state with
+// [api.ETagMatches], returning the version the request expects to change or [backend.VersionMismatchError]
+// if none of the tags match. If-Match requires the strong comparison, so weak tags are removed from the
+// header before it is checked and never match
+func ifMatch(header string, state geospatial.State) (uint64, error) {
+	current := etag(state.Version)
+	if api.ETagMatches(strings.ReplaceAll(header, "W/"+current, ""), current) {
+		return state.Version, nil
+	}
+	return 0, &backend.VersionMismatchError{Name: state.Name, Current: state.Version}
+}
+
+// Checks whether the entity tags of an If-None-Match header match the current version of the state with
+// [api.ETagMatches], in which case the client already has the state
+func ifNoneMatch(header string, state geospatial.State) bool {
+	return api.ETagMatches(header, etag(state.Version))
+}
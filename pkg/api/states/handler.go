@@ -2,6 +2,7 @@ package states
 
 import (
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -16,24 +17,90 @@ type RouteHandler struct {
 	store DataProvider
 }
 
-// HTTP request handler for the /api/v1/state/{name} endpoint
+// HTTP request handler for the /api/v1/state/{name} endpoint renders the state along with its version in the
+// ETag header, or responds with 304 Not Modified if the If-None-Match header matches the current version.
+// The optional asOf query parameter renders the state as it was at that time instead
 func (h RouteHandler) GetState(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 
-	state, err := h.store.GetByName(name)
+	asOf, err := api.ParseAsOf(r.URL.Query())
 	if err != nil {
-		var notFoundErr *backend.StateNotFoundError
-		if errors.As(err, &notFoundErr) {
-			render.Render(w, r, api.NotFoundError(err))
-		} else {
-			render.Render(w, r, api.InternalServerError(err))
-		}
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	var state geospatial.State
+	if asOf.IsZero() {
+		state, err = h.store.GetByName(r.Context(), name)
+	} else {
+		state, err = h.store.GetByNameAsOf(r.Context(), name, asOf)
+	}
+	if err != nil {
+		renderStateError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(state.Version))
+	if ifNoneMatch(r.Header.Get("If-None-Match"), state) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	render.Render(w, r, NewStateResponse(state))
 }
 
+// HTTP request handler for the /api/v1/state/search endpoint renders the states whose names or aliases
+// match the q query parameter, ranked by exact, prefix, substring and then fuzzy matches
+func (h RouteHandler) SearchStates(w http.ResponseWriter, r *http.Request) {
+	query, limit, err := parseSearchQuery(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	matches, err := h.store.Search(r.Context(), query, limit)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	render.Render(w, r, NewSearchResponse(query, matches))
+}
+
+// HTTP request handler for the /api/v1/state/{name}/neighbors endpoint renders the states which
+// share part of their border with the given state along with the length of each shared border
+func (h RouteHandler) GetNeighbors(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	state, err := h.store.GetByName(r.Context(), name)
+	if err != nil {
+		renderStateError(w, r, err)
+		return
+	}
+
+	neighbors, err := h.store.GetNeighbors(r.Context(), state.Name)
+	if err != nil {
+		render.Render(w, r, api.DataStoreError(err))
+		return
+	}
+
+	render.Render(w, r, NeighborsResponse{State: state.Name, Neighbors: neighbors})
+}
+
+// HTTP request handler for the /api/v1/state/{name}/history endpoint renders every change made to the
+// state, oldest first, along with who made it, when and which fields changed
+func (h RouteHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	revisions, err := h.store.GetHistory(r.Context(), name)
+	if err != nil {
+		renderStateError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, NewHistoryResponse(name, revisions))
+}
+
 // HTTP  request handler for the POST /api/v1/state endpoint creates the [geospatialspatial.State] object and
 // adds it to the data store
 func (h RouteHandler) CreateState(w http.ResponseWriter, r *http.Request) {
@@ -43,50 +110,272 @@ func (h RouteHandler) CreateState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := h.store.Create(geospatial.State(*state))
+	created, err := h.store.Create(r.Context(), geospatial.State(*state))
 	if err != nil {
-		var invalidStateErr *backend.InvalidStateError
-		if errors.As(err, &invalidStateErr) {
-			render.Render(w, r, api.BadRequestError(err))
-		} else {
-			render.Render(w, r, api.InternalServerError(err))
-		}
+		renderStateError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(created.Version))
 	w.WriteHeader(http.StatusCreated)
 	render.Render(w, r, NewStateResponse(created))
 
 }
 
+// HTTP request handler for the PUT /api/v1/state/{name} endpoint replaces the state, including its name,
+// border, aliases and properties, with the one given in a single step. With an If-Match header the state
+// is only replaced if it is still at one of the versions given
+func (h RouteHandler) ReplaceState(w http.ResponseWriter, r *http.Request) {
+	state := &CreateStateRequest{}
+	if err := render.Bind(r, state); err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	version, err := h.expectedVersion(r)
+	if err != nil {
+		renderStateError(w, r, err)
+		return
+	}
+
+	h.updateState(w, r, version, geospatial.State(*state))
+}
+
+// HTTP request handler for the PATCH /api/v1/state/{name} endpoint applies a JSON Merge Patch or, with the
+// application/json-patch+json content type, a JSON Patch to the state. Either every change in the patch
+// is applied or none are, and the patch is not applied if the state changes while it is being patched or,
+// with an If-Match header, if the state is not at one of the versions given
+func (h RouteHandler) PatchState(w http.ResponseWriter, r *http.Request) {
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	state, err := h.store.GetByName(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		renderStateError(w, r, err)
+		return
+	}
+	if header := r.Header.Get("If-Match"); header != "" {
+		if _, err := ifMatch(header, state); err != nil {
+			renderStateError(w, r, err)
+			return
+		}
+	}
+
+	patched, err := patchState(state, r.Header.Get("Content-Type"), patch)
+	if err != nil {
+		var unsupportedErr *UnsupportedPatchError
+		if errors.As(err, &unsupportedErr) {
+			render.Render(w, r, api.UnsupportedMediaTypeError(err))
+		} else {
+			render.Render(w, r, api.BadRequestError(err))
+		}
+		return
+	}
+
+	h.updateState(w, r, state.Version, patched)
+}
+
+// replaces the state named in the request path with the given state if it is still at the
+// given version and renders the updated state along with its new version in the ETag header
+func (h RouteHandler) updateState(w http.ResponseWriter, r *http.Request, version uint64, state geospatial.State) {
+	updated, err := h.store.Update(r.Context(), chi.URLParam(r, "name"), version, state)
+	if err != nil {
+		renderStateError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(updated.Version))
+	render.Render(w, r, NewStateResponse(updated))
+}
+
+// Gets the version of the state named in the request path which the request expects to change from
+// its If-Match header, or zero if the request has no If-Match header
+func (h RouteHandler) expectedVersion(r *http.Request) (uint64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+
+	state, err := h.store.GetByName(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		return 0, err
+	}
+	return ifMatch(header, state)
+}
+
 // HTTP request handler for the DELETE /api/v1/state/{name} endpoint removes a given state from
-// the data store
+// the data store. With an If-Match header the state is only removed if it is still at one of the
+// versions given
 func (h RouteHandler) DeleteState(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 
-	if err := h.store.Delete(name); err != nil {
-		render.Render(w, r, api.InternalServerError(err))
+	version, err := h.expectedVersion(r)
+	if err != nil {
+		renderStateError(w, r, err)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(nil)
+	if err := h.store.Delete(r.Context(), name, version); err != nil {
+		renderStateError(w, r, err)
+		return
+	}
 
+	w.WriteHeader(http.StatusOK)
 }
 
 // HTTP request handler for the GET /api/v1/state endpoint renders the entire list of states
-// in the data store to a GeoJSON feature collection
+// in the data store to a GeoJSON feature collection. The optional bbox query parameter limits the
+// collection to the states whose borders intersect the bounding box, and clip=true clips each
+// of those borders to the bounding box. Parameters named properties.<key> limit the collection to
+// the states with one of the given values for that property
 func (h RouteHandler) ListStates(w http.ResponseWriter, r *http.Request) {
-	states, err := h.store.GetAll()
+	view, clip, err := parseViewQuery(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	filter, err := parsePropertyFilter(r.URL.Query())
+	if err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	states, err := h.store.GetAll(r.Context())
 	if err != nil {
-		render.Render(w, r, api.InternalServerError(err))
+		render.Render(w, r, api.DataStoreError(err))
 		return
 	}
 
 	var features []api.Feature
 	for _, state := range states {
-		features = append(features, NewStateResponse(state))
+		if !filter.matches(state) {
+			continue
+		}
+		if view == nil {
+			features = append(features, NewStateResponse(state))
+		} else if feature, ok := stateInView(state, *view, clip); ok {
+			features = append(features, feature)
+		}
 	}
 
 	render.Render(w, r, NewStateCollectionResponse(features))
 
 }
+
+// checks whether the state's border intersects the bounding box, and if so translates the state into a
+// GeoJSON feature with its border optionally clipped to the bounding box
+func stateInView(state geospatial.State, view geospatial.BoundingBox, clip bool) (api.Feature, bool) {
+	if !view.Intersects(state.Border.Bounds()) {
+		return api.Feature{}, false
+	}
+
+	clipped := state.Border.Intersection(view.Polygon())
+	if len(clipped) == 0 {
+		return api.Feature{}, false
+	}
+
+	feature := NewStateResponse(state)
+	if clip {
+		feature.Geometry = api.NewGeometry(clipped)
+	}
+	return feature, true
+}
+
+// HTTP request handler for the /api/v1/state/{name}/county/{county} and
+// /api/v1/state/{name}/county/{county}/place/{place} endpoints
+func (h RouteHandler) GetBoundary(w http.ResponseWriter, r *http.Request) {
+	boundary, err := h.store.GetBoundary(r.Context(), boundaryPath(r))
+	if err != nil {
+		renderBoundaryError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, NewBoundaryResponse(boundary))
+}
+
+// HTTP request handler for the GET /api/v1/state/{name}/county and /api/v1/state/{name}/county/{county}/place
+// endpoints renders the boundaries directly beneath the state or county to a GeoJSON feature collection
+func (h RouteHandler) ListBoundaries(w http.ResponseWriter, r *http.Request) {
+	boundaries, err := h.store.GetChildren(r.Context(), boundaryPath(r))
+	if err != nil {
+		renderBoundaryError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, NewBoundaryCollectionResponse(boundaries))
+}
+
+// HTTP request handler for the POST /api/v1/state/{name}/county and /api/v1/state/{name}/county/{county}/place
+// endpoints creates the [geospatial.Boundary] object and adds it to the data store beneath the state or county
+func (h RouteHandler) CreateBoundary(w http.ResponseWriter, r *http.Request) {
+	boundary := &CreateBoundaryRequest{}
+	if err := render.Bind(r, boundary); err != nil {
+		render.Render(w, r, api.BadRequestError(err))
+		return
+	}
+
+	created, err := h.store.CreateBoundary(r.Context(), boundaryPath(r), geospatial.Boundary(*boundary))
+	if err != nil {
+		renderBoundaryError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.Render(w, r, NewBoundaryResponse(created))
+}
+
+// HTTP request handler for the DELETE /api/v1/state/{name}/county/{county} and
+// /api/v1/state/{name}/county/{county}/place/{place} endpoints removes the boundary,
+// and every boundary beneath it, from the data store
+func (h RouteHandler) DeleteBoundary(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteBoundary(r.Context(), boundaryPath(r)); err != nil {
+		renderBoundaryError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// maps the errors returned by the data store for a state to error responses
+func renderStateError(w http.ResponseWriter, r *http.Request, err error) {
+	var notFoundErr *backend.StateNotFoundError
+	var invalidStateErr *backend.InvalidStateError
+	var conflictErr *backend.ConflictError
+	var versionMismatchErr *backend.VersionMismatchError
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		render.Render(w, r, api.NotFoundError(err))
+	case errors.As(err, &invalidStateErr):
+		render.Render(w, r, api.BadRequestError(err))
+	case errors.As(err, &conflictErr):
+		render.Render(w, r, api.ConflictError(err))
+	case errors.As(err, &versionMismatchErr):
+		render.Render(w, r, api.PreconditionFailedError(err))
+	default:
+		render.Render(w, r, api.DataStoreError(err))
+	}
+}
+
+// maps the errors returned by the data store for the hierarchy of geographies to error responses
+func renderBoundaryError(w http.ResponseWriter, r *http.Request, err error) {
+	var stateNotFoundErr *backend.StateNotFoundError
+	var boundaryNotFoundErr *backend.BoundaryNotFoundError
+	var invalidBoundaryErr *backend.InvalidBoundaryError
+	var conflictErr *backend.ConflictError
+
+	switch {
+	case errors.As(err, &stateNotFoundErr), errors.As(err, &boundaryNotFoundErr):
+		render.Render(w, r, api.NotFoundError(err))
+	case errors.As(err, &invalidBoundaryErr):
+		render.Render(w, r, api.BadRequestError(err))
+	case errors.As(err, &conflictErr):
+		render.Render(w, r, api.ConflictError(err))
+	default:
+		render.Render(w, r, api.DataStoreError(err))
+	}
+}
@@ -1,12 +1,15 @@
 package states
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aaronireland/state-server/pkg/api"
 	"github.com/aaronireland/state-server/pkg/api/backend"
@@ -15,29 +18,135 @@ import (
 )
 
 type mockDataProvider struct {
-	Err    error
-	States []geospatial.State
+	Err        error
+	States     []geospatial.State
+	Boundaries []geospatial.Boundary
+	Revisions  []backend.Revision
 }
 
-func (m mockDataProvider) GetAll() ([]geospatial.State, error) {
+func (m mockDataProvider) GetAll(ctx context.Context) ([]geospatial.State, error) {
 	return m.States, m.Err
 }
 
-func (m mockDataProvider) GetByName(name string) (geospatial.State, error) {
+func (m mockDataProvider) GetByName(ctx context.Context, name string) (geospatial.State, error) {
 	if m.Err != nil {
 		return geospatial.State{}, m.Err
 	}
-	return m.States[0], nil
+	for _, state := range m.States {
+		if strings.EqualFold(state.Name, name) {
+			return state, nil
+		}
+	}
+	return geospatial.State{}, &backend.StateNotFoundError{Name: name}
+}
+
+func (m mockDataProvider) GetByNameAsOf(ctx context.Context, name string, at time.Time) (geospatial.State, error) {
+	if m.Err != nil {
+		return geospatial.State{}, m.Err
+	}
+	var state *geospatial.State
+	for _, revision := range m.Revisions {
+		if strings.EqualFold(revision.State.Name, name) && !revision.Time.After(at) {
+			state = &revision.State
+			if revision.Change == backend.Deleted {
+				state = nil
+			}
+		}
+	}
+	if state == nil {
+		return geospatial.State{}, &backend.StateNotFoundError{Name: name}
+	}
+	return *state, nil
+}
+
+func (m mockDataProvider) GetHistory(ctx context.Context, name string) ([]backend.Revision, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var revisions []backend.Revision
+	for _, revision := range m.Revisions {
+		if strings.EqualFold(revision.State.Name, name) {
+			revisions = append(revisions, revision)
+		}
+	}
+	if len(revisions) == 0 {
+		return nil, &backend.StateNotFoundError{Name: name}
+	}
+	return revisions, nil
+}
+
+func (m mockDataProvider) Search(ctx context.Context, query string, limit int) ([]backend.SearchMatch, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var matches []backend.SearchMatch
+	for _, state := range m.States {
+		if strings.Contains(strings.ToLower(state.Name), strings.ToLower(query)) && len(matches) < limit {
+			matches = append(matches, backend.SearchMatch{State: state.Name, Matched: state.Name, Kind: backend.SubstringMatch})
+		}
+	}
+	return matches, nil
 }
 
-func (m mockDataProvider) Create(state geospatial.State) (geospatial.State, error) {
+func (m mockDataProvider) Create(ctx context.Context, state geospatial.State) (geospatial.State, error) {
 	if m.Err != nil {
 		return geospatial.State{}, m.Err
 	}
 	return state, nil
 }
 
-func (m mockDataProvider) Delete(name string) error {
+func (m mockDataProvider) Update(ctx context.Context, name string, version uint64, state geospatial.State) (geospatial.State, error) {
+	if m.Err != nil {
+		return geospatial.State{}, m.Err
+	}
+	return state, nil
+}
+
+func (m mockDataProvider) Delete(ctx context.Context, name string, version uint64) error {
+	return m.Err
+}
+
+func (m mockDataProvider) GetNeighbors(ctx context.Context, name string) ([]geospatial.Neighbor, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if _, err := m.GetByName(ctx, name); err != nil {
+		return nil, err
+	}
+	var neighbors []geospatial.Neighbor
+	for _, state := range m.States {
+		if !strings.EqualFold(state.Name, name) {
+			neighbors = append(neighbors, geospatial.Neighbor{Name: state.Name, Border: 1})
+		}
+	}
+	return neighbors, nil
+}
+
+func (m mockDataProvider) GetBoundary(ctx context.Context, path []string) (geospatial.Boundary, error) {
+	if m.Err != nil {
+		return geospatial.Boundary{}, m.Err
+	}
+	for _, boundary := range m.Boundaries {
+		if boundary.Name == path[len(path)-1] {
+			return boundary, nil
+		}
+	}
+	return geospatial.Boundary{}, &backend.BoundaryNotFoundError{Path: path}
+}
+
+func (m mockDataProvider) GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error) {
+	return m.Boundaries, m.Err
+}
+
+func (m mockDataProvider) CreateBoundary(ctx context.Context, parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error) {
+	if m.Err != nil {
+		return geospatial.Boundary{}, m.Err
+	}
+	boundary.Path = parent
+	return boundary, nil
+}
+
+func (m mockDataProvider) DeleteBoundary(ctx context.Context, path []string) error {
 	return m.Err
 }
 
@@ -59,12 +168,12 @@ func TestGetStateHandler(t *testing.T) {
 		States: []geospatial.State{squareState},
 	}
 
-	handler := http.HandlerFunc(RouteHandler{testStore}.GetState)
+	handler := Router(testStore)
 
 	t.Run("should render valid RFC 7946 Feature", func(t *testing.T) {
 
 		rr := httptest.NewRecorder()
-		req, err := http.NewRequest("GET", "/api/v1/state/square", nil)
+		req, err := http.NewRequest("GET", "/Square", nil)
 		assert.Nil(t, err, "should generate valid http request")
 
 		handler.ServeHTTP(rr, req)
@@ -137,6 +246,72 @@ func TestCreateStateHandler(t *testing.T) {
 		assert.Equal(t, 1, len(feature.Geometry.Coordinates), "response object should have a valid polygon")
 		assert.GreaterOrEqual(t, len(feature.Geometry.Coordinates[0]), 4, "response object should have a valid polygon")
 	})
+
+	t.Run("should render the properties of the state", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{}}.CreateState)
+		rr := httptest.NewRecorder()
+
+		body := strings.NewReader(`{"state": "Washington", "border": [[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]], "properties": {"abbreviation": "WA", "fips": "53", "capital": {"name": "Olympia"}}}`)
+		req, err := http.NewRequest("POST", "/api/v1/state/", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, "request should respond with 201 Created")
+
+		var feature struct {
+			Properties map[string]interface{} `json:"properties"`
+		}
+		err = json.NewDecoder(rr.Body).Decode(&feature)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, map[string]interface{}{
+			"state": "Washington", "abbreviation": "WA", "fips": "53", "capital": map[string]interface{}{"name": "Olympia"},
+		}, feature.Properties, "properties should be returned alongside the state name")
+	})
+
+	t.Run("should render the aliases and properties of the state", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{}}.CreateState)
+		rr := httptest.NewRecorder()
+
+		body := strings.NewReader(`{"state": "Washington", "border": [[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]], "aliases": ["WA", "53"], "properties": {"capital": {"name": "Olympia"}}}`)
+		req, err := http.NewRequest("POST", "/api/v1/state/", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, "request should respond with 201 Created")
+
+		var feature struct {
+			Properties map[string]interface{} `json:"properties"`
+		}
+		err = json.NewDecoder(rr.Body).Decode(&feature)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, map[string]interface{}{
+			"state": "Washington", "aliases": []interface{}{"WA", "53"}, "capital": map[string]interface{}{"name": "Olympia"},
+		}, feature.Properties, "aliases and properties should be returned alongside the state name")
+	})
+
+	t.Run("should render the country of the state", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{}}.CreateState)
+		rr := httptest.NewRecorder()
+
+		body := strings.NewReader(`{"state": "Washington", "country": "United States", "border": [[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]]}`)
+		req, err := http.NewRequest("POST", "/api/v1/state/", body)
+		assert.Nil(t, err, "should generate valid http request")
+
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, "request should respond with 201 Created")
+
+		var feature api.Feature
+		err = json.NewDecoder(rr.Body).Decode(&feature)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, "United States", feature.Properties.Country, "country should be returned alongside the state name")
+		assert.Nil(t, feature.Properties.Other, "country should not be returned as another property")
+	})
 }
 
 func TestCreateStateHandlerBadRequest(t *testing.T) {
@@ -328,3 +503,623 @@ func TestStateHandlerInternalServerError(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
 	})
 }
+
+func TestStateHandlerContextError(t *testing.T) {
+	t.Run("should render 499 for a request cancelled before the data store answered", func(t *testing.T) {
+		testStore := mockDataProvider{Err: fmt.Errorf("unable to write to the data store: %w", context.Canceled)}
+		handler := http.HandlerFunc(RouteHandler{testStore}.DeleteState)
+
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("DELETE", "/api/v1/state/foo", nil)
+		assert.Nil(t, err, "should generate valid http request")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, api.StatusClientClosedRequest, rr.Code, "request should respond with 499 Client Closed Request")
+	})
+
+	t.Run("should render 504 for a request which passed its deadline before the data store answered", func(t *testing.T) {
+		testStore := mockDataProvider{Err: context.DeadlineExceeded}
+		handler := http.HandlerFunc(RouteHandler{testStore}.ListStates)
+
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/state/", nil)
+		assert.Nil(t, err, "should generate valid http request")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code, "request should respond with 504 Gateway Timeout")
+	})
+}
+
+func TestListStatesInViewHandler(t *testing.T) {
+	westState, err := geospatial.NewState(
+		"west",
+		[]geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	// a U shape open to the north
+	eastState, err := geospatial.NewState(
+		"east",
+		[]geospatial.Coordinate{{Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 13, Lat: 10}, {Lng: 13, Lat: 3}, {Lng: 17, Lat: 3}, {Lng: 17, Lat: 10}, {Lng: 20, Lat: 10}, {Lng: 20, Lat: 0}, {Lng: 10, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{westState, eastState},
+	}
+	handler := http.HandlerFunc(RouteHandler{testStore}.ListStates)
+
+	listStates := func(t *testing.T, query string) (*httptest.ResponseRecorder, api.FeatureCollection) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/state/?"+query, nil)
+		assert.Nil(t, err, "should generate valid http request")
+		handler.ServeHTTP(rr, req)
+
+		var collection api.FeatureCollection
+		if rr.Code == http.StatusOK {
+			err = json.NewDecoder(rr.Body).Decode(&collection)
+			assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		}
+		return rr, collection
+	}
+
+	t.Run("should only render states whose borders intersect the bounding box", func(t *testing.T) {
+		rr, collection := listStates(t, "bbox=12,5,18,8")
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, 1, len(collection.Features), "only the east state intersects the bounding box")
+		assert.Equal(t, "east", collection.Features[0].Properties.State, "only the east state intersects the bounding box")
+		assert.Equal(t, "Polygon", collection.Features[0].Geometry.Type, "unclipped border should be a polygon")
+		assert.ElementsMatch(t, eastState.Border, collection.Features[0].Geometry.Coordinates[0], "unclipped border should be unchanged")
+
+		rr, collection = listStates(t, "bbox=14,5,16,8")
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Empty(t, collection.Features, "bounding box lies within the gap in the east state")
+
+		rr, collection = listStates(t, "bbox=-5,-5,25,15")
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, 2, len(collection.Features), "both states lie within the bounding box")
+	})
+
+	t.Run("should clip borders to the bounding box", func(t *testing.T) {
+		rr, collection := listStates(t, "bbox=5,5,18,8&clip=true")
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, 2, len(collection.Features), "both states intersect the bounding box")
+
+		for _, feature := range collection.Features {
+			switch feature.Properties.State {
+			case "west":
+				assert.Equal(t, "Polygon", feature.Geometry.Type, "clipped west state should be a single polygon")
+				assert.Equal(t, 1, len(feature.Geometry.Coordinates), "clipped west state should have a single ring")
+				for _, coord := range feature.Geometry.Coordinates[0] {
+					assert.GreaterOrEqualf(t, coord.Lng, float64(5), "clipped border should lie within the bounding box: %s", coord)
+				}
+			case "east":
+				assert.Equal(t, "MultiPolygon", feature.Geometry.Type, "clipped east state should be split in two by its gap")
+				assert.Equal(t, 2, len(feature.Geometry.MultiPolygon), "clipped east state should be split in two by its gap")
+			}
+		}
+	})
+
+	t.Run("should return BadRequestError for invalid query parameters", func(t *testing.T) {
+		for _, query := range []string{"bbox=1,2,3", "bbox=a,b,c,d", "bbox=10,0,0,10", "bbox=0,0,10,100", "bbox=0,0,10,10&clip=maybe"} {
+			rr, _ := listStates(t, query)
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request: %s", query)
+		}
+	})
+}
+
+func TestGetNeighborsHandler(t *testing.T) {
+	westState, err := geospatial.NewState(
+		"west",
+		[]geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 10, Lat: 0}, {Lng: 10, Lat: 10}, {Lng: 0, Lat: 10}, {Lng: 0, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	eastState, err := geospatial.NewState(
+		"east",
+		[]geospatial.Coordinate{{Lng: 10, Lat: 0}, {Lng: 20, Lat: 0}, {Lng: 20, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 0}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+
+	t.Run("should render the neighbors of the state", func(t *testing.T) {
+		handler := Router(mockDataProvider{States: []geospatial.State{westState, eastState}})
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/west/neighbors", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var resp NeighborsResponse
+		err = json.NewDecoder(rr.Body).Decode(&resp)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, "west", resp.State, "response should name the state")
+		assert.Equal(t, []geospatial.Neighbor{{Name: "east", Border: 1}}, resp.Neighbors, "response should contain the neighbors of the state")
+	})
+
+	t.Run("should render 404 for no state found", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{Err: &backend.StateNotFoundError{Name: "nunavut"}}}.GetNeighbors)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/state/nunavut/neighbors", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+	})
+
+	t.Run("should render internal server error for backend error", func(t *testing.T) {
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{Err: fmt.Errorf("test internal server error")}}.GetNeighbors)
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/api/v1/state/west/neighbors", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
+
+func TestBoundaryHandlers(t *testing.T) {
+	county, err := geospatial.NewBoundary("north", geospatial.LevelCounty,
+		[]geospatial.Coordinate{{Lng: 0, Lat: 5}, {Lng: 0, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 5}, {Lng: 0, Lat: 5}},
+	)
+	assert.Nil(t, err, "given coordinates should produce a valid boundary")
+	county.Path = []string{"square"}
+
+	router := Router(mockDataProvider{Boundaries: []geospatial.Boundary{county}})
+
+	t.Run("should render a county as a GeoJSON feature", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/square/county/north", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var feature BoundaryFeature
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&feature), "should be able to decode response json")
+		assert.Equal(t, BoundaryProperties{Name: "north", Level: geospatial.LevelCounty, Path: []string{"square"}}, feature.Properties)
+		assert.Equal(t, "Polygon", feature.Geometry.Type)
+	})
+
+	t.Run("should render 404 for no county found", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/square/county/south", nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+	})
+
+	t.Run("should list the counties of a state", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/square/county", nil))
+
+		var collection BoundaryCollection
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&collection), "should be able to decode response json")
+		assert.Equal(t, 1, len(collection.Features))
+
+		rr = httptest.NewRecorder()
+		Router(mockDataProvider{Err: &backend.StateNotFoundError{Name: "nunavut"}}).ServeHTTP(rr, httptest.NewRequest("GET", "/nunavut/county", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+	})
+
+	t.Run("should create a place within a county", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		body := strings.NewReader(`{"name": "town", "border": [[1, 6], [1, 7], [2, 7], [2, 6], [1, 6]]}`)
+		req := httptest.NewRequest("POST", "/square/county/north/place", body)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, "request should respond with 201 Created")
+
+		var feature BoundaryFeature
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&feature), "should be able to decode response json")
+		assert.Equal(t, []string{"square", "north"}, feature.Properties.Path, "place should be created beneath the state and county")
+	})
+
+	t.Run("should render 400 for an invalid boundary", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/square/county", strings.NewReader(`{"name": "north"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+
+		rr = httptest.NewRecorder()
+		req = httptest.NewRequest("POST", "/square/county", strings.NewReader(`{"name": "north", "border": [[1, 6], [1, 7], [2, 7], [2, 6], [1, 6]]}`))
+		req.Header.Set("Content-Type", "application/json")
+		store := mockDataProvider{Err: &backend.InvalidBoundaryError{Err: fmt.Errorf("duplicate county: North")}}
+		Router(store).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+	})
+
+	t.Run("should delete a county", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("DELETE", "/square/county/north", nil))
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+	})
+}
+
+func TestListStatesByPropertyHandler(t *testing.T) {
+	newState := func(name string, properties map[string]interface{}) geospatial.State {
+		state, err := geospatial.NewState(name, []geospatial.Coordinate{{Lng: 0, Lat: 0}, {Lng: 0, Lat: 1}, {Lng: 1, Lat: 1}, {Lng: 1, Lat: 0}, {Lng: 0, Lat: 0}})
+		assert.Nil(t, err, "given coordinates should produce a valid state")
+		state.Properties = properties
+		return state
+	}
+
+	testStore := mockDataProvider{States: []geospatial.State{
+		newState("Pennsylvania", map[string]interface{}{"abbreviation": "PA", "population": float64(12961683), "coastal": false}),
+		newState("New Jersey", map[string]interface{}{"abbreviation": "NJ", "population": float64(9290841), "coastal": true}),
+		newState("Ohio", nil),
+	}}
+	handler := http.HandlerFunc(RouteHandler{testStore}.ListStates)
+
+	for query, expected := range map[string][]string{
+		"":                           {"Pennsylvania", "New Jersey", "Ohio"},
+		"properties.abbreviation=PA": {"Pennsylvania"},
+		"properties.abbreviation=PA&properties.abbreviation=NJ": {"Pennsylvania", "New Jersey"},
+		"properties.population=9290841":                         {"New Jersey"},
+		"properties.coastal=true":                               {"New Jersey"},
+		"properties.coastal=false&properties.abbreviation=NJ":   {},
+		"properties.capital=Columbus":                           {},
+		"properties.state=Ohio":                                 {"Ohio"},
+	} {
+		t.Run("should filter states by "+query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/state?"+query, nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+			var collection api.FeatureCollection
+			err := json.NewDecoder(rr.Body).Decode(&collection)
+			assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+
+			names := []string{}
+			for _, feature := range collection.Features {
+				names = append(names, feature.Properties.State)
+			}
+			assert.ElementsMatch(t, expected, names)
+		})
+	}
+
+	t.Run("should filter states by their aliases", func(t *testing.T) {
+		ohio := newState("Ohio", nil)
+		ohio.Aliases = []string{"OH", "39"}
+		handler := http.HandlerFunc(RouteHandler{mockDataProvider{States: append(slices.Clone(testStore.States[:2]), ohio)}}.ListStates)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/state?properties.aliases=39", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var collection api.FeatureCollection
+		err := json.NewDecoder(rr.Body).Decode(&collection)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, 1, len(collection.Features), "only the state with the alias should match")
+		assert.Equal(t, "Ohio", collection.Features[0].Properties.State, "state should be found by its alias")
+	})
+
+	t.Run("should render 400 for a filter without a property name", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/state?properties.=PA", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+	})
+}
+
+func TestSearchStatesHandler(t *testing.T) {
+	testStore := mockDataProvider{States: []geospatial.State{{Name: "Pennsylvania"}, {Name: "Kansas"}, {Name: "Arkansas"}}}
+	router := Router(testStore)
+
+	t.Run("should render the matching states", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/search?q=kansas", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.JSONEq(t, `{"query": "kansas", "results": [
+			{"state": "Kansas", "matched": "Kansas", "match": "substring", "distance": 0},
+			{"state": "Arkansas", "matched": "Arkansas", "match": "substring", "distance": 0}
+		]}`, rr.Body.String())
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/search?q=kansas&limit=1", nil))
+
+		var resp SearchResponse
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&resp), "should be able to decode response json")
+		assert.Equal(t, 1, len(resp.Results), "results should be limited")
+	})
+
+	t.Run("should render an empty list for no matches", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/search?q=nunavut", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.JSONEq(t, `{"query": "nunavut", "results": []}`, rr.Body.String())
+	})
+
+	t.Run("should render 400 for an invalid search", func(t *testing.T) {
+		for _, query := range []string{"", "q=", "q=penn&limit=0", "q=penn&limit=many", "q=penn&limit=1000"} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/search?"+query, nil))
+
+			assert.Equalf(t, http.StatusBadRequest, rr.Code, "request with query %q should respond with 400 Bad Request", query)
+		}
+	})
+
+	t.Run("should render internal server error for backend error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		Router(mockDataProvider{Err: fmt.Errorf("test internal server error")}).ServeHTTP(rr, httptest.NewRequest("GET", "/search?q=penn", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code, "request should respond with 500 Internal Server Error")
+	})
+}
+
+func TestUpdateStateHandler(t *testing.T) {
+	square, err := geospatial.NewState("Square", []geospatial.Coordinate{
+		{Lng: 0, Lat: 0}, {Lng: 0, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 0}, {Lng: 0, Lat: 0},
+	})
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+	square.Aliases = []string{"SQ"}
+	square.Properties = map[string]interface{}{"sides": float64(4), "color": "red"}
+
+	router := Router(mockDataProvider{States: []geospatial.State{square}})
+	update := func(method, contentType, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/square", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	properties := func(rr *httptest.ResponseRecorder) map[string]interface{} {
+		var feature struct {
+			Properties map[string]interface{} `json:"properties"`
+		}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&feature), "should be able to decode response json")
+		return feature.Properties
+	}
+
+	t.Run("should replace the state", func(t *testing.T) {
+		rr := update("PUT", "application/json", `{"state": "Rectangle", "border": [[0, 0], [0, 5], [10, 5], [10, 0], [0, 0]]}`)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, map[string]interface{}{"state": "Rectangle"}, properties(rr), "aliases and properties not given should be removed")
+	})
+
+	t.Run("should apply a merge patch", func(t *testing.T) {
+		rr := update("PATCH", "application/merge-patch+json", `{"properties": {"color": null, "sides": 5}}`)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, map[string]interface{}{"state": "Square", "aliases": []interface{}{"SQ"}, "sides": float64(5)}, properties(rr))
+	})
+
+	t.Run("should apply a json patch to the properties and vertices", func(t *testing.T) {
+		rr := update("PATCH", "application/json-patch+json", `[
+			{"op": "test", "path": "/properties/sides", "value": 4},
+			{"op": "add", "path": "/aliases/-", "value": "99"},
+			{"op": "replace", "path": "/border/2", "value": [12, 12]}
+		]`)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		var feature api.Feature
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&feature), "should be able to decode response json")
+		assert.Equal(t, []string{"SQ", "99"}, feature.Properties.Aliases)
+		assert.Contains(t, feature.Geometry.Coordinates[0], geospatial.Coordinate{Lng: 12, Lat: 12}, "the vertex should be moved")
+	})
+
+	t.Run("should render 400 for invalid updates", func(t *testing.T) {
+		for _, rr := range []*httptest.ResponseRecorder{
+			update("PUT", "application/json", `{"state": "Rectangle"}`),
+			update("PATCH", "application/merge-patch+json", `{"state": null}`),
+			update("PATCH", "application/json-patch+json", `[{"op": "test", "path": "/properties/sides", "value": 3}]`),
+			update("PATCH", "application/json-patch+json", `[{"op": "remove", "path": "/border/20"}]`),
+			update("PATCH", "application/json-patch+json", `{"op": "remove", "path": "/aliases"}`),
+		} {
+			assert.Equal(t, http.StatusBadRequest, rr.Code, "request should respond with 400 Bad Request")
+		}
+	})
+
+	t.Run("should render 415 for unsupported patch documents", func(t *testing.T) {
+		rr := update("PATCH", "text/plain", `sides=5`)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code, "request should respond with 415 Unsupported Media Type")
+	})
+
+	t.Run("should render 404 for a state which does not exist", func(t *testing.T) {
+		router = Router(mockDataProvider{Err: &backend.StateNotFoundError{Name: "square"}})
+
+		assert.Equal(t, http.StatusNotFound, update("PATCH", "", `{"properties": {"sides": 5}}`).Code)
+		assert.Equal(t, http.StatusNotFound, update("PUT", "application/json", `{"state": "Square", "border": [[0, 0], [0, 5], [10, 5], [10, 0], [0, 0]]}`).Code)
+	})
+}
+
+func TestStateVersionHandlers(t *testing.T) {
+	square, err := geospatial.NewState("Square", []geospatial.Coordinate{
+		{Lng: 0, Lat: 0}, {Lng: 0, Lat: 10}, {Lng: 10, Lat: 10}, {Lng: 10, Lat: 0}, {Lng: 0, Lat: 0},
+	})
+	assert.Nil(t, err, "given coordinates should produce a valid state")
+	square.Version = 7
+
+	router := Router(mockDataProvider{States: []geospatial.State{square}})
+	request := func(method string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/square", strings.NewReader(body))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	replacement := `{"state": "Square", "border": [[0, 0], [0, 5], [5, 5], [5, 0], [0, 0]]}`
+
+	t.Run("should render the version of the state as an ETag", func(t *testing.T) {
+		rr := request("GET", nil, "")
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, `"7"`, rr.Header().Get("ETag"))
+	})
+
+	t.Run("should render 304 when the client has the current version", func(t *testing.T) {
+		for _, header := range []string{`"7"`, `"3", W/"7"`, "*"} {
+			rr := request("GET", map[string]string{"If-None-Match": header}, "")
+			assert.Equalf(t, http.StatusNotModified, rr.Code, "If-None-Match %s should respond with 304 Not Modified", header)
+			assert.Empty(t, rr.Body.String(), "304 responses should have no body")
+		}
+
+		rr := request("GET", map[string]string{"If-None-Match": `"6"`}, "")
+		assert.Equal(t, http.StatusOK, rr.Code, "request for a changed state should respond with 200 OK")
+	})
+
+	t.Run("should only change the state when If-Match has the current version", func(t *testing.T) {
+		for _, header := range []string{`"7"`, `"6", "7"`, `W/"7", "7"`, " * "} {
+			rr := request("PUT", map[string]string{"If-Match": header, "Content-Type": "application/json"}, replacement)
+			assert.Equalf(t, http.StatusOK, rr.Code, "If-Match %s should respond with 200 OK", header)
+		}
+
+		for _, rr := range []*httptest.ResponseRecorder{
+			request("PUT", map[string]string{"If-Match": `"6"`, "Content-Type": "application/json"}, replacement),
+			request("PUT", map[string]string{"If-Match": `W/"7"`, "Content-Type": "application/json"}, replacement),
+			request("PUT", map[string]string{"If-Match": `"6", W/"7"`, "Content-Type": "application/json"}, replacement),
+			request("PATCH", map[string]string{"If-Match": `"6"`}, `{"properties": {"sides": 4}}`),
+			request("DELETE", map[string]string{"If-Match": `"6"`}, ""),
+		} {
+			assert.Equal(t, http.StatusPreconditionFailed, rr.Code, "stale versions should respond with 412 Precondition Failed")
+		}
+	})
+
+	t.Run("should render 412 when the state changes during an update", func(t *testing.T) {
+		router := Router(mockDataProvider{States: []geospatial.State{square}, Err: &backend.VersionMismatchError{Name: "Square", Current: 8}})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("DELETE", "/square", nil))
+
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code, "request should respond with 412 Precondition Failed")
+	})
+}
+
+func TestStateHandlersWithMemoryStore(t *testing.T) {
+	router := Router(backend.WithContext(backend.NewMemoryStore()))
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	square := `{"state": "Square", "border": [[0, 0], [0, 10], [10, 10], [10, 0], [0, 0]], "aliases": ["SQ"]}`
+	other := `{"state": "Other", "border": [[20, 0], [20, 10], [30, 10], [30, 0], [20, 0]], "aliases": ["OT"]}`
+	county := `{"name": "North", "border": [[0, 5], [0, 10], [5, 10], [5, 5], [0, 5]]}`
+
+	t.Run("should render 409 for states which clash with existing states", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, request("POST", "/", square).Code, "request should respond with 201 Created")
+		assert.Equal(t, http.StatusCreated, request("POST", "/", other).Code, "request should respond with 201 Created")
+
+		for _, rr := range []*httptest.ResponseRecorder{
+			request("POST", "/", square),
+			request("POST", "/", `{"state": "sq", "border": [[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]]}`),
+			request("PUT", "/square", other),
+			request("PATCH", "/square", `{"aliases": ["OT"]}`),
+		} {
+			assert.Equal(t, http.StatusConflict, rr.Code, "request should respond with 409 Conflict")
+		}
+		assert.Equal(t, http.StatusBadRequest, request("POST", "/", `{"state": "B", "border": [[40, 0], [40, 1], [41, 1], [41, 0], [40, 0]]}`).Code, "invalid states should still respond with 400 Bad Request")
+	})
+
+	t.Run("should render 409 for boundaries which already exist", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, request("POST", "/square/county", county).Code, "request should respond with 201 Created")
+		assert.Equal(t, http.StatusConflict, request("POST", "/sq/county", county).Code, "request should respond with 409 Conflict")
+	})
+
+	t.Run("should render 404 for deleting what does not exist", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("DELETE", "/square/county/north", "").Code, "request should respond with 200 OK")
+		assert.Equal(t, http.StatusNotFound, request("DELETE", "/square/county/north", "").Code, "request should respond with 404 Not Found")
+		assert.Equal(t, http.StatusNotFound, request("DELETE", "/missing/county/north", "").Code, "request should respond with 404 Not Found")
+
+		rr := request("DELETE", "/sq", "")
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Empty(t, rr.Body.String(), "successful deletes should have no body")
+
+		for _, target := range []string{"/square", "/sq"} {
+			rr = request("DELETE", target, "")
+			assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+			assert.Contains(t, rr.Body.String(), "no state found", "only the error response should be written")
+			assert.Equal(t, http.StatusNotFound, request("GET", target, "").Code, "request should respond with 404 Not Found")
+		}
+	})
+}
+
+func TestStateHistoryHandler(t *testing.T) {
+	square := func(size float64) geospatial.Polygon {
+		return geospatial.Polygon{{Lng: 0, Lat: 0}, {Lng: 0, Lat: size}, {Lng: size, Lat: size}, {Lng: size, Lat: 0}, {Lng: 0, Lat: 0}}
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	moved := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testStore := mockDataProvider{
+		States: []geospatial.State{{Name: "square", Border: square(20), Version: 2}},
+		Revisions: []backend.Revision{
+			{Version: 1, Time: created, Author: "alice", Change: backend.Created, State: geospatial.State{Name: "square", Border: square(10), Version: 1}},
+			{Version: 2, Time: moved, Author: "bob", Change: backend.Updated, Fields: []string{"border"}, State: geospatial.State{Name: "square", Border: square(20), Version: 2}},
+		},
+	}
+	handler := Router(testStore)
+
+	t.Run("should render every change to the state, oldest first", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/square/history", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+
+		var history HistoryResponse
+		err = json.NewDecoder(rr.Body).Decode(&history)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.Equal(t, "square", history.State)
+		assert.Equal(t, 2, len(history.Revisions), "history should have a revision for each change")
+		assert.Equal(t, "created", history.Revisions[0].Change)
+		assert.Equal(t, "alice", history.Revisions[0].Author)
+		assert.Equal(t, created, history.Revisions[0].Time)
+		assert.Equal(t, "updated", history.Revisions[1].Change)
+		assert.Equal(t, []string{"border"}, history.Revisions[1].Fields, "history should list the fields which changed")
+		assert.Equal(t, "square", history.Revisions[1].State.Properties.State, "revisions should render the state as a GeoJSON feature")
+	})
+
+	t.Run("should respond with 404 for a state with no history", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/nunavut/history", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, "request should respond with 404 Not Found")
+	})
+
+	t.Run("should render the state as it was at the asOf time", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/square?asOf=2024-03-01T00:00:00Z", nil)
+		assert.Nil(t, err, "should generate valid http request")
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "request should respond with 200 OK")
+		assert.Equal(t, `"1"`, rr.Header().Get("ETag"), "ETag should be the version of the state at the asOf time")
+
+		var feature api.Feature
+		err = json.NewDecoder(rr.Body).Decode(&feature)
+		assert.Nilf(t, err, "should be able to decode response json, got error: %s", err)
+		assert.ElementsMatch(t, square(10), feature.Geometry.Coordinates[0], "state should have its border from before the update")
+	})
+
+	t.Run("should respond with 404 before the state was created and 400 for an invalid asOf", func(t *testing.T) {
+		for query, status := range map[string]int{"asOf=2023-01-01T00:00:00Z": http.StatusNotFound, "asOf=2024-01-01": http.StatusBadRequest} {
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/square?"+query, nil)
+			assert.Nil(t, err, "should generate valid http request")
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equalf(t, status, rr.Code, "unexpected response status for %s", query)
+		}
+	})
+}
@@ -0,0 +1,325 @@
+package states
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aaronireland/state-server/pkg/geospatial"
+)
+
+// Media types of the patch documents accepted by the PATCH /api/v1/state/{name} endpoint.
+// Plain JSON documents are treated as merge patches
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+)
+
+// Error returned when a PATCH request body is not one of the supported patch documents
+type UnsupportedPatchError struct {
+	MediaType string
+}
+
+func (e *UnsupportedPatchError) Error() string {
+	return fmt.Sprintf("unsupported patch media type: %s, expecting %s or %s", e.MediaType, mergePatchMediaType, jsonPatchMediaType)
+}
+
+// A single operation of a JSON Patch (RFC 6902) document
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Applies the patch document to the state in the same JSON representation used to create it, so that the
+// properties, aliases and border vertices of the state can be changed, e.g. with a JSON Patch operation on
+// /border/3 or a merge patch of {"properties": {"population": 13002700}}. The patch is applied to a copy of
+// the state and the patched state is only returned if every operation in the patch succeeds
+func patchState(state geospatial.State, contentType string, patch []byte) (geospatial.State, error) {
+	mediaType := mergePatchMediaType
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return geospatial.State{}, &UnsupportedPatchError{contentType}
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return geospatial.State{}, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return geospatial.State{}, err
+	}
+
+	switch mediaType {
+	case mergePatchMediaType, "application/json":
+		var merge interface{}
+		if err := json.Unmarshal(patch, &merge); err != nil {
+			return geospatial.State{}, fmt.Errorf("invalid merge patch: %w", err)
+		}
+		doc = applyMergePatch(doc, merge)
+	case jsonPatchMediaType:
+		var operations []patchOperation
+		if err := json.Unmarshal(patch, &operations); err != nil {
+			return geospatial.State{}, fmt.Errorf("invalid json patch: %w", err)
+		}
+		if doc, err = applyJSONPatch(doc, operations); err != nil {
+			return geospatial.State{}, err
+		}
+	default:
+		return geospatial.State{}, &UnsupportedPatchError{mediaType}
+	}
+
+	if data, err = json.Marshal(doc); err != nil {
+		return geospatial.State{}, err
+	}
+	patched := CreateStateRequest{}
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return geospatial.State{}, fmt.Errorf("invalid patched state: %w", err)
+	}
+	return geospatial.State(patched), nil
+}
+
+// Applies a JSON Merge Patch (RFC 7386) to the target document. Members of a patch object
+// which are null are removed from the target, and anything which is not an object replaces it
+func applyMergePatch(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = applyMergePatch(object[name], value)
+		}
+	}
+	return object
+}
+
+// Applies the operations of a JSON Patch (RFC 6902) to the document in order,
+// stopping at the first operation which fails
+func applyJSONPatch(doc interface{}, operations []patchOperation) (interface{}, error) {
+	for i, operation := range operations {
+		var err error
+		if doc, err = operation.apply(doc); err != nil {
+			return nil, fmt.Errorf("invalid json patch: operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+func (op patchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%s requires a value", op.Op)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return addValue(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, err = removeValue(doc, path); err != nil {
+				return nil, err
+			}
+			return addValue(doc, path, value)
+		default:
+			current, err := getValue(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("test failed: %s does not have the given value", op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		return removeValue(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			if doc, err = removeValue(doc, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = copyValue(value); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unknown operation: %q", op.Op)
+	}
+}
+
+// Splits a JSON Pointer (RFC 6901) into its reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path: %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Parses a reference token as an index into an array of the given length. The
+// index may equal the length when adding a value to the end of the array
+func arrayIndex(token string, length int, adding bool) (int, error) {
+	if adding && token == "-" {
+		return length, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index: %q", token)
+	}
+	if i > length || (i == length && !adding) {
+		return 0, fmt.Errorf("array index out of range: %d", i)
+	}
+	return i, nil
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found: %s", token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("path not found: %s", token)
+		}
+	}
+	return doc, nil
+}
+
+// Replaces the container at the parent of the path with the result of calling change with the container
+// and the last token of the path, returning the document with the changed container
+func changeParent(doc interface{}, path []string, change func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+
+	child, err := getValue(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = changeParent(child, path[1:], change); err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+	case []interface{}:
+		i, _ := strconv.Atoi(path[0])
+		node[i] = child
+	}
+	return doc, nil
+}
+
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return changeParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("path not found: %s", token)
+		}
+	})
+}
+
+func removeValue(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole state")
+	}
+
+	return changeParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("path not found: %s", token)
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("path not found: %s", token)
+		}
+	})
+}
+
+// Copies a value so that later operations on the copy do not change the original
+func copyValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
@@ -0,0 +1,60 @@
+package states
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPatch(t *testing.T) {
+	decode := func(data string) (v interface{}) {
+		assert.Nil(t, json.Unmarshal([]byte(data), &v), "test json should be valid")
+		return
+	}
+	doc := `{"a": {"b": 1, "c/d": 2, "e~f": 3}, "list": [1, 2, 3]}`
+
+	t.Run("should apply merge patches", func(t *testing.T) {
+		patched := applyMergePatch(decode(doc), decode(`{"a": {"b": null, "g": {"h": true}}, "list": [4]}`))
+		assert.Equal(t, decode(`{"a": {"c/d": 2, "e~f": 3, "g": {"h": true}}, "list": [4]}`), patched)
+	})
+
+	t.Run("should apply json patch operations", func(t *testing.T) {
+		for patch, want := range map[string]string{
+			`[{"op": "add", "path": "/list/1", "value": 9}]`:                        `{"a": {"b": 1, "c/d": 2, "e~f": 3}, "list": [1, 9, 2, 3]}`,
+			`[{"op": "add", "path": "/a/b", "value": null}]`:                        `{"a": {"b": null, "c/d": 2, "e~f": 3}, "list": [1, 2, 3]}`,
+			`[{"op": "remove", "path": "/a/c~1d"}]`:                                 `{"a": {"b": 1, "e~f": 3}, "list": [1, 2, 3]}`,
+			`[{"op": "replace", "path": "/a/e~0f", "value": [4]}]`:                  `{"a": {"b": 1, "c/d": 2, "e~f": [4]}, "list": [1, 2, 3]}`,
+			`[{"op": "move", "from": "/list/0", "path": "/list/-"}]`:                `{"a": {"b": 1, "c/d": 2, "e~f": 3}, "list": [2, 3, 1]}`,
+			`[{"op": "copy", "from": "/list", "path": "/a/list"}]`:                  `{"a": {"b": 1, "c/d": 2, "e~f": 3, "list": [1, 2, 3]}, "list": [1, 2, 3]}`,
+			`[{"op": "test", "path": "/a", "value": {"b": 1, "c/d": 2, "e~f": 3}}]`: doc,
+		} {
+			var operations []patchOperation
+			assert.Nil(t, json.Unmarshal([]byte(patch), &operations), "test patch should be valid")
+
+			patched, err := applyJSONPatch(decode(doc), operations)
+			assert.Nilf(t, err, "patch %s should be applied", patch)
+			assert.Equal(t, decode(want), patched, patch)
+		}
+	})
+
+	t.Run("should fail without applying any operation", func(t *testing.T) {
+		for _, patch := range []string{
+			`[{"op": "add", "path": "/list/4", "value": 1}]`,
+			`[{"op": "add", "path": "/list/01", "value": 1}]`,
+			`[{"op": "add", "path": "/missing/b", "value": 1}]`,
+			`[{"op": "add", "path": "/a/b"}]`,
+			`[{"op": "remove", "path": "/a/missing"}]`,
+			`[{"op": "replace", "path": "list/0", "value": 1}]`,
+			`[{"op": "move", "from": "/a", "path": "/a/b"}]`,
+			`[{"op": "remove", "path": "/list/0"}, {"op": "test", "path": "/list/0", "value": 1}]`,
+			`[{"op": "rename", "path": "/a"}]`,
+		} {
+			var operations []patchOperation
+			assert.Nil(t, json.Unmarshal([]byte(patch), &operations), "test patch should be valid")
+
+			_, err := applyJSONPatch(decode(doc), operations)
+			assert.NotNilf(t, err, "patch %s should fail", patch)
+		}
+	})
+}
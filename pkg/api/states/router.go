@@ -3,18 +3,31 @@
 package states
 
 import (
+	"context"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 
+	"github.com/aaronireland/state-server/pkg/api/backend"
 	"github.com/aaronireland/state-server/pkg/geospatial"
 )
 
 // Injects the dependcies required by the handler for the
 // backend data store.
 type DataProvider interface {
-	GetAll() ([]geospatial.State, error)
-	GetByName(name string) (geospatial.State, error)
-	Create(geospatial.State) (geospatial.State, error)
-	Delete(name string) error
+	GetAll(ctx context.Context) ([]geospatial.State, error)
+	GetByName(ctx context.Context, name string) (geospatial.State, error)
+	GetByNameAsOf(ctx context.Context, name string, at time.Time) (geospatial.State, error)
+	GetHistory(ctx context.Context, name string) ([]backend.Revision, error)
+	Search(ctx context.Context, query string, limit int) ([]backend.SearchMatch, error)
+	Create(ctx context.Context, state geospatial.State) (geospatial.State, error)
+	Update(ctx context.Context, name string, version uint64, state geospatial.State) (geospatial.State, error)
+	Delete(ctx context.Context, name string, version uint64) error
+	GetNeighbors(ctx context.Context, name string) ([]geospatial.Neighbor, error)
+	GetBoundary(ctx context.Context, path []string) (geospatial.Boundary, error)
+	GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error)
+	CreateBoundary(ctx context.Context, parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error)
+	DeleteBoundary(ctx context.Context, path []string) error
 }
 
 // Maps the handler to the REST API endpoints for the state API
@@ -24,8 +37,24 @@ func Router(store DataProvider) chi.Router {
 
 	router.Get("/", handler.ListStates)
 	router.Post("/", handler.CreateState)
+	router.Get("/search", handler.SearchStates)
 	router.Get("/{name}", handler.GetState)
+	router.Get("/{name}/neighbors", handler.GetNeighbors)
+	router.Get("/{name}/history", handler.GetHistory)
+	router.Put("/{name}", handler.ReplaceState)
+	router.Patch("/{name}", handler.PatchState)
 	router.Delete("/{name}", handler.DeleteState)
 
+	router.Route("/{name}/county", func(r chi.Router) {
+		r.Get("/", handler.ListBoundaries)
+		r.Post("/", handler.CreateBoundary)
+		r.Get("/{county}", handler.GetBoundary)
+		r.Delete("/{county}", handler.DeleteBoundary)
+		r.Get("/{county}/place", handler.ListBoundaries)
+		r.Post("/{county}/place", handler.CreateBoundary)
+		r.Get("/{county}/place/{place}", handler.GetBoundary)
+		r.Delete("/{county}/place/{place}", handler.DeleteBoundary)
+	})
+
 	return router
 }
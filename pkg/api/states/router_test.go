@@ -51,6 +51,17 @@ func TestStatesRouter(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode, "router should give a 200 OK response")
 	})
 
+	t.Run("get neighbors", func(t *testing.T) {
+		testServer := httptest.NewServer(testRouter)
+		defer testServer.Close()
+
+		req, err := http.NewRequest("GET", testServer.URL+"/square/neighbors", nil)
+		assert.Nil(t, err, "should be a valid request")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err, "router should handle GET for state neighbors")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "router should give a 200 OK response")
+	})
+
 	t.Run("invalid request", func(t *testing.T) {
 		testServer := httptest.NewServer(testRouter)
 		defer testServer.Close()
@@ -4,22 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/aaronireland/state-server/pkg/api"
+	"github.com/aaronireland/state-server/pkg/api/backend"
 	"github.com/aaronireland/state-server/pkg/geospatial"
 )
 
 // Translates the [geospatial.State] object from the [geospatial] package into a GeoJSON feature
 func NewStateResponse(state geospatial.State) api.Feature {
-	return api.Feature{
-		Type: "Feature",
-		Geometry: api.Geometry{
-			Type:        "Polygon",
-			Coordinates: [][]geospatial.Coordinate{state.Border},
-		},
-		Properties: api.Properties{State: state.Name},
-	}
+	return api.NewFeature(state)
 }
 
 // Adds the Bind method to the [geospatial.State] object to hook into the go-chi renderer
@@ -27,8 +26,11 @@ type CreateStateRequest geospatial.State
 
 func (csr *CreateStateRequest) UnmarshalJSON(data []byte) error {
 	required := struct {
-		Name   *string             `json:"state"`
-		Border *geospatial.Polygon `json:"border"`
+		Name       *string                `json:"state"`
+		Border     *geospatial.Polygon    `json:"border"`
+		Country    string                 `json:"country"`
+		Aliases    []string               `json:"aliases"`
+		Properties map[string]interface{} `json:"properties"`
 	}{}
 
 	if err := json.Unmarshal(data, &required); err != nil {
@@ -47,6 +49,9 @@ func (csr *CreateStateRequest) UnmarshalJSON(data []byte) error {
 	}
 	csr.Name = *required.Name
 	csr.Border = *required.Border
+	csr.Country = required.Country
+	csr.Aliases = required.Aliases
+	csr.Properties = required.Properties
 
 	return nil
 }
@@ -55,10 +60,327 @@ func (sr *CreateStateRequest) Bind(r *http.Request) error {
 	return nil
 }
 
-// Translates an array of [geo.State] objects into a GeoJSON FeatureCollection
-func NewStateCollectionResponse(features []api.Feature) api.FeatureCollection {
-	return api.FeatureCollection{
+// GeoJSON schema for the properties object of a boundary feature. Path holds the
+// names of the boundaries above the boundary, starting with the state
+type BoundaryProperties struct {
+	Name  string           `json:"name"`
+	Level geospatial.Level `json:"level"`
+	Path  []string         `json:"path"`
+}
+
+// GeoJSON schema for a feature object representing a county or place within a state
+type BoundaryFeature struct {
+	Type       string             `json:"type"`
+	Properties BoundaryProperties `json:"properties"`
+	Geometry   api.Geometry       `json:"geometry"`
+}
+
+// GeoJSON schema for a FeatureCollection object to represent the boundaries beneath a state or county
+type BoundaryCollection struct {
+	Type     string            `json:"type"`
+	Features []BoundaryFeature `json:"features"`
+}
+
+// render method which hooks into the go-chi renderer
+func (bf BoundaryFeature) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (bc BoundaryCollection) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Translates the [geospatial.Boundary] object from the [geospatial] package into a GeoJSON feature
+func NewBoundaryResponse(boundary geospatial.Boundary) BoundaryFeature {
+	return BoundaryFeature{
+		Type:       "Feature",
+		Properties: BoundaryProperties{Name: boundary.Name, Level: boundary.Level, Path: boundary.Path},
+		Geometry:   api.NewGeometry(boundary.Border.MultiPolygon()),
+	}
+}
+
+// Translates an array of [geospatial.Boundary] objects into a GeoJSON FeatureCollection
+func NewBoundaryCollectionResponse(boundaries []geospatial.Boundary) BoundaryCollection {
+	features := []BoundaryFeature{}
+	for _, boundary := range boundaries {
+		features = append(features, NewBoundaryResponse(boundary))
+	}
+	return BoundaryCollection{
 		Type:     "FeatureCollection",
 		Features: features,
 	}
 }
+
+// Adds the Bind method to the [geospatial.Boundary] object to hook into the go-chi renderer.
+// The level of the boundary is set by the data store from the level of its parent
+type CreateBoundaryRequest geospatial.Boundary
+
+func (cbr *CreateBoundaryRequest) UnmarshalJSON(data []byte) error {
+	required := struct {
+		Name   *string             `json:"name"`
+		Border *geospatial.Polygon `json:"border"`
+	}{}
+
+	if err := json.Unmarshal(data, &required); err != nil {
+		return err
+	} else {
+		var missing []string
+		if required.Name == nil {
+			missing = append(missing, "name is required")
+		}
+		if required.Border == nil {
+			missing = append(missing, "border is required")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("invalid json: %s", strings.Join(missing, ", "))
+		}
+	}
+	cbr.Name = *required.Name
+	cbr.Border = *required.Border
+
+	return nil
+}
+
+func (cbr *CreateBoundaryRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Gets the path through the hierarchy of geographies given by the state, county
+// and place URL parameters of a request, stopping at the first parameter not given
+func boundaryPath(r *http.Request) (path []string) {
+	for _, param := range []string{"name", "county", "place"} {
+		name := chi.URLParam(r, param)
+		if name == "" {
+			break
+		}
+		path = append(path, name)
+	}
+	return
+}
+
+// The default and largest number of results returned by a search
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+)
+
+// A single state found by a search along with the name or alias which matched the query, how it
+// matched (exact, prefix, substring or fuzzy) and the number of typos for fuzzy matches
+type SearchResult struct {
+	State    string `json:"state"`
+	Matched  string `json:"matched"`
+	Match    string `json:"match"`
+	Distance int    `json:"distance"`
+}
+
+// The states found by a search, best match first
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+func (sr SearchResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Translates the matches found by the data store into a search response
+func NewSearchResponse(query string, matches []backend.SearchMatch) SearchResponse {
+	results := make([]SearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, SearchResult{
+			State:    match.State,
+			Matched:  match.Matched,
+			Match:    match.Kind.String(),
+			Distance: match.Distance,
+		})
+	}
+	return SearchResponse{Query: query, Results: results}
+}
+
+// Parses the required q and optional limit query parameters of a search request
+func parseSearchQuery(params url.Values) (query string, limit int, err error) {
+	query = strings.TrimSpace(params.Get("q"))
+	if query == "" {
+		return "", 0, fmt.Errorf("q is required")
+	}
+
+	limit = defaultSearchLimit
+	if params.Has("limit") {
+		limit, err = strconv.Atoi(params.Get("limit"))
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return "", 0, fmt.Errorf("invalid limit: %s, expecting a number from 1 to %d", params.Get("limit"), maxSearchLimit)
+		}
+	}
+	return query, limit, nil
+}
+
+// The states which share part of their border with a state
+type NeighborsResponse struct {
+	State     string                `json:"state"`
+	Neighbors []geospatial.Neighbor `json:"neighbors"`
+}
+
+func (nr NeighborsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// A single change made to a state, with the state as it was after the change, or as it was when it was
+// deleted, rendered as a GeoJSON feature
+type RevisionResponse struct {
+	Version  uint64      `json:"version"`
+	Time     time.Time   `json:"time"`
+	Author   string      `json:"author,omitempty"`
+	Change   string      `json:"change"`
+	Previous string      `json:"previous,omitempty"`
+	Fields   []string    `json:"fields,omitempty"`
+	State    api.Feature `json:"state"`
+}
+
+// Every change made to a state, oldest first
+type HistoryResponse struct {
+	State     string             `json:"state"`
+	Revisions []RevisionResponse `json:"revisions"`
+}
+
+func (hr HistoryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Translates the revisions recorded by the data store into a history response
+func NewHistoryResponse(name string, revisions []backend.Revision) HistoryResponse {
+	response := HistoryResponse{State: name, Revisions: make([]RevisionResponse, 0, len(revisions))}
+	for _, revision := range revisions {
+		response.Revisions = append(response.Revisions, RevisionResponse{
+			Version:  revision.Version,
+			Time:     revision.Time,
+			Author:   revision.Author,
+			Change:   string(revision.Change),
+			Previous: revision.Previous,
+			Fields:   revision.Fields,
+			State:    NewStateResponse(revision.State),
+		})
+	}
+	if len(revisions) > 0 {
+		response.State = revisions[len(revisions)-1].State.Name
+	}
+	return response
+}
+
+// Translates an array of [geo.State] objects into a GeoJSON FeatureCollection
+func NewStateCollectionResponse(features []api.Feature) api.FeatureCollection {
+	return api.NewFeatureCollection(features)
+}
+
+// The prefix of the query parameters which filter a list of states by their properties,
+// e.g. properties.abbreviation=PA
+const propertyFilterPrefix = "properties."
+
+// The property values which a state must have to be included in a list of states. A state
+// matches if, for every filtered property, its value is equal to any of the given values
+type propertyFilter map[string][]string
+
+// Parses the query parameters of a request to list states which filter the states by their properties
+func parsePropertyFilter(params url.Values) (propertyFilter, error) {
+	filter := propertyFilter{}
+	for param, values := range params {
+		if !strings.HasPrefix(param, propertyFilterPrefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(param, propertyFilterPrefix)
+		if key == "" {
+			return nil, fmt.Errorf("invalid property filter: %s must be followed by a property name", param)
+		}
+		filter[key] = values
+	}
+	return filter, nil
+}
+
+// Checks whether the state has one of the given values for every filtered property. The state
+// and aliases properties filter by the name of the state and by any of its aliases
+func (f propertyFilter) matches(state geospatial.State) bool {
+	for key, values := range f {
+		var candidates []interface{}
+		switch key {
+		case "state":
+			candidates = []interface{}{state.Name}
+		case "aliases":
+			for _, alias := range state.Aliases {
+				candidates = append(candidates, alias)
+			}
+		default:
+			if value, ok := state.Properties[key]; ok {
+				candidates = []interface{}{value}
+			}
+		}
+
+		if !anyPropertyEquals(candidates, values) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks whether any of the property values is equal to any of the wanted values
+func anyPropertyEquals(candidates []interface{}, values []string) bool {
+	for _, candidate := range candidates {
+		for _, want := range values {
+			if propertyEquals(candidate, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Compares a property value decoded from JSON with a value given as a query parameter. Strings are
+// compared as they are, numbers and booleans are compared by value and anything else is compared
+// by its JSON encoding
+func propertyEquals(value interface{}, want string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == want
+	case float64:
+		f, err := strconv.ParseFloat(want, 64)
+		return err == nil && f == v
+	case bool:
+		b, err := strconv.ParseBool(want)
+		return err == nil && b == v
+	default:
+		encoded, err := json.Marshal(v)
+		return err == nil && string(encoded) == want
+	}
+}
+
+// Parses the optional bbox and clip query parameters of a request to list states. The bounding box is
+// given as minLng,minLat,maxLng,maxLat and clip is a boolean. Returns nil if no bounding box was given
+func parseViewQuery(params url.Values) (view *geospatial.BoundingBox, clip bool, err error) {
+	if params.Has("clip") {
+		if clip, err = strconv.ParseBool(params.Get("clip")); err != nil {
+			return nil, false, fmt.Errorf("invalid clip: %s", params.Get("clip"))
+		}
+	}
+
+	if !params.Has("bbox") {
+		return nil, clip, nil
+	}
+
+	corners := strings.Split(params.Get("bbox"), ",")
+	if len(corners) != 4 {
+		return nil, false, fmt.Errorf("invalid bbox: expecting minLng,minLat,maxLng,maxLat")
+	}
+
+	var values [4]float64
+	for i, corner := range corners {
+		if values[i], err = strconv.ParseFloat(strings.TrimSpace(corner), 64); err != nil {
+			return nil, false, fmt.Errorf("invalid bbox: %s is not a number", corner)
+		}
+	}
+
+	box, err := geospatial.NewBoundingBox(values[0], values[1], values[2], values[3])
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid bbox: %w", err)
+	}
+
+	return &box, clip, nil
+}
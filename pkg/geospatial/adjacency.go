@@ -0,0 +1,151 @@
+package geospatial
+
+import "math"
+
+// Measures the length in kilometers of the border which two polygons share, counting
+// the sections of their edges which run alongside each other no further apart than the
+// tolerance (in degrees). Borders which are only approximated rarely line up exactly,
+// so a tolerance allows neighbouring polygons to be recognised as such
+func SharedBorder(a, b Polygon, tolerance float64) float64 {
+	if !a.Bounds().Expand(tolerance).Intersects(b.Bounds()) {
+		return 0
+	}
+
+	// the sections are measured along each polygon's edges in turn so
+	// that the result is the same regardless of the order of the polygons
+	return (alongside(a, b, tolerance) + alongside(b, a, tolerance)) / 2
+}
+
+// The total length of the sections of the first polygon's edges which run
+// alongside the second polygon's edges
+func alongside(a, b Polygon, tolerance float64) (length float64) {
+	for i := 1; i < len(a); i++ {
+		ea := edge{a[i-1], a[i]}
+		for j := 1; j < len(b); j++ {
+			if start, end, ok := sharedSection(ea, edge{b[j-1], b[j]}, tolerance); ok {
+				length += start.DistanceTo(end)
+			}
+		}
+	}
+	return
+}
+
+// Finds the section of the first edge which runs alongside the second edge,
+// with both ends of the section within the tolerance of the second edge
+func sharedSection(a, b edge, tolerance float64) (Coordinate, Coordinate, bool) {
+	d := Coordinate{a.p2.Lng - a.p1.Lng, a.p2.Lat - a.p1.Lat}
+	lengthSquared := d.Lng*d.Lng + d.Lat*d.Lat
+	if lengthSquared == 0 {
+		return Coordinate{}, Coordinate{}, false
+	}
+
+	project := func(p Coordinate) float64 {
+		return ((p.Lng-a.p1.Lng)*d.Lng + (p.Lat-a.p1.Lat)*d.Lat) / lengthSquared
+	}
+
+	low, high := project(b.p1), project(b.p2)
+	if low > high {
+		low, high = high, low
+	}
+	low, high = math.Max(low, 0), math.Min(high, 1)
+	if high-low <= epsilon {
+		return Coordinate{}, Coordinate{}, false
+	}
+
+	start, end := a.at(low), a.at(high)
+	if b.distanceTo(start) > tolerance || b.distanceTo(end) > tolerance {
+		return Coordinate{}, Coordinate{}, false
+	}
+	return start, end, true
+}
+
+// The sine of the largest angle between two edges for which the space
+// between them is considered to be a gap in a shared border
+const maxBorderAngle = 0.5
+
+// Finds the slivers between two polygons where their borders run alongside each other within
+// the tolerance (in degrees) but do not meet, leaving an area which lies within neither polygon
+func BorderGaps(a, b Polygon, tolerance float64) (gaps MultiPolygon) {
+	if !a.Bounds().Expand(tolerance).Intersects(b.Bounds()) {
+		return nil
+	}
+
+	for i := 1; i < len(a); i++ {
+		ea := edge{a[i-1], a[i]}
+		for j := 1; j < len(b); j++ {
+			eb := edge{b[j-1], b[j]}
+			start, end, ok := sharedSection(ea, eb, tolerance)
+			if !ok || !parallel(ea, eb) {
+				continue
+			}
+
+			for _, sliver := range sliversBetween(edge{start, end}, edge{eb.closest(start), eb.closest(end)}) {
+				if math.Abs(signedArea(sliver)) <= minRingArea {
+					continue
+				}
+				center := sliver.centroid()
+				if !a.Contains(center) && !b.Contains(center) {
+					gaps = append(gaps, []Polygon{sliver.clockwise()})
+				}
+			}
+		}
+	}
+	return
+}
+
+// Checks whether the edges point in roughly the same, or opposite, direction. Edges which
+// meet at a corner can come within the tolerance of each other without running alongside
+// each other, and the space between them is part of neither border
+func parallel(a, b edge) bool {
+	da := Coordinate{a.p2.Lng - a.p1.Lng, a.p2.Lat - a.p1.Lat}
+	db := Coordinate{b.p2.Lng - b.p1.Lng, b.p2.Lat - b.p1.Lat}
+	return math.Abs(cross(da, db)) <= maxBorderAngle*math.Hypot(da.Lng, da.Lat)*math.Hypot(db.Lng, db.Lat)
+}
+
+// The rings enclosed between two roughly parallel edges, split in two where the edges cross
+func sliversBetween(a, b edge) []Polygon {
+	if t, ok := edgeIntersection(a, b); ok && t > epsilon && t < 1-epsilon {
+		crossing := a.at(t)
+		return []Polygon{
+			{a.p1, crossing, b.p1, a.p1},
+			{crossing, a.p2, b.p2, crossing},
+		}
+	}
+	return []Polygon{{a.p1, a.p2, b.p2, b.p1, a.p1}}
+}
+
+// The average of the ring's vertices
+func (p Polygon) centroid() (center Coordinate) {
+	for _, coord := range p[:len(p)-1] {
+		center.Lng += coord.Lng
+		center.Lat += coord.Lat
+	}
+	n := float64(len(p) - 1)
+	return Coordinate{center.Lng / n, center.Lat / n}
+}
+
+// The ring oriented clockwise following the conventions of [Polygon]
+func (p Polygon) clockwise() Polygon {
+	if signedArea(p) > 0 {
+		return reversed(p)
+	}
+	return p
+}
+
+// The closest point on the edge to the coordinate
+func (e edge) closest(p Coordinate) Coordinate {
+	d := Coordinate{e.p2.Lng - e.p1.Lng, e.p2.Lat - e.p1.Lat}
+	lengthSquared := d.Lng*d.Lng + d.Lat*d.Lat
+	if lengthSquared == 0 {
+		return e.p1
+	}
+
+	t := ((p.Lng-e.p1.Lng)*d.Lng + (p.Lat-e.p1.Lat)*d.Lat) / lengthSquared
+	return e.at(math.Max(0, math.Min(1, t)))
+}
+
+// The planar distance in degrees from the coordinate to the closest point on the edge
+func (e edge) distanceTo(p Coordinate) float64 {
+	closest := e.closest(p)
+	return math.Hypot(p.Lng-closest.Lng, p.Lat-closest.Lat)
+}
@@ -0,0 +1,66 @@
+package geospatial
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedBorder(t *testing.T) {
+	t.Run("should measure the border shared by adjacent polygons", func(t *testing.T) {
+		west, east := square(0, 0, 1), square(1, 0, 1)
+		expected := Coordinate{1, 0}.DistanceTo(Coordinate{1, 1})
+
+		assert.InDelta(t, expected, SharedBorder(west, east, 0), 1e-9, "squares share an entire edge")
+		assert.InDelta(t, expected, SharedBorder(east, west, 0), 1e-9, "shared border should not depend on the order of the polygons")
+	})
+
+	t.Run("should measure partially shared edges", func(t *testing.T) {
+		west, east := square(0, 0, 2), square(2, 1, 2)
+		expected := Coordinate{2, 1}.DistanceTo(Coordinate{2, 2})
+
+		assert.InDelta(t, expected, SharedBorder(west, east, 0), 1e-9, "squares share half of an edge")
+	})
+
+	t.Run("should allow for roughly approximated borders", func(t *testing.T) {
+		west := square(0, 0, 1)
+		east := Polygon{{1.01, 0}, {1.01, 1}, {2, 1}, {2, 0}, {1.01, 0}}
+
+		assert.Zero(t, SharedBorder(west, east, 0), "borders are not exactly aligned")
+		assert.Greater(t, SharedBorder(west, east, 0.05), float64(100), "borders are aligned within the tolerance")
+	})
+
+	t.Run("should not count polygons which only meet at a corner or are apart", func(t *testing.T) {
+		assert.Zero(t, SharedBorder(square(0, 0, 1), square(1, 1, 1), 0), "squares only share a corner")
+		assert.Zero(t, SharedBorder(square(0, 0, 1), square(5, 5, 1), 0.05), "squares are far apart")
+		assert.Zero(t, SharedBorder(square(0, 0, 1), square(1.5, 0, 1), 0.05), "squares are further apart than the tolerance")
+	})
+}
+
+func TestBorderGaps(t *testing.T) {
+	west := square(0, 0, 1)
+
+	t.Run("should find the sliver between roughly aligned borders", func(t *testing.T) {
+		east := Polygon{{1.02, 0}, {1.02, 1}, {2, 1}, {2, 0}, {1.02, 0}}
+		got := BorderGaps(west, east, 0.05)
+
+		assert.Equal(t, 1, len(got), "expect a single gap")
+		assert.InDeltaSlice(t, []float64{0.02}, planarAreas(got), 1e-9, "expect the 0.02x1 strip between the squares")
+		assert.Less(t, signedArea(got[0][0]), float64(0), "gap should be oriented clockwise")
+		assert.False(t, west.Contains(Coordinate{1.01, 0.5}) || east.Contains(Coordinate{1.01, 0.5}), "gap lies in neither polygon")
+	})
+
+	t.Run("should only find the part of a sliver outside both polygons where borders cross", func(t *testing.T) {
+		east := Polygon{{1.02, 0}, {0.98, 1}, {2, 1}, {2, 0}, {1.02, 0}}
+		got := BorderGaps(west, east, 0.05)
+
+		assert.Equal(t, 1, len(got), "the other half of the sliver is an overlap rather than a gap")
+		assert.InDeltaSlice(t, []float64{0.005}, planarAreas(got), 1e-4, "expect the triangle below the crossing")
+	})
+
+	t.Run("should not find gaps between touching, overlapping or distant polygons", func(t *testing.T) {
+		assert.Empty(t, BorderGaps(west, square(1, 0, 1), 0.05), "squares share an entire edge")
+		assert.Empty(t, BorderGaps(west, square(0.98, 0, 1), 0.05), "squares overlap")
+		assert.Empty(t, BorderGaps(west, Polygon{{1.02, 0}, {1.02, 1}, {2, 1}, {2, 0}, {1.02, 0}}, 0.01), "squares are further apart than the tolerance")
+	})
+}
@@ -0,0 +1,59 @@
+package geospatial
+
+import "fmt"
+
+// The level of a [Boundary] within the hierarchy of geographies. Each
+// level is divided into the boundaries of the level beneath it
+type Level string
+
+const (
+	LevelCountry Level = "country"
+	LevelState   Level = "state"
+	LevelCounty  Level = "county"
+	LevelPlace   Level = "place"
+)
+
+// The level beneath this level in the hierarchy, or an empty
+// level if boundaries at this level cannot be divided further
+func (l Level) Child() Level {
+	switch l {
+	case LevelCountry:
+		return LevelState
+	case LevelState:
+		return LevelCounty
+	case LevelCounty:
+		return LevelPlace
+	default:
+		return ""
+	}
+}
+
+// Represents a named geography within a [State], such as a county or a place, as its level in the
+// hierarchy, the names of the boundaries above it starting with the state and a geospatial polygon
+type Boundary struct {
+	Name   string   `json:"name"`
+	Level  Level    `json:"level"`
+	Path   []string `json:"path"`
+	Border Polygon  `json:"border"`
+}
+
+// Constructor generates a new [Boundary] object at the given level with the provided
+// name and array of coordinates, translating the coordinates into a [Polygon]
+func NewBoundary(name string, level Level, coords []Coordinate) (boundary Boundary, err error) {
+	if len(name) < 2 {
+		err = fmt.Errorf("invalid name for %s, minimum length is 2: %s", level, name)
+		return
+	}
+
+	border, err := NewPolygon(coords)
+	if err != nil {
+		return
+	}
+	boundary = Boundary{Name: name, Level: level, Border: *border}
+	return
+}
+
+// Calls the Contains method for the [Polygon] object representing the boundary's border
+func (b Boundary) Contains(coordinate Coordinate) bool {
+	return b.Border.Contains(coordinate)
+}
@@ -0,0 +1,29 @@
+package geospatial
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundary(t *testing.T) {
+	t.Run("levels should descend from country to place", func(t *testing.T) {
+		assert.Equal(t, LevelState, LevelCountry.Child(), "countries are divided into states")
+		assert.Equal(t, LevelCounty, LevelState.Child())
+		assert.Equal(t, LevelPlace, LevelCounty.Child())
+		assert.Equal(t, Level(""), LevelPlace.Child(), "places cannot be divided further")
+	})
+
+	t.Run("should validate the name and border", func(t *testing.T) {
+		got, err := NewBoundary("Allegheny", LevelCounty, reversed(square(0, 0, 1)))
+		assert.Nil(t, err, "given ring should produce a valid boundary")
+		assert.Equal(t, LevelCounty, got.Level)
+		assert.Equal(t, square(0, 0, 1), got.Border, "counter-clockwise border should be reversed")
+		assert.True(t, got.Contains(Coordinate{0.5, 0.5}))
+
+		_, err = NewBoundary("A", LevelCounty, square(0, 0, 1))
+		assert.NotNil(t, err, "name is too short")
+		_, err = NewBoundary("Allegheny", LevelCounty, []Coordinate{{0, 0}, {1, 1}, {0, 0}})
+		assert.NotNil(t, err, "border is too short")
+	})
+}
@@ -0,0 +1,84 @@
+package geospatial
+
+import (
+	"fmt"
+	"math"
+)
+
+// An axis-aligned rectangle of longitude and latitude described
+// by its south-west (Min) and north-east (Max) corners
+type BoundingBox struct {
+	Min Coordinate
+	Max Coordinate
+}
+
+// Constructs a new bounding box from its south-west and north-east corners,
+// ensuring that the corners are valid coordinates and correctly ordered
+func NewBoundingBox(minLng, minLat, maxLng, maxLat float64) (BoundingBox, error) {
+	for _, lng := range []float64{minLng, maxLng} {
+		if math.IsNaN(lng) || lng < -180 || lng > 180 {
+			return BoundingBox{}, &InvalidGeometryError{fmt.Sprintf("invalid longitude for bounding box: %G", lng)}
+		}
+	}
+	for _, lat := range []float64{minLat, maxLat} {
+		if math.IsNaN(lat) || lat < -90 || lat > 90 {
+			return BoundingBox{}, &InvalidGeometryError{fmt.Sprintf("invalid latitude for bounding box: %G", lat)}
+		}
+	}
+	if minLng >= maxLng || minLat >= maxLat {
+		return BoundingBox{}, &InvalidGeometryError{BoxUnordered}
+	}
+
+	return BoundingBox{Min: Coordinate{minLng, minLat}, Max: Coordinate{maxLng, maxLat}}, nil
+}
+
+// Translates the box into a clockwise [Polygon]
+func (b BoundingBox) Polygon() Polygon {
+	return Polygon{
+		b.Min, {b.Min.Lng, b.Max.Lat}, b.Max, {b.Max.Lng, b.Min.Lat}, b.Min,
+	}
+}
+
+// The smallest box containing every coordinate of the polygon
+func (p Polygon) Bounds() BoundingBox {
+	if len(p) == 0 {
+		return BoundingBox{}
+	}
+
+	bounds := BoundingBox{Min: p[0], Max: p[0]}
+	for _, coord := range p[1:] {
+		bounds.Min.Lng = math.Min(bounds.Min.Lng, coord.Lng)
+		bounds.Min.Lat = math.Min(bounds.Min.Lat, coord.Lat)
+		bounds.Max.Lng = math.Max(bounds.Max.Lng, coord.Lng)
+		bounds.Max.Lat = math.Max(bounds.Max.Lat, coord.Lat)
+	}
+	return bounds
+}
+
+// The smallest box containing both boxes
+func (b BoundingBox) Extend(other BoundingBox) BoundingBox {
+	return BoundingBox{
+		Min: Coordinate{Lng: math.Min(b.Min.Lng, other.Min.Lng), Lat: math.Min(b.Min.Lat, other.Min.Lat)},
+		Max: Coordinate{Lng: math.Max(b.Max.Lng, other.Max.Lng), Lat: math.Max(b.Max.Lat, other.Max.Lat)},
+	}
+}
+
+// Grows the box by the given number of degrees in every direction
+func (b BoundingBox) Expand(degrees float64) BoundingBox {
+	return BoundingBox{
+		Min: Coordinate{Lng: b.Min.Lng - degrees, Lat: b.Min.Lat - degrees},
+		Max: Coordinate{Lng: b.Max.Lng + degrees, Lat: b.Max.Lat + degrees},
+	}
+}
+
+// Checks whether the two boxes share any area or boundary
+func (b BoundingBox) Intersects(other BoundingBox) bool {
+	return b.Min.Lng <= other.Max.Lng && other.Min.Lng <= b.Max.Lng &&
+		b.Min.Lat <= other.Max.Lat && other.Min.Lat <= b.Max.Lat
+}
+
+// Checks whether the coordinate lies within or on the boundary of the box
+func (b BoundingBox) Contains(coord Coordinate) bool {
+	return b.Min.Lng <= coord.Lng && coord.Lng <= b.Max.Lng &&
+		b.Min.Lat <= coord.Lat && coord.Lat <= b.Max.Lat
+}
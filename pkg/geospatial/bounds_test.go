@@ -0,0 +1,59 @@
+package geospatial
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundingBox(t *testing.T) {
+	t.Run("should validate the corners of the box", func(t *testing.T) {
+		box, err := NewBoundingBox(-80.5, 39.7, -74.7, 42)
+		assert.Nil(t, err, "expect valid bounding box")
+		assert.Equal(t, Coordinate{-80.5, 39.7}, box.Min, "expect south-west corner")
+		assert.Equal(t, Coordinate{-74.7, 42}, box.Max, "expect north-east corner")
+
+		invalid := [][4]float64{
+			{-74.7, 39.7, -80.5, 42},
+			{-80.5, 42, -74.7, 39.7},
+			{-80.5, 39.7, -80.5, 42},
+			{-181, 39.7, -74.7, 42},
+			{-80.5, -91, -74.7, 42},
+		}
+		for _, corners := range invalid {
+			_, err := NewBoundingBox(corners[0], corners[1], corners[2], corners[3])
+			var invalidGeoErr *InvalidGeometryError
+			assert.NotNilf(t, err, "expect invalid bounding box: %v", corners)
+			assert.Truef(t, errors.As(err, &invalidGeoErr), "expecting error to be InvalidGeometryError: %v", corners)
+		}
+	})
+
+	t.Run("should translate the box into a valid polygon", func(t *testing.T) {
+		box, err := NewBoundingBox(0, 0, 10, 5)
+		assert.Nil(t, err, "expect valid bounding box")
+
+		polygon := box.Polygon()
+		assert.Nil(t, polygon.Validate(), "expect a valid clockwise polygon")
+		assert.Equal(t, box, polygon.Bounds(), "polygon should be bounded by the box")
+	})
+
+	t.Run("should compare boxes and coordinates", func(t *testing.T) {
+		box := square(0, 0, 10).Bounds()
+		assert.Equal(t, BoundingBox{Coordinate{0, 0}, Coordinate{10, 10}}, box, "expect the square's corners")
+
+		assert.True(t, box.Contains(Coordinate{5, 5}), "box should contain its center")
+		assert.True(t, box.Contains(Coordinate{10, 0}), "box should contain its corners")
+		assert.False(t, box.Contains(Coordinate{11, 5}), "box should not contain coordinates beyond its edges")
+
+		assert.True(t, box.Intersects(square(5, 5, 10).Bounds()), "overlapping boxes intersect")
+		assert.True(t, box.Intersects(square(10, 10, 10).Bounds()), "boxes sharing a corner intersect")
+		assert.False(t, box.Intersects(square(11, 0, 10).Bounds()), "separate boxes do not intersect")
+
+		extended := box.Extend(square(20, -5, 1).Bounds())
+		assert.Equal(t, BoundingBox{Coordinate{0, -5}, Coordinate{21, 10}}, extended, "extended box should contain both boxes")
+
+		multi := MultiPolygon{{square(0, 0, 10)}, {square(20, -5, 1)}}
+		assert.Equal(t, extended, multi.Bounds(), "multipolygon should be bounded by all of its polygons")
+	})
+}
@@ -0,0 +1,419 @@
+package geospatial
+
+import (
+	"math"
+	"sort"
+)
+
+// The spacing, in degrees, of the grid onto which every vertex is snapped before
+// polygons are clipped. Snapping ensures that coordinates which are meant to be the
+// same point (e.g. vertices of a border shared by two states) compare as equal
+const clipPrecision = 1e-9
+
+// Rings which enclose less than this many square degrees are treated as slivers
+// left over from floating point error and are dropped from clipping results
+const minRingArea = 1e-12
+
+type overlayOperation int
+
+const (
+	intersection overlayOperation = iota
+	union
+	difference
+)
+
+// Finds the area shared by both polygons. The result may contain several polygons,
+// and those polygons may contain holes, so it is returned as a [MultiPolygon]
+func (p Polygon) Intersection(other Polygon) MultiPolygon {
+	return p.MultiPolygon().Intersection(other.MultiPolygon())
+}
+
+// Finds the area shared by both collections of polygons
+func (m MultiPolygon) Intersection(other MultiPolygon) MultiPolygon {
+	return overlay(m, other, intersection)
+}
+
+// Finds the area covered by either polygon. Polygons which overlap or share part of
+// their border are dissolved into a single polygon, otherwise both are kept
+func (p Polygon) Union(other Polygon) MultiPolygon {
+	return p.MultiPolygon().Union(other.MultiPolygon())
+}
+
+// Finds the area covered by either collection of polygons
+func (m MultiPolygon) Union(other MultiPolygon) MultiPolygon {
+	return overlay(m, other, union)
+}
+
+// Finds the area covered by the polygon but not by the other polygon. Removing an area
+// from within the polygon leaves a hole, so the result is returned as a [MultiPolygon]
+func (p Polygon) Difference(other Polygon) MultiPolygon {
+	return p.MultiPolygon().Difference(other.MultiPolygon())
+}
+
+// Finds the area covered by the collection of polygons but not by the other collection
+func (m MultiPolygon) Difference(other MultiPolygon) MultiPolygon {
+	return overlay(m, other, difference)
+}
+
+// Clips two collections of polygons against each other. Every edge of both
+// collections is split wherever it touches an edge of the other collection, so
+// the pieces each lie entirely inside, outside or along the boundary of the
+// other collection. The pieces are then selected according to the operation
+// and stitched back together into rings:
+//
+//   - intersection keeps the pieces of each collection inside the other
+//   - union keeps the pieces of each collection outside the other
+//   - difference keeps the pieces of the first collection outside the second and
+//     the pieces of the second inside the first, reversed to bound the removed area
+//
+// Pieces of the boundary shared by both collections are kept once when the areas
+// are on the same side of the boundary, for intersection and union, and when they
+// are on opposite sides, for difference
+func overlay(a, b MultiPolygon, op overlayOperation) MultiPolygon {
+	segmentsA, segmentsB := split(orientedSegments(a), orientedSegments(b))
+
+	var selected []segment
+	shared := make(map[segment]bool, len(segmentsB))
+	for _, s := range segmentsB {
+		shared[s] = true
+	}
+
+	for _, s := range segmentsA {
+		switch {
+		case shared[s]:
+			if op != difference {
+				selected = append(selected, s)
+			}
+		case shared[s.reverse()]:
+			if op == difference {
+				selected = append(selected, s)
+			}
+		case b.Contains(s.midpoint()):
+			if op == intersection {
+				selected = append(selected, s)
+			}
+		default:
+			if op != intersection {
+				selected = append(selected, s)
+			}
+		}
+	}
+
+	lookup := make(map[segment]bool, len(segmentsA))
+	for _, s := range segmentsA {
+		lookup[s] = true
+	}
+
+	for _, s := range segmentsB {
+		if lookup[s] || lookup[s.reverse()] {
+			continue
+		}
+
+		switch inside := a.Contains(s.midpoint()); {
+		case inside && op == intersection:
+			selected = append(selected, s)
+		case inside && op == difference:
+			selected = append(selected, s.reverse())
+		case !inside && op == union:
+			selected = append(selected, s)
+		}
+	}
+
+	return assemble(selected)
+}
+
+// A directed edge of a ring. Rings are oriented so that the area they
+// enclose is always to the left of each of their segments
+type segment struct {
+	from Coordinate
+	to   Coordinate
+}
+
+func (s segment) reverse() segment {
+	return segment{s.to, s.from}
+}
+
+func (s segment) midpoint() Coordinate {
+	return edge{s.from, s.to}.at(0.5)
+}
+
+func (s segment) direction() Coordinate {
+	return Coordinate{s.to.Lng - s.from.Lng, s.to.Lat - s.from.Lat}
+}
+
+// Breaks every ring of the polygons into segments, snapping each vertex to the
+// clipping grid and orienting exterior rings counter-clockwise and holes clockwise
+func orientedSegments(m MultiPolygon) (segments []segment) {
+	for _, polygon := range m {
+		for i, ring := range polygon {
+			vertices := make([]Coordinate, len(ring))
+			for j, coord := range ring {
+				vertices[j] = snap(coord)
+			}
+
+			counterClockwise := signedArea(vertices) > 0
+			reverse := (i == 0) != counterClockwise
+
+			for j := 1; j < len(vertices); j++ {
+				s := segment{vertices[j-1], vertices[j]}
+				if s.from == s.to {
+					continue
+				}
+				if reverse {
+					s = s.reverse()
+				}
+				segments = append(segments, s)
+			}
+		}
+	}
+	return
+}
+
+// Splits the segments of each collection at every point where they intersect or
+// touch a segment of the other collection
+func split(a, b []segment) ([]segment, []segment) {
+	cutsA := make([][]Coordinate, len(a))
+	cutsB := make([][]Coordinate, len(b))
+
+	for i, sa := range a {
+		boundsA := edgeBounds(sa)
+		for j, sb := range b {
+			if !boundsA.Intersects(edgeBounds(sb)) {
+				continue
+			}
+			for _, point := range segmentIntersections(sa, sb) {
+				cutsA[i] = append(cutsA[i], point)
+				cutsB[j] = append(cutsB[j], point)
+			}
+		}
+	}
+
+	return cut(a, cutsA), cut(b, cutsB)
+}
+
+// Finds the points at which two segments cross or touch. Collinear segments
+// which overlap touch at the endpoints of the overlapping section
+func segmentIntersections(a, b segment) []Coordinate {
+	da, db := a.direction(), b.direction()
+	lengthA, lengthB := math.Hypot(da.Lng, da.Lat), math.Hypot(db.Lng, db.Lat)
+	offset := Coordinate{b.from.Lng - a.from.Lng, b.from.Lat - a.from.Lat}
+	denominator := cross(da, db)
+
+	if math.Abs(denominator) <= 1e-12*lengthA*lengthB {
+		if math.Abs(cross(offset, da)) > clipPrecision*lengthA {
+			return nil
+		}
+
+		var points []Coordinate
+		for _, p := range []Coordinate{b.from, b.to} {
+			if onSegment(p, a) {
+				points = append(points, p)
+			}
+		}
+		for _, p := range []Coordinate{a.from, a.to} {
+			if onSegment(p, b) {
+				points = append(points, p)
+			}
+		}
+		return points
+	}
+
+	const tolerance = 1e-9
+	t := cross(offset, db) / denominator
+	u := cross(offset, da) / denominator
+	if t < -tolerance || t > 1+tolerance || u < -tolerance || u > 1+tolerance {
+		return nil
+	}
+
+	switch {
+	case u <= tolerance:
+		return []Coordinate{b.from}
+	case u >= 1-tolerance:
+		return []Coordinate{b.to}
+	case t <= tolerance:
+		return []Coordinate{a.from}
+	case t >= 1-tolerance:
+		return []Coordinate{a.to}
+	default:
+		return []Coordinate{snap(edge{a.from, a.to}.at(t))}
+	}
+}
+
+// Checks whether the point lies on the segment between, but not at, its endpoints
+func onSegment(p Coordinate, s segment) bool {
+	if p == s.from || p == s.to {
+		return false
+	}
+
+	d := s.direction()
+	length := math.Hypot(d.Lng, d.Lat)
+	offset := Coordinate{p.Lng - s.from.Lng, p.Lat - s.from.Lat}
+	if math.Abs(cross(offset, d)) > clipPrecision*length {
+		return false
+	}
+
+	t := (offset.Lng*d.Lng + offset.Lat*d.Lat) / (length * length)
+	return t > 0 && t < 1
+}
+
+// Splits each segment at its cut points, ordered by their distance from the start of the segment
+func cut(segments []segment, cuts [][]Coordinate) (pieces []segment) {
+	for i, s := range segments {
+		if len(cuts[i]) == 0 {
+			pieces = append(pieces, s)
+			continue
+		}
+
+		d := s.direction()
+		param := func(p Coordinate) float64 {
+			return (p.Lng-s.from.Lng)*d.Lng + (p.Lat-s.from.Lat)*d.Lat
+		}
+
+		points := cuts[i]
+		sort.Slice(points, func(i, j int) bool {
+			return param(points[i]) < param(points[j])
+		})
+
+		from := s.from
+		for _, point := range append(points, s.to) {
+			if point == from || point == s.from {
+				continue
+			}
+			pieces = append(pieces, segment{from, point})
+			from = point
+		}
+	}
+	return
+}
+
+// Stitches the selected segments together into closed rings. Where several segments
+// leave the same point, the ring turns as sharply clockwise as possible so that
+// rings which only touch at a point are kept apart
+func assemble(segments []segment) MultiPolygon {
+	outgoing := make(map[Coordinate][]int, len(segments))
+	for i, s := range segments {
+		outgoing[s.from] = append(outgoing[s.from], i)
+	}
+
+	used := make([]bool, len(segments))
+	var exteriors, holes [][]Coordinate
+
+	for i := range segments {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+
+		start, current := segments[i].from, segments[i]
+		ring := []Coordinate{start}
+		closed := true
+
+		for current.to != start {
+			ring = append(ring, current.to)
+
+			next := nextSegment(segments, outgoing[current.to], used, current)
+			if next < 0 {
+				closed = false
+				break
+			}
+			used[next] = true
+			current = segments[next]
+		}
+		ring = append(ring, start)
+
+		if !closed || len(ring) < 4 {
+			continue
+		}
+
+		switch area := signedArea(ring); {
+		case area > minRingArea:
+			exteriors = append(exteriors, ring)
+		case area < -minRingArea:
+			holes = append(holes, ring)
+		}
+	}
+
+	return nestHoles(exteriors, holes)
+}
+
+// Picks the unused segment which turns most sharply clockwise
+// from the reverse direction of the current segment
+func nextSegment(segments []segment, candidates []int, used []bool, current segment) int {
+	d := current.direction()
+	back := math.Atan2(-d.Lat, -d.Lng)
+
+	next, best := -1, math.Inf(1)
+	for _, j := range candidates {
+		if used[j] {
+			continue
+		}
+		dj := segments[j].direction()
+		turn := math.Mod(back-math.Atan2(dj.Lat, dj.Lng)+4*math.Pi, 2*math.Pi)
+		if turn <= 0 {
+			turn = 2 * math.Pi
+		}
+		if turn < best {
+			next, best = j, turn
+		}
+	}
+	return next
+}
+
+// Places each hole within the smallest exterior ring which contains it and orients
+// the rings following the conventions of [Polygon]: exterior rings clockwise and
+// holes counter-clockwise
+func nestHoles(exteriors, holes [][]Coordinate) MultiPolygon {
+	sort.Slice(exteriors, func(i, j int) bool {
+		return signedArea(exteriors[i]) < signedArea(exteriors[j])
+	})
+
+	result := make(MultiPolygon, len(exteriors))
+	for i, ring := range exteriors {
+		result[i] = []Polygon{reversed(ring)}
+	}
+
+	for _, hole := range holes {
+		// a point just inside the hole is inside its exterior ring but
+		// clear of any boundary the hole may share with that ring
+		d := segment{hole[0], hole[1]}.direction()
+		length := math.Hypot(d.Lng, d.Lat)
+		mid := segment{hole[0], hole[1]}.midpoint()
+		inside := Coordinate{mid.Lng + d.Lat/length*clipPrecision*10, mid.Lat - d.Lng/length*clipPrecision*10}
+
+		for i := range exteriors {
+			if result[i][0].Contains(inside) {
+				result[i] = append(result[i], reversed(hole))
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// The planar area enclosed by a closed ring, positive if the ring is counter-clockwise
+func signedArea(ring []Coordinate) (area float64) {
+	for i := 1; i < len(ring); i++ {
+		area += cross(ring[i-1], ring[i])
+	}
+	return area / 2
+}
+
+func reversed(ring []Coordinate) Polygon {
+	polygon := make(Polygon, len(ring))
+	for i, coord := range ring {
+		polygon[len(ring)-1-i] = coord
+	}
+	return polygon
+}
+
+func snap(coord Coordinate) Coordinate {
+	return Coordinate{
+		Lng: math.Round(coord.Lng/clipPrecision) * clipPrecision,
+		Lat: math.Round(coord.Lat/clipPrecision) * clipPrecision,
+	}
+}
+
+func edgeBounds(s segment) BoundingBox {
+	return Polygon{s.from, s.to}.Bounds()
+}
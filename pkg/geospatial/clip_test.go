@@ -0,0 +1,170 @@
+package geospatial
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the planar area of each polygon in square degrees, for comparing clipping results. Exterior
+// rings are clockwise and holes are counter-clockwise so their signed areas can be summed
+func planarAreas(m MultiPolygon) (areas []float64) {
+	for _, polygon := range m {
+		var area float64
+		for _, ring := range polygon {
+			area -= signedArea(ring)
+		}
+		areas = append(areas, area)
+	}
+	return
+}
+
+func square(minLng, minLat, size float64) Polygon {
+	return Polygon{
+		{minLng, minLat}, {minLng, minLat + size}, {minLng + size, minLat + size}, {minLng + size, minLat}, {minLng, minLat},
+	}
+}
+
+func TestIntersection(t *testing.T) {
+	t.Run("overlapping squares", func(t *testing.T) {
+		got := square(0, 0, 10).Intersection(square(5, 5, 10))
+
+		assert.Equal(t, 1, len(got), "expect a single polygon")
+		assert.Equal(t, 1, len(got[0]), "expect no holes")
+		assert.Equal(t, []float64{25}, planarAreas(got), "expect the 5x5 overlapping corner")
+		assert.Nil(t, got[0][0].Validate(), "expect a valid clockwise polygon")
+		assert.True(t, got.Contains(Coordinate{7, 7}), "overlap should contain the shared corner")
+		assert.False(t, got.Contains(Coordinate{2, 2}), "overlap should not contain the rest of either square")
+	})
+
+	t.Run("polygon inside another", func(t *testing.T) {
+		got := square(0, 0, 10).Intersection(square(2, 2, 2))
+		assert.Equal(t, []float64{4}, planarAreas(got), "expect the inner square")
+
+		got = square(2, 2, 2).Intersection(square(0, 0, 10))
+		assert.Equal(t, []float64{4}, planarAreas(got), "intersection should be symmetric")
+	})
+
+	t.Run("disjoint and touching polygons", func(t *testing.T) {
+		assert.Empty(t, square(0, 0, 10).Intersection(square(20, 20, 10)), "disjoint squares do not overlap")
+		assert.Empty(t, square(0, 0, 10).Intersection(square(10, 0, 10)), "squares sharing an edge do not overlap")
+		assert.Empty(t, square(0, 0, 10).Intersection(square(10, 10, 10)), "squares sharing a corner do not overlap")
+	})
+
+	t.Run("identical and edge-aligned polygons", func(t *testing.T) {
+		assert.Equal(t, []float64{100}, planarAreas(square(0, 0, 10).Intersection(square(0, 0, 10))), "identical squares overlap entirely")
+		assert.Equal(t, []float64{50}, planarAreas(square(0, 0, 10).Intersection(square(5, 0, 10))), "squares sharing part of two edges")
+	})
+
+	t.Run("concave polygon producing several pieces", func(t *testing.T) {
+		// a U shape whose arms both cross the bar
+		u := Polygon{{0, 0}, {0, 10}, {3, 10}, {3, 3}, {7, 3}, {7, 10}, {10, 10}, {10, 0}, {0, 0}}
+		bar := Polygon{{-1, 5}, {-1, 8}, {11, 8}, {11, 5}, {-1, 5}}
+		got := u.Intersection(bar)
+
+		assert.Equal(t, 2, len(got), "expect one piece for each arm of the U")
+		assert.ElementsMatch(t, []float64{9, 9}, planarAreas(got), "expect a 3x3 piece from each arm")
+	})
+
+	t.Run("intersection with a hole", func(t *testing.T) {
+		frame := MultiPolygon{{square(0, 0, 10), square(3, 3, 4)}}
+		got := frame.Intersection(square(-1, -1, 12).MultiPolygon())
+
+		assert.Equal(t, 1, len(got), "expect a single polygon")
+		assert.Equal(t, 2, len(got[0]), "expect the hole to be kept")
+		assert.Equal(t, []float64{84}, planarAreas(got), "expect the frame without its hole")
+		assert.False(t, got.Contains(Coordinate{5, 5}), "hole should not be contained")
+		assert.True(t, got.Contains(Coordinate{1, 1}), "frame should be contained")
+	})
+}
+
+func TestUnion(t *testing.T) {
+	t.Run("overlapping squares", func(t *testing.T) {
+		got := square(0, 0, 10).Union(square(5, 5, 10))
+
+		assert.Equal(t, 1, len(got), "expect a single polygon")
+		assert.Equal(t, 1, len(got[0]), "expect no holes")
+		assert.InDeltaSlice(t, []float64{175}, planarAreas(got), 1e-9, "expect both squares less the overlap counted twice")
+		assert.Nil(t, got[0][0].Validate(), "expect a valid clockwise polygon")
+		assert.True(t, got.Contains(Coordinate{2, 2}) && got.Contains(Coordinate{12, 12}), "union should contain both squares")
+	})
+
+	t.Run("squares sharing an edge are dissolved", func(t *testing.T) {
+		got := square(0, 0, 10).Union(square(10, 0, 10))
+
+		assert.Equal(t, []float64{200}, planarAreas(got), "expect a single 20x10 rectangle")
+		assert.Equal(t, 1, len(got[0]), "expect no holes")
+		assert.False(t, got.Contains(Coordinate{10, 20}), "shared edge should not leave a seam")
+
+		got = square(0, 0, 10).Union(square(0, 0, 10))
+		assert.Equal(t, []float64{100}, planarAreas(got), "union of identical squares is the square")
+	})
+
+	t.Run("disjoint and nested polygons", func(t *testing.T) {
+		got := square(0, 0, 10).Union(square(20, 20, 10))
+		assert.ElementsMatch(t, []float64{100, 100}, planarAreas(got), "disjoint squares are both kept")
+
+		got = square(0, 0, 10).Union(square(2, 2, 2))
+		assert.Equal(t, []float64{100}, planarAreas(got), "inner square is absorbed")
+	})
+
+	t.Run("closing a U shape leaves a hole", func(t *testing.T) {
+		u := Polygon{{0, 0}, {0, 10}, {3, 10}, {3, 3}, {7, 3}, {7, 10}, {10, 10}, {10, 0}, {0, 0}}
+		lid := Polygon{{0, 8}, {0, 10}, {10, 10}, {10, 8}, {0, 8}}
+		got := u.Union(lid)
+
+		assert.Equal(t, 1, len(got), "expect a single polygon")
+		assert.Equal(t, 2, len(got[0]), "expect the space inside the U to become a hole")
+		assert.Equal(t, []float64{80}, planarAreas(got), "expect the 10x10 square less the 4x5 hole")
+		assert.False(t, got.Contains(Coordinate{5, 5}), "hole should not be contained")
+	})
+}
+
+func TestDifference(t *testing.T) {
+	t.Run("overlapping squares", func(t *testing.T) {
+		got := square(0, 0, 10).Difference(square(5, 5, 10))
+
+		assert.Equal(t, 1, len(got), "expect a single polygon")
+		assert.Equal(t, []float64{75}, planarAreas(got), "expect the square less its overlapping corner")
+		assert.Nil(t, got[0][0].Validate(), "expect a valid clockwise polygon")
+		assert.False(t, got.Contains(Coordinate{7, 7}), "overlap should be removed")
+		assert.True(t, got.Contains(Coordinate{2, 2}), "rest of the square should be kept")
+	})
+
+	t.Run("removing an inner polygon leaves a hole", func(t *testing.T) {
+		got := square(0, 0, 10).Difference(square(3, 3, 4))
+
+		assert.Equal(t, 1, len(got), "expect a single polygon")
+		assert.Equal(t, 2, len(got[0]), "expect a hole")
+		assert.Equal(t, []float64{84}, planarAreas(got), "expect the square less the hole")
+	})
+
+	t.Run("edge-aligned polygons", func(t *testing.T) {
+		assert.Equal(t, []float64{100}, planarAreas(square(0, 0, 10).Difference(square(10, 0, 10))), "squares sharing an edge are unchanged")
+		assert.Equal(t, []float64{50}, planarAreas(square(0, 0, 10).Difference(square(5, 0, 10))), "squares sharing part of two edges")
+		assert.Empty(t, square(0, 0, 10).Difference(square(0, 0, 10)), "identical squares leave nothing")
+		assert.Empty(t, square(2, 2, 2).Difference(square(0, 0, 10)), "inner square is removed entirely")
+	})
+
+	t.Run("cutting a polygon in two", func(t *testing.T) {
+		bar := Polygon{{-1, 4}, {-1, 6}, {11, 6}, {11, 4}, {-1, 4}}
+		got := square(0, 0, 10).Difference(bar)
+
+		assert.ElementsMatch(t, []float64{40, 40}, planarAreas(got), "expect a piece above and below the bar")
+	})
+}
+
+func TestArea(t *testing.T) {
+	wyoming, err := NewPolygon([]Coordinate{
+		{-111.056888, 44.99962}, {-104.05216, 45.003424}, {-104.053615, 41.00211}, {-111.046723, 40.997959}, {-111.056888, 44.99962},
+	})
+	assert.Nil(t, err, "given ring should produce a valid polygon")
+	assert.InDelta(t, 253000, wyoming.Area(), 2000, "Wyoming is about 253,000 square kilometers")
+	assert.InDelta(t, 253000, wyoming.MultiPolygon().Area(), 2000, "Wyoming is about 253,000 square kilometers")
+
+	reversed := reversed(*wyoming)
+	assert.InDelta(t, wyoming.Area(), reversed.Area(), 1e-6, "area should not depend on orientation")
+
+	withHole := MultiPolygon{{*wyoming, square(-108, 42, 1)}}
+	assert.InDelta(t, wyoming.Area()-square(-108, 42, 1).Area(), withHole.Area(), 1e-6, "holes should not count towards area")
+}
@@ -3,8 +3,14 @@ package geospatial
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/golang/geo/s2"
 )
 
+// The mean radius of the Earth in kilometers used to translate
+// spherical angles and areas into distances and surface areas
+const EarthRadiusKm = 6371.0088
+
 // Creates the expected [Coordinate] object
 // with ordered arguments by latitude and longitude
 func LatLng(lat, lng float64) Coordinate {
@@ -21,6 +27,19 @@ func (c Coordinate) String() string {
 	return fmt.Sprintf("[%G, %G]", c.Lng, c.Lat)
 }
 
+// The great-circle distance in kilometers between two coordinates
+func (c Coordinate) DistanceTo(other Coordinate) float64 {
+	return c.latLng().Distance(other.latLng()).Radians() * EarthRadiusKm
+}
+
+func (c Coordinate) latLng() s2.LatLng {
+	return s2.LatLngFromDegrees(c.Lat, c.Lng)
+}
+
+func (c Coordinate) point() s2.Point {
+	return s2.PointFromLatLng(c.latLng())
+}
+
 func (c Coordinate) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]float64{c.Lng, c.Lat})
 }
@@ -6,6 +6,8 @@ const (
 	RingTooShort         = "polygon ring too short, must contain at least 4 positions"
 	RingUnclosed         = "polygon ring must be closed, first and last positions must be equal"
 	RingCounterClockwise = "polygon exterior ring must be clockwise"
+	LineTooShort         = "linestring too short, must contain at least 2 positions"
+	BoxUnordered         = "bounding box minimum must be south-west of its maximum"
 )
 
 type InvalidGeometryError struct {
@@ -0,0 +1,85 @@
+package geospatial
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Represents a path on a map or spherical geometry
+// as an ordered array of coordinates
+type LineString []Coordinate
+
+// Constructs a new instance of a LineString with valid
+// coordinates which satisfy the RFC 7946 GeoJSON specifications
+// [See: 3.1.4 LineString](https://datatracker.ietf.org/doc/html/rfc7946#section-3.1.4)
+func NewLineString(coords []Coordinate) (*LineString, error) {
+	var l LineString = append([]Coordinate{}, coords...)
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Formats the coordinate array as a string
+func (l LineString) String() string {
+	var coords []string
+	for _, coord := range l {
+		coords = append(coords, coord.String())
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(coords, ", "))
+}
+
+// Implements the RFC 7946 specifications for a geospatial LineString
+// [See: 3.1.4 LineString](https://datatracker.ietf.org/doc/html/rfc7946#section-3.1.4)
+func (l LineString) Validate() error {
+	if len(l) < 2 {
+		return &InvalidGeometryError{LineTooShort}
+	}
+	return nil
+}
+
+// The great-circle length of the path in kilometers
+func (l LineString) Length() (length float64) {
+	for i := 1; i < len(l); i++ {
+		length += l[i-1].DistanceTo(l[i])
+	}
+	return
+}
+
+func (l LineString) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]Coordinate(l))
+}
+
+func (l *LineString) UnmarshalJSON(data []byte) error {
+	var coordinates []Coordinate
+	if err := json.Unmarshal(data, &coordinates); err != nil {
+		return err
+	}
+
+	line, err := NewLineString(coordinates)
+	if err != nil {
+		return err
+	}
+	*l = *line
+
+	return nil
+}
+
+// A section of a [LineString] which lies within the boundaries of a [Polygon],
+// from the coordinate where the path enters the polygon to the coordinate where it
+// exits. Start and End are the distances in kilometers along the path at which
+// the section begins and ends
+type Traversal struct {
+	Entry Coordinate
+	Exit  Coordinate
+	Start float64
+	End   float64
+}
+
+// The distance in kilometers travelled within the polygon
+func (t Traversal) Distance() float64 {
+	return t.End - t.Start
+}
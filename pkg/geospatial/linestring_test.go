@@ -0,0 +1,48 @@
+package geospatial
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineString(t *testing.T) {
+	t.Run("should validate the number of positions", func(t *testing.T) {
+		got, err := NewLineString([]Coordinate{{0, 0}, {1, 1}})
+		assert.Nil(t, err, "two positions should produce a valid linestring")
+		assert.Equal(t, 2, len(*got), "constructor should not modify the coordinates")
+
+		_, err = NewLineString([]Coordinate{{0, 0}})
+		var invalidGeoErr *InvalidGeometryError
+		assert.NotNil(t, err, "a single position should not validate")
+		assert.True(t, errors.As(err, &invalidGeoErr), "expecting error to be InvalidGeometryError")
+		assert.Equal(t, LineTooShort, err.Error(), "expecting LineTooShort validation error")
+	})
+
+	t.Run("should measure the great-circle length of the path", func(t *testing.T) {
+		equator := LineString{{0, 0}, {1, 0}, {2, 0}}
+		assert.InDelta(t, 222.39, equator.Length(), 0.01, "two degrees of longitude along the equator is about 222km")
+
+		philadelphiaToPittsburgh := LineString{LatLng(39.9526, -75.1652), LatLng(40.4406, -79.9959)}
+		assert.InDelta(t, 414, philadelphiaToPittsburgh.Length(), 2, "Philadelphia to Pittsburgh is about 414km")
+	})
+
+	t.Run("should produce expected json and unmarshal back with same coordinates", func(t *testing.T) {
+		expected := "[[0,0],[2,2],[1,1]]"
+		l := LineString([]Coordinate{{0, 0}, {2, 2}, {1, 1}})
+		got, err := l.MarshalJSON()
+		assert.Nil(t, err, "expect valid json from jsonMarshal")
+		assert.Equal(t, expected, string(got), "expect json to marshal into array of coordinates")
+
+		var gotL LineString
+		err = gotL.UnmarshalJSON(got)
+		assert.Nil(t, err, "expect json to unmarshal back into linestring")
+		assert.Equal(t, l, gotL, "unmarshalled json should contain the same coordinates in the same order")
+
+		for _, invalid := range []string{`[[0,0]]`, `[[0,0,0],[1,1]]`, "ceci n'est pas un json"} {
+			err = gotL.UnmarshalJSON([]byte(invalid))
+			assert.NotNilf(t, err, "expect UnmarshalJSON to produce error for invalid json: %s", invalid)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package geospatial
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// Represents a collection of polygons, any of which may contain holes, following
+// the RFC 7946 GeoJSON specifications where the first ring of each polygon is its
+// exterior boundary and any other rings are the boundaries of holes within it
+// [See: 3.1.7 MultiPolygon](https://datatracker.ietf.org/doc/html/rfc7946#section-3.1.7)
+type MultiPolygon [][]Polygon
+
+// Translates the polygon into a [MultiPolygon] with a single polygon and no holes
+func (p Polygon) MultiPolygon() MultiPolygon {
+	return MultiPolygon{{p}}
+}
+
+// Checks whether the coordinate is within the exterior boundary
+// of any of the polygons and outside all of that polygon's holes
+func (m MultiPolygon) Contains(coord Coordinate) bool {
+	for _, polygon := range m {
+		var contains bool
+		for _, ring := range polygon {
+			if ring.Contains(coord) {
+				contains = !contains
+			}
+		}
+		if contains {
+			return true
+		}
+	}
+	return false
+}
+
+// The spherical surface area in square kilometers covered by the polygons
+func (m MultiPolygon) Area() (area float64) {
+	for _, polygon := range m {
+		for i, ring := range polygon {
+			if i == 0 {
+				area += ring.Area()
+			} else {
+				area -= ring.Area()
+			}
+		}
+	}
+	return
+}
+
+// The smallest box containing every polygon
+func (m MultiPolygon) Bounds() BoundingBox {
+	var bounds BoundingBox
+	for i, polygon := range m {
+		if len(polygon) == 0 {
+			continue
+		}
+		if i == 0 {
+			bounds = polygon[0].Bounds()
+		} else {
+			bounds = bounds.Extend(polygon[0].Bounds())
+		}
+	}
+	return bounds
+}
+
+func (m MultiPolygon) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([][]Polygon(m))
+}
+
+// The spherical surface area in square kilometers enclosed by the polygon's ring
+func (p Polygon) Area() float64 {
+	if len(p) < 4 {
+		return 0
+	}
+
+	points := make([]s2.Point, len(p)-1)
+	for i := 0; i < len(p)-1; i++ {
+		points[i] = p[i].point()
+	}
+
+	// the loop's area is measured to its left, so a clockwise ring
+	// measures everything outside it instead
+	area := s2.LoopFromPoints(points).Area()
+	if area > 2*math.Pi {
+		area = 4*math.Pi - area
+	}
+
+	return area * EarthRadiusKm * EarthRadiusKm
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"slices"
+	"sort"
 	"strings"
 
 	"github.com/golang/geo/s2"
@@ -97,6 +98,89 @@ func (p Polygon) Contains(coord Coordinate) (contains bool) {
 	return
 }
 
+// Walks the given path and finds each section of it which lies within the polygon. Every
+// segment of the path is split wherever it intersects one of the polygon's edges, and the
+// pieces in between are checked with the Ray-casting algorithm. Sections are returned in
+// the order they are travelled
+func (p Polygon) Traverse(line LineString) (sections []Traversal) {
+	var current *Traversal
+	var travelled float64
+
+	for i := 1; i < len(line); i++ {
+		segment := edge{line[i-1], line[i]}
+		length := segment.p1.DistanceTo(segment.p2)
+
+		for _, piece := range p.split(segment) {
+			start, end := piece[0], piece[1]
+			if !p.Contains(segment.at((start + end) / 2)) {
+				if current != nil {
+					sections = append(sections, *current)
+					current = nil
+				}
+				continue
+			}
+
+			if current == nil {
+				current = &Traversal{Entry: segment.at(start), Start: travelled + start*length}
+			}
+			current.Exit = segment.at(end)
+			current.End = travelled + end*length
+		}
+
+		travelled += length
+	}
+
+	if current != nil {
+		sections = append(sections, *current)
+	}
+
+	return
+}
+
+// Splits an edge at every point where it intersects the polygon's edges, returning the
+// pieces as pairs of parameters along the edge, from 0 at its start to 1 at its end
+func (p Polygon) split(e edge) (pieces [][2]float64) {
+	cuts := []float64{0, 1}
+	for i := 1; i < len(p); i++ {
+		if t, ok := edgeIntersection(e, edge{p[i-1], p[i]}); ok {
+			cuts = append(cuts, t)
+		}
+	}
+	sort.Float64s(cuts)
+
+	for i := 1; i < len(cuts); i++ {
+		if cuts[i]-cuts[i-1] > epsilon {
+			pieces = append(pieces, [2]float64{cuts[i-1], cuts[i]})
+		}
+	}
+	return
+}
+
+// Finds where two edges intersect, returning the parameter along the
+// first edge at which the intersection occurs. Parallel edges are
+// never considered to intersect
+func edgeIntersection(a, b edge) (float64, bool) {
+	da := Coordinate{a.p2.Lng - a.p1.Lng, a.p2.Lat - a.p1.Lat}
+	db := Coordinate{b.p2.Lng - b.p1.Lng, b.p2.Lat - b.p1.Lat}
+	denominator := cross(da, db)
+	if denominator == 0 {
+		return 0, false
+	}
+
+	offset := Coordinate{b.p1.Lng - a.p1.Lng, b.p1.Lat - a.p1.Lat}
+	t := cross(offset, db) / denominator
+	u := cross(offset, da) / denominator
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// The z component of the cross product of two vectors
+func cross(a, b Coordinate) float64 {
+	return a.Lng*b.Lat - a.Lat*b.Lng
+}
+
 // Implementation of the Ray-casting algortithm
 func rayIntersectsEdge(p Coordinate, e edge) bool {
 	var a, b Coordinate
@@ -140,13 +224,25 @@ func rayIntersectsEdge(p Coordinate, e edge) bool {
 func turningAngle(vertices []Coordinate) float64 {
 	points := make([]s2.Point, len(vertices)-1)
 	for i := 0; i < len(vertices)-1; i++ {
-		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(vertices[i].Lat, vertices[i].Lng))
+		points[i] = vertices[i].point()
 	}
 
 	return s2.LoopFromPoints(points).TurningAngle()
 }
 
+// Tolerance used when comparing parameters along an edge
+const epsilon = 1e-12
+
 type edge struct {
 	p1 Coordinate
 	p2 Coordinate
 }
+
+// The coordinate at the given parameter along the edge, from
+// 0 at its first point to 1 at its second point
+func (e edge) at(t float64) Coordinate {
+	return Coordinate{
+		Lng: e.p1.Lng + t*(e.p2.Lng-e.p1.Lng),
+		Lat: e.p1.Lat + t*(e.p2.Lat-e.p1.Lat),
+	}
+}
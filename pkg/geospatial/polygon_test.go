@@ -114,3 +114,39 @@ func TestPolygonJSON(t *testing.T) {
 	})
 
 }
+
+func TestTraverse(t *testing.T) {
+	square, err := NewPolygon([]Coordinate{
+		{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0},
+	})
+	assert.Nil(t, err, "square should be a valid polygon")
+
+	t.Run("should find entry and exit for a path crossing the polygon", func(t *testing.T) {
+		line := LineString{{-5, 5}, {15, 5}}
+		sections := square.Traverse(line)
+
+		assert.Equal(t, 1, len(sections), "path crosses the square once")
+		assert.InDelta(t, 0, sections[0].Entry.Lng, 1e-9, "path should enter on the western edge")
+		assert.InDelta(t, 10, sections[0].Exit.Lng, 1e-9, "path should exit on the eastern edge")
+		assert.InDelta(t, line.Length()/4, sections[0].Start, 0.5, "path should enter a quarter of the way along")
+		assert.InDelta(t, line.Length()/2, sections[0].Distance(), 1, "half of the path lies within the square")
+	})
+
+	t.Run("should track sections which span several segments", func(t *testing.T) {
+		line := LineString{{5, -5}, {5, 5}, {8, 8}, {20, 8}, {20, 2}, {8, 2}}
+		sections := square.Traverse(line)
+
+		assert.Equal(t, 2, len(sections), "path enters the square twice")
+		assert.InDelta(t, 0, sections[0].Entry.Lat, 1e-9, "path should first enter on the southern edge")
+		assert.InDelta(t, 10, sections[0].Exit.Lng, 1e-9, "path should first exit on the eastern edge")
+		assert.InDelta(t, 10, sections[1].Entry.Lng, 1e-9, "path should re-enter on the eastern edge")
+		assert.Equal(t, Coordinate{8, 2}, sections[1].Exit, "path should end within the square")
+		assert.Less(t, sections[0].End, sections[1].Start, "sections should be ordered along the path")
+		assert.InDelta(t, line.Length(), sections[1].End, 1e-6, "last section should end at the end of the path")
+	})
+
+	t.Run("should find nothing for a path outside the polygon", func(t *testing.T) {
+		sections := square.Traverse(LineString{{-5, -5}, {-5, 15}, {-1, 20}})
+		assert.Empty(t, sections, "path never enters the square")
+	})
+}
@@ -0,0 +1,27 @@
+package geospatial
+
+// Represents a named group of states, such as a sales region, as the names of its
+// member states and the dissolved union of their borders
+type Region struct {
+	Name   string       `json:"region"`
+	States []string     `json:"states"`
+	Border MultiPolygon `json:"border"`
+}
+
+// Constructor for the [Region] struct dissolves the borders of the member states into
+// a single border, removing any borders which the member states share
+func NewRegion(name string, members []State) Region {
+	region := Region{Name: name, States: make([]string, 0, len(members))}
+
+	for _, state := range members {
+		region.States = append(region.States, state.Name)
+		region.Border = region.Border.Union(state.Border.MultiPolygon())
+	}
+
+	return region
+}
+
+// Determines if a given [Coordinate] is contained within the region's border
+func (r Region) Contains(c Coordinate) bool {
+	return r.Border.Contains(c)
+}
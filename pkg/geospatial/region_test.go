@@ -0,0 +1,28 @@
+package geospatial
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegion(t *testing.T) {
+	t.Run("should dissolve the borders of the member states", func(t *testing.T) {
+		got := NewRegion("Squares", []State{{Name: "West", Border: square(0, 0, 1)}, {Name: "East", Border: square(1, 0, 1)}})
+
+		assert.Equal(t, "Squares", got.Name)
+		assert.Equal(t, []string{"West", "East"}, got.States)
+		assert.Equal(t, 1, len(got.Border), "adjacent members should be dissolved into a single polygon")
+		assert.Equal(t, []float64{2}, planarAreas(got.Border), "expect both squares")
+		assert.True(t, got.Contains(Coordinate{0.5, 0.5}) && got.Contains(Coordinate{1.5, 0.5}), "region should contain both members")
+		assert.False(t, got.Contains(Coordinate{2.5, 0.5}), "region should not contain points outside its members")
+	})
+
+	t.Run("should allow a region without members", func(t *testing.T) {
+		got := NewRegion("Empty", nil)
+
+		assert.Empty(t, got.States)
+		assert.Empty(t, got.Border)
+		assert.False(t, got.Contains(Coordinate{0, 0}))
+	})
+}
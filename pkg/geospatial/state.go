@@ -9,10 +9,27 @@ import (
 )
 
 // Represents a geographic state as a name and a
-// geospatial polygon (i.e. a linear ring of boundary coordinates)
+// geospatial polygon (i.e. a linear ring of boundary coordinates).
+// Country names the country the state belongs to, the level above
+// the state in the hierarchy of geographies, if it is known.
+// Aliases are the other names by which the state is known, e.g. its
+// USPS or FIPS code. Any other attributes of the state, e.g. its
+// population, are kept as arbitrary JSON properties. Version is set
+// by the data store and increases every time the state is changed
 type State struct {
+	Name       string                 `json:"state"`
+	Border     Polygon                `json:"border"`
+	Country    string                 `json:"country,omitempty"`
+	Aliases    []string               `json:"aliases,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Version    uint64                 `json:"-"`
+}
+
+// A state which shares part of its border with another state, along
+// with the length in kilometers of the border they share
+type Neighbor struct {
 	Name   string  `json:"state"`
-	Border Polygon `json:"border"`
+	Border float64 `json:"border"`
 }
 
 // Constructor generates a new [State] object with the
@@ -38,3 +55,9 @@ func NewState(name string, coords []Coordinate) (state State, err error) {
 func (s State) Contains(coordinate Coordinate) bool {
 	return s.Border.Contains(coordinate)
 }
+
+// Calls the Traverse method for the [Polygon] object representing
+// the state's border
+func (s State) Traverse(line LineString) []Traversal {
+	return s.Border.Traverse(line)
+}
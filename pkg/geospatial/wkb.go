@@ -0,0 +1,97 @@
+package geospatial
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The geometry type of a polygon in the Well-Known Binary format
+const wkbPolygon = 3
+
+// Encodes the polygon as a little-endian Well-Known Binary (WKB) polygon with a single ring,
+// the format used to store geometry in SQL databases
+// [See: OGC Simple Feature Access 8.2](https://www.ogc.org/standard/sfa/)
+func (p Polygon) MarshalWKB() []byte {
+	data := make([]byte, 0, 13+16*len(p))
+	data = append(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, wkbPolygon)
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(p)))
+	for _, coord := range p {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(coord.Lng))
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(coord.Lat))
+	}
+	return data
+}
+
+// Decodes a Well-Known Binary (WKB) polygon in either byte order into the polygon. Only
+// the exterior ring is kept, since polygons do not have holes
+func (p *Polygon) UnmarshalWKB(data []byte) error {
+	reader := wkbReader{data: data}
+	switch reader.byte() {
+	case 0:
+		reader.order = binary.BigEndian
+	case 1:
+		reader.order = binary.LittleEndian
+	default:
+		return &InvalidGeometryError{"invalid WKB byte order"}
+	}
+
+	if kind := reader.uint32(); kind != wkbPolygon {
+		return &InvalidGeometryError{fmt.Sprintf("invalid WKB geometry type %d, must be a polygon", kind)}
+	}
+	if rings := reader.uint32(); rings == 0 {
+		return &InvalidGeometryError{RingTooShort}
+	}
+
+	count := reader.uint32()
+	if uint64(count)*16 > uint64(len(data)) {
+		return &InvalidGeometryError{"WKB polygon is truncated"}
+	}
+	coords := make([]Coordinate, count)
+	for i := range coords {
+		coords[i] = Coordinate{Lng: reader.float64(), Lat: reader.float64()}
+	}
+	if reader.err != nil {
+		return reader.err
+	}
+
+	polygon, err := NewPolygon(coords)
+	if err != nil {
+		return err
+	}
+	*p = *polygon
+
+	return nil
+}
+
+// Reads the fields of a WKB geometry in order, recording an error
+// instead of reading past the end of the data
+type wkbReader struct {
+	data  []byte
+	order binary.ByteOrder
+	err   error
+}
+
+func (r *wkbReader) next(size int) []byte {
+	if r.err != nil || len(r.data) < size {
+		r.err = &InvalidGeometryError{"WKB polygon is truncated"}
+		return make([]byte, size)
+	}
+	field := r.data[:size]
+	r.data = r.data[size:]
+	return field
+}
+
+func (r *wkbReader) byte() byte {
+	return r.next(1)[0]
+}
+
+func (r *wkbReader) uint32() uint32 {
+	return r.order.Uint32(r.next(4))
+}
+
+func (r *wkbReader) float64() float64 {
+	return math.Float64frombits(r.order.Uint64(r.next(8)))
+}
@@ -0,0 +1,59 @@
+package geospatial
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWKB(t *testing.T) {
+	box, err := NewBoundingBox(0, 0, 10, 5)
+	assert.Nil(t, err, "expect valid bounding box")
+	square := box.Polygon()
+
+	t.Run("should round trip a polygon through WKB", func(t *testing.T) {
+		data := square.MarshalWKB()
+		assert.Equal(t, 13+16*len(square), len(data), "expect a header followed by two doubles per coordinate")
+		assert.Equal(t, []byte{1, 3, 0, 0, 0, 1, 0, 0, 0, 5, 0, 0, 0}, data[:13], "expect a little-endian polygon with one ring")
+
+		var decoded Polygon
+		assert.Nil(t, decoded.UnmarshalWKB(data), "expect valid WKB")
+		assert.Equal(t, square, decoded)
+	})
+
+	t.Run("should decode big-endian WKB", func(t *testing.T) {
+		data := []byte{0}
+		data = binary.BigEndian.AppendUint32(data, 3)
+		data = binary.BigEndian.AppendUint32(data, 1)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(square)))
+		for _, coord := range square {
+			data = binary.BigEndian.AppendUint64(data, math.Float64bits(coord.Lng))
+			data = binary.BigEndian.AppendUint64(data, math.Float64bits(coord.Lat))
+		}
+
+		var decoded Polygon
+		assert.Nil(t, decoded.UnmarshalWKB(data), "expect valid WKB")
+		assert.Equal(t, square, decoded)
+	})
+
+	t.Run("should reject invalid WKB", func(t *testing.T) {
+		data := square.MarshalWKB()
+		point := append([]byte{1}, binary.LittleEndian.AppendUint32(nil, 1)...)
+
+		for name, invalid := range map[string][]byte{
+			"empty":         {},
+			"byte order":    append([]byte{2}, data[1:]...),
+			"geometry type": point,
+			"truncated":     data[:len(data)-4],
+			"point count":   append(append(append([]byte{}, data[:9]...), 0xff, 0xff, 0xff, 0x7f), data[13:]...),
+		} {
+			var decoded Polygon
+			err := decoded.UnmarshalWKB(invalid)
+			var invalidGeoErr *InvalidGeometryError
+			assert.Truef(t, errors.As(err, &invalidGeoErr), "expecting error to be InvalidGeometryError: %s", name)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aaronireland/state-server/pkg/api/backend"
+)
+
+// The options given for a backend in the STATE_SERVER_BACKEND_OPTIONS configuration,
+// formatted as comma separated name:value pairs, e.g. path:/var/lib/state-server/states.db
+type BackendOptions map[string]string
+
+// Opens the data store for a backend with the options given for it in the server configuration. Data
+// stores whose methods do not take a [context.Context] can be adapted with [backend.WithContext]. Data
+// stores which need to be closed when the server stops implement [io.Closer]
+type BackendFactory func(options BackendOptions) (StateLocationDataProvider, error)
+
+var (
+	backends   = map[string]BackendFactory{}
+	backendsMu sync.RWMutex
+)
+
+func init() {
+	RegisterBackend("memory", func(options BackendOptions) (StateLocationDataProvider, error) {
+		if err := options.allow(); err != nil {
+			return nil, err
+		}
+		return backend.WithContext(backend.NewMemoryStore()), nil
+	})
+
+	RegisterBackend("file", func(options BackendOptions) (StateLocationDataProvider, error) {
+		if err := options.allow("dir", "compact_after"); err != nil {
+			return nil, err
+		}
+		dir, err := options.required("dir")
+		if err != nil {
+			return nil, err
+		}
+		compactAfter, err := options.int("compact_after", 1000)
+		if err != nil {
+			return nil, err
+		}
+		store, err := backend.NewFileStore(dir, compactAfter)
+		if err != nil {
+			return nil, err
+		}
+		return backend.WithContext(store), nil
+	})
+
+	RegisterBackend("bolt", func(options BackendOptions) (StateLocationDataProvider, error) {
+		if err := options.allow("path"); err != nil {
+			return nil, err
+		}
+		path, err := options.required("path")
+		if err != nil {
+			return nil, err
+		}
+		store, err := backend.NewBoltStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return backend.WithContext(store), nil
+	})
+
+	RegisterBackend("sqlite", func(options BackendOptions) (StateLocationDataProvider, error) {
+		if err := options.allow("path"); err != nil {
+			return nil, err
+		}
+		path, err := options.required("path")
+		if err != nil {
+			return nil, err
+		}
+		store, err := backend.NewSQLiteStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return backend.WithContext(store), nil
+	})
+}
+
+// Makes a backend selectable by name in the STATE_SERVER_BACKEND configuration, replacing any
+// backend already registered with the name. Deployments which build their own binary can register
+// backends which are not part of the state-server, e.g. a client for a remote data store
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[strings.ToLower(name)] = factory
+}
+
+// The names of the registered backends in alphabetical order
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Opens the data store for the backend selected in the server configuration
+func OpenBackend(config serverConfig) (StateLocationDataProvider, error) {
+	backendsMu.RLock()
+	factory, ok := backends[strings.ToLower(config.Backend)]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q, must be one of: %s", config.Backend, strings.Join(Backends(), ", "))
+	}
+
+	store, err := factory(config.BackendOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s backend: %w", config.Backend, err)
+	}
+	return store, nil
+}
+
+// Opens the data store for a backend given by its name followed by a colon and its options in the format of
+// STATE_SERVER_BACKEND_OPTIONS, e.g. bolt:path:/var/lib/state-server/states.db, rather than by the server
+// configuration, e.g. to open two data stores at once when migrating from one backend into another
+func OpenBackendSpec(spec string) (StateLocationDataProvider, error) {
+	name, options, _ := strings.Cut(spec, ":")
+	config := serverConfig{Backend: name, BackendOptions: BackendOptions{}}
+	if options != "" {
+		for _, option := range strings.Split(options, ",") {
+			key, value, ok := strings.Cut(option, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid option %q for %s backend, must be formatted as name:value", option, name)
+			}
+			config.BackendOptions[key] = value
+		}
+	}
+	return OpenBackend(config)
+}
+
+// Checks that every option is one the backend understands, so that misspelled options are not ignored
+func (o BackendOptions) allow(names ...string) error {
+	for option := range o {
+		if !slices.Contains(names, option) {
+			return fmt.Errorf("unknown option %q, must be one of: %s", option, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+func (o BackendOptions) required(name string) (string, error) {
+	if value := o[name]; value != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("option %q is required", name)
+}
+
+func (o BackendOptions) int(name string, fallback int) (int, error) {
+	value, ok := o[name]
+	if !ok {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for option %q: %s", name, value)
+	}
+	return number, nil
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/aaronireland/state-server/pkg/api/backend/backendtest"
+	"github.com/aaronireland/state-server/pkg/geospatial"
+	"github.com/stretchr/testify/assert"
+)
+
+// Options which open each of the built-in backends in a temporary directory. Every registered
+// backend must be given options here so that it runs the conformance tests
+func testBackendOptions(t *testing.T, name string) (BackendOptions, bool) {
+	dir := t.TempDir()
+	switch name {
+	case "memory":
+		return BackendOptions{}, true
+	case "file":
+		return BackendOptions{"dir": dir, "compact_after": "3"}, true
+	case "bolt":
+		return BackendOptions{"path": filepath.Join(dir, "states.db")}, true
+	case "sqlite":
+		return BackendOptions{"path": filepath.Join(dir, "states.sqlite")}, true
+	}
+	return nil, false
+}
+
+func TestBackendConformance(t *testing.T) {
+	square := func(lng, lat, size float64) []geospatial.Coordinate {
+		return []geospatial.Coordinate{{Lng: lng, Lat: lat}, {Lng: lng, Lat: lat + size}, {Lng: lng + size, Lat: lat + size}, {Lng: lng + size, Lat: lat}, {Lng: lng, Lat: lat}}
+	}
+
+	for _, name := range Backends() {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := testBackendOptions(t, name); !assert.Truef(t, ok, "backend %s should have options for the conformance tests", name) {
+				return
+			}
+
+			backendtest.Run(t, func(t *testing.T) backendtest.DataStore {
+				options, _ := testBackendOptions(t, name)
+				store, err := OpenBackend(serverConfig{Backend: name, BackendOptions: options})
+				assert.Nil(t, err, "backend should open")
+				return store
+			})
+
+			t.Run("should keep every change after it is reopened", func(t *testing.T) {
+				options, _ := testBackendOptions(t, name)
+				config := serverConfig{Backend: name, BackendOptions: options}
+				store, err := OpenBackend(config)
+				if !assert.Nil(t, err, "backend should open") {
+					return
+				}
+				_, ok := store.(*backend.ContextStore).Unwrap().(io.Closer)
+				if !ok {
+					t.Skipf("backend %s is not persistent", name)
+				}
+				ctx := context.Background()
+
+				_, err = store.Create(ctx, geospatial.State{Name: "West", Border: square(0, 0, 10), Aliases: []string{"WS"}})
+				assert.Nil(t, err, "backend should add valid state with no errors")
+				_, err = store.Create(ctx, geospatial.State{Name: "East", Border: square(10, 0, 10)})
+				assert.Nil(t, err, "backend should add valid state with no errors")
+				_, err = store.CreateRegion(ctx, geospatial.Region{Name: "Both", States: []string{"west", "east"}})
+				assert.Nil(t, err, "backend should add valid region with no errors")
+				_, err = store.CreateBoundary(ctx, []string{"West"}, geospatial.Boundary{Name: "North", Border: square(0, 5, 5)})
+				assert.Nil(t, err, "backend should add valid county with no errors")
+				renamed, err := store.Update(ctx, "ws", 0, geospatial.State{Name: "Western", Border: square(0, 0, 10), Country: "Squareland", Aliases: []string{"WS"}})
+				assert.Nil(t, err, "backend should update state with no errors")
+				assert.Nil(t, store.Delete(ctx, "East", 0), "backend should delete state with no errors")
+				version := store.Version()
+				assert.Nil(t, store.(io.Closer).Close(), "backend should close with no errors")
+
+				reopened, err := OpenBackend(config)
+				if !assert.Nil(t, err, "backend should reopen") {
+					return
+				}
+				defer reopened.(io.Closer).Close()
+
+				got, err := reopened.GetByName(ctx, "ws")
+				assert.Nil(t, err, "states should be recovered")
+				assert.Equal(t, renamed, got, "states should be recovered as they were last changed")
+				all, err := reopened.GetAll(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(all), "deleted states should stay deleted")
+				region, err := reopened.GetRegion(ctx, "BOTH")
+				assert.Nil(t, err, "regions should be recovered")
+				assert.Equal(t, []string{"Western"}, region.States, "regions should follow renamed and deleted members")
+				county, err := reopened.GetBoundary(ctx, []string{"ws", "north"})
+				assert.Nil(t, err, "boundaries should be recovered")
+				assert.Equal(t, []string{"Western"}, county.Path, "boundaries should move with their renamed state")
+				assert.Equal(t, version, reopened.Version(), "data store version should be recovered")
+			})
+		})
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	t.Run("should select the backend and its options from the environment", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "states.db")
+		t.Setenv("STATE_SERVER_BACKEND", "Bolt")
+		t.Setenv("STATE_SERVER_BACKEND_OPTIONS", "path:"+path)
+
+		config, err := LoadConfig()
+		assert.Nil(t, err, "configuration should load")
+		assert.Equal(t, BackendOptions{"path": path}, config.BackendOptions)
+
+		store, err := OpenBackend(config)
+		assert.Nil(t, err, "backend names should be case-insensitive")
+		assert.IsType(t, &backend.StateLocationBoltStore{}, store.(*backend.ContextStore).Unwrap())
+		assert.Nil(t, store.(io.Closer).Close())
+	})
+
+	t.Run("should default to the memory backend", func(t *testing.T) {
+		config, err := LoadConfig()
+		assert.Nil(t, err, "configuration should load")
+
+		store, err := OpenBackend(config)
+		assert.Nil(t, err, "memory backend should open")
+		assert.IsType(t, &backend.StateLocationMemoryStore{}, store.(*backend.ContextStore).Unwrap())
+	})
+
+	t.Run("should select the file backend and its options from the environment", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("STATE_SERVER_BACKEND", "file")
+		t.Setenv("STATE_SERVER_BACKEND_OPTIONS", "dir:"+dir+",compact_after:5")
+
+		config, err := LoadConfig()
+		assert.Nil(t, err, "configuration should load")
+		assert.Equal(t, BackendOptions{"dir": dir, "compact_after": "5"}, config.BackendOptions)
+
+		store, err := OpenBackend(config)
+		assert.Nil(t, err, "file backend should open")
+		assert.IsType(t, &backend.StateLocationFileStore{}, store.(*backend.ContextStore).Unwrap())
+		assert.Nil(t, store.(io.Closer).Close())
+	})
+
+	t.Run("should reject unknown backends and invalid options", func(t *testing.T) {
+		for _, config := range []serverConfig{
+			{Backend: "remote"},
+			{Backend: "memory", BackendOptions: BackendOptions{"path": "states.db"}},
+			{Backend: "sqlite"},
+			{Backend: "file", BackendOptions: BackendOptions{"dir": t.TempDir(), "compact_after": "often"}},
+		} {
+			_, err := OpenBackend(config)
+			assert.NotNilf(t, err, "backend %s with options %v should not open", config.Backend, config.BackendOptions)
+		}
+	})
+
+	t.Run("should open registered backends", func(t *testing.T) {
+		store := backend.WithContext(backend.NewMemoryStore())
+		RegisterBackend("shared", func(options BackendOptions) (StateLocationDataProvider, error) {
+			return store, nil
+		})
+		defer func() {
+			backendsMu.Lock()
+			delete(backends, "shared")
+			backendsMu.Unlock()
+		}()
+
+		opened, err := OpenBackend(serverConfig{Backend: "shared"})
+		assert.Nil(t, err, "registered backend should open")
+		assert.Same(t, store, opened)
+	})
+}
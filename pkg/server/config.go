@@ -9,10 +9,12 @@ import (
 const envPrefix = ""
 
 type serverConfig struct {
-	IdleTimeout  time.Duration `envconfig:"HTTP_SERVER_IDLE_TIMEOUT" default:"60s"`
-	Port         int           `envconfig:"PORT" default:"8080"`
-	ReadTimeout  time.Duration `envconfig:"HTTP_SERVER_READ_TIMEOUT" default:"1s"`
-	WriteTimeout time.Duration `envconfig:"HTTP_SERVER_WRITE_TIMEOUT" default:"2s"`
+	IdleTimeout    time.Duration  `envconfig:"HTTP_SERVER_IDLE_TIMEOUT" default:"60s"`
+	Port           int            `envconfig:"PORT" default:"8080"`
+	ReadTimeout    time.Duration  `envconfig:"HTTP_SERVER_READ_TIMEOUT" default:"1s"`
+	WriteTimeout   time.Duration  `envconfig:"HTTP_SERVER_WRITE_TIMEOUT" default:"2s"`
+	Backend        string         `envconfig:"STATE_SERVER_BACKEND" default:"memory"`
+	BackendOptions BackendOptions `envconfig:"STATE_SERVER_BACKEND_OPTIONS"`
 }
 
 func LoadConfig() (serverConfig, error) {
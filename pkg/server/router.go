@@ -1,11 +1,16 @@
 package server
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 
+	"github.com/aaronireland/state-server/pkg/api/audit"
+	"github.com/aaronireland/state-server/pkg/api/backend"
 	"github.com/aaronireland/state-server/pkg/api/location"
+	"github.com/aaronireland/state-server/pkg/api/regions"
 	"github.com/aaronireland/state-server/pkg/api/states"
 )
 
@@ -14,9 +19,30 @@ func StateServerAPIRouter(store StateLocationDataProvider) *chi.Mux {
 
 	router.Use(middleware.Logger)
 	router.Use(render.SetContentType(render.ContentTypeJSON))
+	router.Use(authorHeader)
 
 	router.Mount("/", location.Router(store))
+	router.Mount("/api/v1/locate", location.LocateRouter(store))
 	router.Mount("/api/v1/state", states.Router(store))
+	router.Mount("/api/v1/region", regions.Router(store))
+	router.Mount("/api/v1/audit", audit.Router(store))
 
 	return router
 }
+
+// The request header which names who is making a change to the data store, recorded in the history of the
+// changed state. The State Server does not authenticate its clients, so the author is self-reported: any
+// client can name anyone. Deployments which need an audit trail they can trust should serve the API behind a
+// proxy which authenticates each request and sets the header itself, replacing any value sent by the client
+const AuthorHeader = "X-Author"
+
+// Middleware which passes the author named in the X-Author header of a request through to the data store
+// as given, without checking who sent the request
+func authorHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if author := r.Header.Get(AuthorHeader); author != "" {
+			r = r.WithContext(backend.WithAuthor(r.Context(), author))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
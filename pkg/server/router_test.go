@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aaronireland/state-server/pkg/api/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateServerAPIRouter(t *testing.T) {
+	t.Run("should record the author named in the X-Author header", func(t *testing.T) {
+		store := backend.NewMemoryStore()
+		router := StateServerAPIRouter(backend.WithContext(store))
+
+		body := strings.NewReader(`{"state": "West", "border": [[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]]}`)
+		req := httptest.NewRequest("POST", "/api/v1/state/", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(AuthorHeader, "alice")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, "request should respond with 201 Created")
+
+		history, err := store.GetHistory("West")
+		assert.Nil(t, err, "GetHistory should find the created state")
+		assert.Equal(t, 1, len(history), "history should have a revision for the created state")
+		assert.Equal(t, "alice", history[0].Author, "revision should record the author from the request header")
+	})
+}
@@ -1,7 +1,7 @@
 // Package state-server is the State Server REST API HTTP server. It uses the go-chi framework to handle
 // http requests to get the state (or states) in which a location is contained, render
 // the state location data as JSON in the [RFC 7946 GeoJSON] format, render the full
-// collection of states, create a state, or delete a state.
+// collection of states, create a state, update a state, or delete a state.
 //
 // # Example requests
 //
@@ -27,9 +27,11 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/aaronireland/state-server/pkg/api/backend"
 	"github.com/aaronireland/state-server/pkg/geospatial"
 )
 
@@ -48,10 +50,28 @@ func init() {
 
 type Action string
 type StateLocationDataProvider interface {
-	GetAll() ([]geospatial.State, error)
-	GetByName(name string) (geospatial.State, error)
-	Create(geospatial.State) (geospatial.State, error)
-	Delete(name string) error
+	GetAll(ctx context.Context) ([]geospatial.State, error)
+	GetByName(ctx context.Context, name string) (geospatial.State, error)
+	GetContaining(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, error)
+	GetContainingWithVersion(ctx context.Context, coord geospatial.Coordinate) ([]geospatial.State, uint64, error)
+	GetHistory(ctx context.Context, name string) ([]backend.Revision, error)
+	GetAllAsOf(ctx context.Context, at time.Time) ([]geospatial.State, error)
+	GetByNameAsOf(ctx context.Context, name string, at time.Time) (geospatial.State, error)
+	GetContainingAsOf(ctx context.Context, coord geospatial.Coordinate, at time.Time) ([]geospatial.State, error)
+	Search(ctx context.Context, query string, limit int) ([]backend.SearchMatch, error)
+	Create(ctx context.Context, state geospatial.State) (geospatial.State, error)
+	Update(ctx context.Context, name string, version uint64, state geospatial.State) (geospatial.State, error)
+	Delete(ctx context.Context, name string, version uint64) error
+	GetNeighbors(ctx context.Context, name string) ([]geospatial.Neighbor, error)
+	Version() uint64
+	GetAllRegions(ctx context.Context) ([]geospatial.Region, error)
+	GetRegion(ctx context.Context, name string) (geospatial.Region, error)
+	CreateRegion(ctx context.Context, region geospatial.Region) (geospatial.Region, error)
+	DeleteRegion(ctx context.Context, name string) error
+	GetBoundary(ctx context.Context, path []string) (geospatial.Boundary, error)
+	GetChildren(ctx context.Context, path []string) ([]geospatial.Boundary, error)
+	CreateBoundary(ctx context.Context, parent []string, boundary geospatial.Boundary) (geospatial.Boundary, error)
+	DeleteBoundary(ctx context.Context, path []string) error
 }
 
 type StateServer struct {